@@ -0,0 +1,134 @@
+// Package authz decides what a user may do on a project: a permission matrix
+// for the built-in roles, custom roles defined by project owners, and the
+// helpers resolving the caller's grant once per request.
+package authz
+
+import (
+	"sort"
+)
+
+type Permission string
+
+const (
+	ProjectView       Permission = "project:view"
+	ProjectUpdate     Permission = "project:update"
+	ProjectDelete     Permission = "project:delete"
+	ProjectTransfer   Permission = "project:transfer"
+	ParticipantManage Permission = "participant:manage"
+	RoleManage        Permission = "role:manage"
+	APIKeyManage      Permission = "apikey:manage"
+	DoDCreate         Permission = "dod:create"
+	DoDUpdate         Permission = "dod:update"
+	DoDDelete         Permission = "dod:delete"
+	WorkItemCreate    Permission = "workitem:create"
+	WorkItemUpdate    Permission = "workitem:update"
+	ItemCheck         Permission = "item:check"
+	IntegrationManage Permission = "integration:manage"
+)
+
+// Permissions lists every permission known to the matrix.
+var Permissions = []Permission{
+	ProjectView, ProjectUpdate, ProjectDelete, ProjectTransfer,
+	ParticipantManage, RoleManage, APIKeyManage,
+	DoDCreate, DoDUpdate, DoDDelete,
+	WorkItemCreate, WorkItemUpdate, ItemCheck,
+	IntegrationManage,
+}
+
+// Built-in roles
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// Matrix grants permissions to the built-in roles. Every participant can view
+// the project whatever their role.
+var Matrix = map[string][]Permission{
+	RoleOwner: Permissions,
+	RoleEditor: {
+		ProjectView, ProjectUpdate,
+		DoDCreate, DoDUpdate, DoDDelete,
+		WorkItemCreate, WorkItemUpdate, ItemCheck,
+	},
+	RoleViewer: {ProjectView},
+}
+
+// ownerOnly permissions cannot be granted through custom roles.
+var ownerOnly = map[Permission]bool{
+	ProjectDelete:   true,
+	ProjectTransfer: true,
+}
+
+func IsBuiltinRole(role string) bool {
+	_, ok := Matrix[role]
+	return ok
+}
+
+// IsReservedRole reports whether a custom role cannot take the name.
+func IsReservedRole(role string) bool {
+	return IsBuiltinRole(role) || role == RoleOrgAdmin
+}
+
+// Grantable reports whether a custom role may hold the permission.
+func Grantable(p Permission) bool {
+	for _, known := range Permissions {
+		if known == p {
+			return !ownerOnly[p]
+		}
+	}
+	return false
+}
+
+// Grant is what one user may do on one project.
+type Grant struct {
+	ProjectID uint
+	UserID    uint
+	Role      string // empty when the user does not participate
+
+	permissions map[Permission]bool
+}
+
+// NewGrant builds the grant of a participant holding role with the given
+// permissions. An empty role yields a grant allowing nothing.
+func NewGrant(projectID, userID uint, role string, permissions []Permission) *Grant {
+	g := &Grant{ProjectID: projectID, UserID: userID, Role: role, permissions: map[Permission]bool{}}
+	if role == "" {
+		return g
+	}
+
+	g.permissions[ProjectView] = true
+	for _, p := range permissions {
+		g.permissions[p] = true
+	}
+	return g
+}
+
+func (g *Grant) Participant() bool {
+	return g.Role != ""
+}
+
+func (g *Grant) Can(p Permission) bool {
+	return g.permissions[p]
+}
+
+// Covers reports whether the grant holds all the permissions, which is
+// required to hand them over to someone else.
+func (g *Grant) Covers(permissions []Permission) bool {
+	for _, p := range permissions {
+		if !g.permissions[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// Permissions returns the granted permissions in a stable order.
+func (g *Grant) Permissions() []Permission {
+	permissions := make([]Permission, 0, len(g.permissions))
+	for p := range g.permissions {
+		permissions = append(permissions, p)
+	}
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i] < permissions[j] })
+	return permissions
+}
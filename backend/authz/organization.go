@@ -0,0 +1,32 @@
+package authz
+
+import (
+	"dod-backend/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Organization roles. Admins manage the organization and hold every
+// permission on its projects.
+const (
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// RoleOrgAdmin is reported for org admins who do not participate in the
+// project themselves.
+const RoleOrgAdmin = "org_admin"
+
+// OrgRole returns the role of the user in the organization, empty when they
+// are not a member.
+func OrgRole(db *gorm.DB, organizationID, userID uint) (string, error) {
+	var member models.OrganizationMember
+	err := db.Where("organization_id = ? AND user_id = ?", organizationID, userID).First(&member).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
@@ -0,0 +1,173 @@
+package authz
+
+import (
+	"net/http"
+	"strconv"
+
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// grantsKey caches the grants resolved during a request, by project.
+const grantsKey = "authz_grants"
+
+// Resolve loads the grant of a user on a project: the highest of their own
+// participation and of the roles granted to their teams, plus everything when
+// they administer the project's organization.
+func Resolve(db *gorm.DB, projectID, userID uint) (*Grant, error) {
+	var role string
+	var permissions []Permission
+
+	var participant models.ProjectParticipant
+	err := db.Where("project_id = ? AND user_id = ?", projectID, userID).First(&participant).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+	if err == nil {
+		role = participant.Role
+		if permissions, _, err = RolePermissions(db, projectID, role); err != nil {
+			return nil, err
+		}
+	}
+
+	teamRoles, err := TeamRoles(db, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, teamRole := range teamRoles {
+		teamPermissions, _, err := RolePermissions(db, projectID, teamRole)
+		if err != nil {
+			return nil, err
+		}
+		// The reported role is the one granting the most
+		if role == "" || len(teamPermissions) > len(permissions) {
+			role = teamRole
+		}
+		permissions = append(permissions, teamPermissions...)
+	}
+
+	var project models.Project
+	if err := db.Select("id, organization_id").First(&project, projectID).Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+	if project.OrganizationID != nil {
+		orgRole, err := OrgRole(db, *project.OrganizationID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if orgRole == OrgRoleAdmin {
+			if role == "" {
+				role = RoleOrgAdmin
+			}
+			permissions = append(permissions, Matrix[RoleOwner]...)
+		}
+	}
+
+	return NewGrant(projectID, userID, role, permissions), nil
+}
+
+// HoldsRole reports whether the user holds the role on the project, directly
+// or through one of their teams.
+func HoldsRole(db *gorm.DB, projectID, userID uint, role string) (bool, error) {
+	var participant models.ProjectParticipant
+	err := db.Where("project_id = ? AND user_id = ?", projectID, userID).First(&participant).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return false, err
+	}
+	if err == nil && participant.Role == role {
+		return true, nil
+	}
+
+	teamRoles, err := TeamRoles(db, projectID, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, teamRole := range teamRoles {
+		if teamRole == role {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RolePermissions returns the permissions of a built-in or custom role of the
+// project. Unknown roles, such as a deleted custom role, grant nothing beyond
+// viewing the project.
+func RolePermissions(db *gorm.DB, projectID uint, role string) ([]Permission, bool, error) {
+	if permissions, ok := Matrix[role]; ok {
+		return permissions, true, nil
+	}
+
+	var custom models.ProjectRole
+	err := db.Where("project_id = ? AND name = ?", projectID, role).First(&custom).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	permissions := make([]Permission, 0, len(custom.PermissionList))
+	for _, p := range custom.PermissionList {
+		permissions = append(permissions, Permission(p))
+	}
+	return permissions, true, nil
+}
+
+// For returns the caller's grant on the project, resolving it at most once
+// per request.
+func For(c *gin.Context, db *gorm.DB, projectID uint) (*Grant, error) {
+	grants, _ := c.Get(grantsKey)
+	cache, ok := grants.(map[uint]*Grant)
+	if !ok {
+		cache = map[uint]*Grant{}
+		c.Set(grantsKey, cache)
+	}
+
+	if grant, ok := cache[projectID]; ok {
+		return grant, nil
+	}
+
+	grant, err := Resolve(db, projectID, c.GetUint("user_id"))
+	if err != nil {
+		return nil, err
+	}
+	cache[projectID] = grant
+	return grant, nil
+}
+
+// Require checks that the caller holds the permission on the project, writing
+// the error response itself when they do not.
+func Require(c *gin.Context, db *gorm.DB, projectID uint, permission Permission, message string) bool {
+	grant, err := For(c, db, projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return false
+	}
+
+	if !grant.Can(permission) {
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+		return false
+	}
+	return true
+}
+
+// RequirePermission guards routes whose :id parameter is the project.
+func RequirePermission(db *gorm.DB, permission Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		projectID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+			c.Abort()
+			return
+		}
+
+		if !Require(c, db, uint(projectID), permission, "Missing permission '"+string(permission)+"' on this project") {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
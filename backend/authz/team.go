@@ -0,0 +1,38 @@
+package authz
+
+import (
+	"dod-backend/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Team roles. Maintainers manage the team's members.
+const (
+	TeamRoleMaintainer = "maintainer"
+	TeamRoleMember     = "member"
+)
+
+// TeamRole returns the role of the user in the team, empty when they are not
+// a member.
+func TeamRole(db *gorm.DB, teamID, userID uint) (string, error) {
+	var member models.TeamMember
+	err := db.Where("team_id = ? AND user_id = ?", teamID, userID).First(&member).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// TeamRoles returns the project roles granted to the teams of the user. They
+// are read on every request, so membership changes apply immediately.
+func TeamRoles(db *gorm.DB, projectID, userID uint) ([]string, error) {
+	var roles []string
+	err := db.Model(&models.ProjectTeam{}).
+		Joins("JOIN team_members ON team_members.team_id = project_teams.team_id").
+		Where("project_teams.project_id = ? AND team_members.user_id = ?", projectID, userID).
+		Pluck("project_teams.role", &roles).Error
+	return roles, err
+}
@@ -0,0 +1,184 @@
+// Package chat posts notifications to chat incoming webhooks: Slack, as Block
+// Kit messages, and Microsoft Teams, as Adaptive Cards.
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"dod-backend/safehttp"
+)
+
+// Chat kinds
+const (
+	Slack = "slack"
+	Teams = "teams"
+)
+
+var ErrUnknownKind = errors.New("unknown chat kind")
+
+// Notification is a chat message, formatted for each kind of chat.
+type Notification struct {
+	Title string
+	Text  string
+	Facts []Fact
+	URL   string // link to the DoD Manager, optional
+}
+
+// Fact is a name/value line of a notification.
+type Fact struct {
+	Name  string
+	Value string
+}
+
+// Payload returns the JSON body of the notification for the kind of chat.
+func Payload(kind string, n Notification) ([]byte, error) {
+	switch kind {
+	case Slack:
+		return json.Marshal(slackMessage(n))
+	case Teams:
+		return json.Marshal(teamsMessage(n))
+	}
+	return nil, ErrUnknownKind
+}
+
+// slackEscaper escapes the control characters of Slack's mrkdwn, so that
+// text such as <!channel> in a title is shown rather than acted upon.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Slack Block Kit
+func slackMessage(n Notification) map[string]interface{} {
+	blocks := []interface{}{
+		map[string]interface{}{
+			"type": "header",
+			"text": map[string]interface{}{"type": "plain_text", "text": truncate(n.Title, 150), "emoji": true},
+		},
+	}
+	if n.Text != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": truncate(slackEscaper.Replace(n.Text), 3000)},
+		})
+	}
+	if len(n.Facts) > 0 {
+		// Sections take up to 10 fields
+		fields := []interface{}{}
+		for _, fact := range n.Facts {
+			if len(fields) == 10 {
+				break
+			}
+			fields = append(fields, map[string]interface{}{
+				"type": "mrkdwn",
+				"text": truncate(fmt.Sprintf("*%s*\n%s", slackEscaper.Replace(fact.Name), slackEscaper.Replace(fact.Value)), 2000),
+			})
+		}
+		blocks = append(blocks, map[string]interface{}{"type": "section", "fields": fields})
+	}
+	if n.URL != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "actions",
+			"elements": []interface{}{
+				map[string]interface{}{
+					"type": "button",
+					"text": map[string]interface{}{"type": "plain_text", "text": "Open in DoD Manager"},
+					"url":  n.URL,
+				},
+			},
+		})
+	}
+
+	// Text is what notifications and clients without blocks show
+	return map[string]interface{}{"text": slackEscaper.Replace(n.Title), "blocks": blocks}
+}
+
+// Microsoft Teams Adaptive Card
+func teamsMessage(n Notification) map[string]interface{} {
+	body := []interface{}{
+		map[string]interface{}{"type": "TextBlock", "text": n.Title, "weight": "Bolder", "size": "Medium", "wrap": true},
+	}
+	if n.Text != "" {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": n.Text, "wrap": true})
+	}
+	if len(n.Facts) > 0 {
+		facts := []interface{}{}
+		for _, fact := range n.Facts {
+			facts = append(facts, map[string]interface{}{"title": fact.Name, "value": fact.Value})
+		}
+		body = append(body, map[string]interface{}{"type": "FactSet", "facts": facts})
+	}
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	if n.URL != "" {
+		card["actions"] = []interface{}{
+			map[string]interface{}{"type": "Action.OpenUrl", "title": "Open in DoD Manager", "url": n.URL},
+		}
+	}
+
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []interface{}{
+			map[string]interface{}{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"contentUrl":  nil,
+				"content":     card,
+			},
+		},
+	}
+}
+
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+// Client posts notifications to incoming webhook URLs.
+type Client struct {
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client that only posts to public addresses, without
+// following redirects.
+func NewClient() *Client {
+	return &Client{HTTPClient: safehttp.NewClient(10 * time.Second)}
+}
+
+// Send posts the notification and fails unless the chat answers with a 2xx
+// status. The error only carries the status: the URL is chosen by users, and
+// what it answers is not for them to read.
+func (c *Client) Send(ctx context.Context, kind, url string, n Notification) error {
+	payload, err := Payload(kind, n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("chat answered %s", resp.Status)
+	}
+	return nil
+}
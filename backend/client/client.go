@@ -0,0 +1,118 @@
+// Package client talks to the DoD API from CI pipelines and tooling.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Gate verdicts, as returned by the API.
+const (
+	VerdictPass   = "pass"
+	VerdictFail   = "fail"
+	VerdictWaived = "waived"
+)
+
+// Verdict is the answer of the release gate for a work item.
+type Verdict struct {
+	Verdict       string    `json:"verdict"`
+	Pass          bool      `json:"pass"`
+	ProjectID     uint      `json:"project_id"`
+	WorkItemID    uint      `json:"work_item_id"`
+	ExternalRef   string    `json:"external_ref"`
+	Title         string    `json:"title"`
+	DoDID         *uint     `json:"dod_id"`
+	Revision      int       `json:"revision,omitempty"`
+	RequiredTotal int       `json:"required_total"`
+	RequiredMet   int       `json:"required_met"`
+	Unmet         []Item    `json:"unmet"`
+	Waived        []Item    `json:"waived"`
+	Reasons       []string  `json:"reasons"`
+	EvaluatedAt   time.Time `json:"evaluated_at"`
+}
+
+// Item is a required DoD item the verdict depends on.
+type Item struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Inherited   bool       `json:"inherited"`
+	Reason      string     `json:"reason"`
+	WaiverID    *uint      `json:"waiver_id,omitempty"`
+	WaivedUntil *time.Time `json:"waived_until,omitempty"`
+}
+
+// Error is returned when the API answers with an error status.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("dod api: %d %s", e.StatusCode, e.Message)
+}
+
+// Client calls the API at BaseURL (e.g. https://dod.example.com) with an API
+// token, preferably a project API key granted the "gate" scope.
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+func New(baseURL, token string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Gate returns the verdict for the work item with the given external
+// reference or ID. The project is the one of the key when projectID is 0.
+func (c *Client) Gate(ctx context.Context, ref string, projectID uint) (*Verdict, error) {
+	path := "/api/v1/gates/" + url.PathEscape(ref)
+	if projectID != 0 {
+		path += "?project_id=" + strconv.FormatUint(uint64(projectID), 10)
+	}
+
+	var verdict Verdict
+	if err := c.get(ctx, path, &verdict); err != nil {
+		return nil, err
+	}
+	return &verdict, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		if body.Error == "" {
+			body.Error = http.StatusText(resp.StatusCode)
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: body.Error}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,20 @@
+// Command dodctl queries the DoD API from the command line and CI pipelines.
+//
+// Usage:
+//
+//	dodctl gate [-server URL] [-token TOKEN] [-project ID] [-strict] [-json] <work item ref>
+//
+// The server and token default to $DOD_SERVER and $DOD_TOKEN. gate exits
+// with 0 when the work item passes (or is waived, unless -strict), 1 when it
+// fails, and 2 when the verdict could not be obtained.
+package main
+
+import (
+	"os"
+
+	"dod-backend/dodctl"
+)
+
+func main() {
+	os.Exit(dodctl.Run(os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -12,9 +15,38 @@ type Config struct {
 	DBName      string
 	JWTSecret   string
 	Environment string
+
+	// Sessions
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// Brute-force protection. Rates are per minute; zero disables a limit.
+	RateLimitPerIP      int
+	RateLimitPerAccount int
+	LockoutThreshold    int
+	LockoutDuration     time.Duration
+
+	// Emails
+	AppURL       string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+
+	// OpenID Connect login, enabled when OIDCIssuer is set
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+
+	// Code hosts repositories can be connected through, by API host name
+	SCMAPIHosts []string
 }
 
 func Load() *Config {
+	appURL := getEnv("APP_URL", "http://localhost:3000")
+
 	return &Config{
 		DBHost:      getEnv("DB_HOST", "localhost"),
 		DBPort:      getEnv("DB_PORT", "5432"),
@@ -23,6 +55,28 @@ func Load() *Config {
 		DBName:      getEnv("DB_NAME", "dod_database"),
 		JWTSecret:   getEnv("JWT_SECRET", "your-secret-key"),
 		Environment: getEnv("GIN_MODE", "debug"),
+
+		AccessTokenTTL:  getDurationEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getDurationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		RateLimitPerIP:      getIntEnv("RATE_LIMIT_PER_IP", 20),
+		RateLimitPerAccount: getIntEnv("RATE_LIMIT_PER_ACCOUNT", 5),
+		LockoutThreshold:    getIntEnv("LOCKOUT_THRESHOLD", 10),
+		LockoutDuration:     getDurationEnv("LOCKOUT_DURATION", 15*time.Minute),
+
+		AppURL:       appURL,
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		MailFrom:     getEnv("MAIL_FROM", "DoD Manager <no-reply@dod-manager.local>"),
+
+		OIDCIssuer:       getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:     getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:  getEnv("OIDC_REDIRECT_URL", appURL+"/oidc/callback"),
+
+		SCMAPIHosts: strings.Split(getEnv("SCM_API_HOSTS", "api.github.com,gitlab.com"), ","),
 	}
 }
 
@@ -31,4 +85,18 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,286 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"dod-backend/authz"
+	"dod-backend/middleware"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Personal access tokens
+func (ctrl *Controller) GetAPITokens(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var tokens []models.APIToken
+	if err := ctrl.DB.Where("user_id = ? AND project_id IS NULL", userID).Find(&tokens).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+func (ctrl *Controller) CreateAPIToken(c *gin.Context) {
+	var req models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expiry must be in the future"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	apiToken := models.APIToken{
+		Name:      req.Name,
+		UserID:    userID,
+		CreatedBy: userID,
+		ExpiresAt: req.ExpiresAt,
+	}
+	apiToken.SetScopes(req.Scopes)
+
+	token, err := ctrl.createAPIToken(ctrl.DB, &apiToken, middleware.PersonalTokenPrefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Token created successfully",
+		"token":     token,
+		"api_token": apiToken,
+	})
+}
+
+func (ctrl *Controller) RevokeAPIToken(c *gin.Context) {
+	tokenID, err := strconv.Atoi(c.Param("tokenId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	result := ctrl.DB.Where("id = ? AND user_id = ? AND project_id IS NULL", tokenID, userID).Delete(models.APIToken{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked successfully"})
+}
+
+// Project API keys
+func (ctrl *Controller) GetProjectAPIKeys(c *gin.Context) {
+	project, ok := ctrl.loadKeyManagedProject(c)
+	if !ok {
+		return
+	}
+
+	var keys []models.APIToken
+	if err := ctrl.DB.Where("project_id = ?", project.ID).Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// CreateProjectAPIKey backs the key with a service account joined to the
+// project, so that the usual participant checks apply to it unchanged: it is
+// an editor when granted "write", a viewer otherwise. Callers cannot create
+// keys holding more than they do.
+func (ctrl *Controller) CreateProjectAPIKey(c *gin.Context) {
+	project, ok := ctrl.loadKeyManagedProject(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expiry must be in the future"})
+		return
+	}
+
+	apiKey := models.APIToken{
+		Name:      req.Name,
+		ProjectID: &project.ID,
+		CreatedBy: c.GetUint("user_id"),
+		ExpiresAt: req.ExpiresAt,
+	}
+	apiKey.SetScopes(req.Scopes)
+
+	role := authz.RoleViewer
+	if apiKey.HasScope(models.ScopeWrite) {
+		role = authz.RoleEditor
+	}
+	if !ctrl.checkCovered(c, project.ID, authz.Matrix[role], "Cannot create an API key with more permissions than your own") {
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	serviceAccount, err := createServiceAccount(tx)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	participant := models.ProjectParticipant{
+		ProjectID: project.ID,
+		UserID:    serviceAccount.ID,
+		Role:      role,
+	}
+	if err := tx.Create(&participant).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	apiKey.UserID = serviceAccount.ID
+	token, err := ctrl.createAPIToken(tx, &apiKey, middleware.ProjectKeyPrefix)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created successfully",
+		"token":   token,
+		"api_key": apiKey,
+	})
+}
+
+func (ctrl *Controller) RevokeProjectAPIKey(c *gin.Context) {
+	project, ok := ctrl.loadKeyManagedProject(c)
+	if !ok {
+		return
+	}
+
+	keyID, err := strconv.Atoi(c.Param("keyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	var apiKey models.APIToken
+	if err := ctrl.DB.Where("id = ? AND project_id = ?", keyID, project.ID).First(&apiKey).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := deleteProjectAPIKeys(tx, []models.APIToken{apiKey}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
+
+// loadKeyManagedProject resolves the :id route parameter, only letting
+// through those who may manage its API keys.
+func (ctrl *Controller) loadKeyManagedProject(c *gin.Context) (*models.Project, bool) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return nil, false
+	}
+
+	var project models.Project
+	if err := ctrl.DB.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil, false
+	}
+
+	if !authz.Require(c, ctrl.DB, project.ID, authz.APIKeyManage, "No permission to manage API keys") {
+		return nil, false
+	}
+
+	return &project, true
+}
+
+// createAPIToken fills in the secret of apiToken and stores it, returning the
+// plain token which is only ever shown once.
+func (ctrl *Controller) createAPIToken(db *gorm.DB, apiToken *models.APIToken, kind string) (string, error) {
+	token, prefix, hash, err := middleware.GenerateAPIToken(kind)
+	if err != nil {
+		return "", err
+	}
+
+	apiToken.Prefix = prefix
+	apiToken.TokenHash = hash
+	if err := db.Create(apiToken).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// createServiceAccount creates a user that nobody can sign in as.
+func createServiceAccount(tx *gorm.DB) (*models.User, error) {
+	random, _, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(random), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	name := "api-key-" + middleware.HashToken(random)[:12]
+	user := models.User{
+		Username:       name,
+		Email:          name + "@service.invalid",
+		Password:       string(hashedPassword),
+		ServiceAccount: true,
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// deleteProjectAPIKeys removes the keys along with their service accounts.
+func deleteProjectAPIKeys(tx *gorm.DB, keys []models.APIToken) error {
+	for _, key := range keys {
+		if err := tx.Where("user_id = ?", key.UserID).Delete(models.ProjectParticipant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", key.ID).Delete(models.APIToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ? AND service_account = ?", key.UserID, true).Delete(models.User{}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,553 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dod-backend/authz"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Applicability Controllers
+//
+// Rules say which work items a DoD, or one of its items, governs: by type,
+// labels, component and size estimate.
+func (ctrl *Controller) GetDoDRules(c *gin.Context) {
+	dod, ok := ctrl.loadRuleDoD(c, authz.ProjectView, "No access to this project")
+	if !ok {
+		return
+	}
+
+	var rules []models.ApplicabilityRule
+	if err := ctrl.DB.Where("do_d_id = ?", dod.ID).Order("id").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+func (ctrl *Controller) CreateDoDRule(c *gin.Context) {
+	dod, ok := ctrl.loadRuleDoD(c, authz.DoDUpdate, "No permission to edit this DoD")
+	if !ok {
+		return
+	}
+
+	var req models.ApplicabilityRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := models.ApplicabilityRule{
+		DoDID:     dod.ID,
+		CreatedBy: c.GetUint("user_id"),
+	}
+	if !ctrl.applyRuleRequest(c, &rule, req) {
+		return
+	}
+
+	if err := ctrl.DB.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Rule created successfully",
+		"rule":    rule,
+	})
+}
+
+// UpdateDoDRule replaces the conditions of a rule.
+func (ctrl *Controller) UpdateDoDRule(c *gin.Context) {
+	rule, ok := ctrl.loadDoDRule(c)
+	if !ok {
+		return
+	}
+
+	var req models.ApplicabilityRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !ctrl.applyRuleRequest(c, rule, req) {
+		return
+	}
+
+	if err := ctrl.DB.Save(rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Rule updated successfully",
+		"rule":    rule,
+	})
+}
+
+func (ctrl *Controller) DeleteDoDRule(c *gin.Context) {
+	rule, ok := ctrl.loadDoDRule(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.DB.Delete(rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted successfully"})
+}
+
+// EvaluateApplicability resolves the checklist of a kind (done by default)
+// for the given work item attributes, or those of an existing work item, and
+// explains which rule selected each DoD and item.
+func (ctrl *Controller) EvaluateApplicability(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var req models.EvaluateApplicabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, uint(projectID), authz.ProjectView, "No access to this project") {
+		return
+	}
+
+	attributes := models.WorkItemAttributes{
+		Type:      req.Type,
+		Labels:    req.Labels,
+		Component: req.Component,
+		Size:      req.Size,
+	}
+	var workItem *models.WorkItem
+	if req.WorkItemID != nil {
+		workItem = &models.WorkItem{}
+		err := ctrl.DB.Where("id = ? AND project_id = ?", *req.WorkItemID, projectID).Preload("DoD").First(workItem).Error
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Work item not found"})
+			return
+		}
+		attributes = workItem.Attributes()
+	}
+	if attributes.Labels == nil {
+		attributes.Labels = []string{}
+	}
+
+	kind := dodKind(req.Kind)
+	var dods []models.ResolvedDoD
+	var items, excluded []models.ResolvedItem
+	if workItem != nil && workItem.DoDID != nil && (req.Kind == "" || req.Kind == workItem.DoD.Kind) {
+		// A work item is judged against its own DoD, as pinned, not against
+		// every active DoD of the kind
+		kind = workItem.DoD.Kind
+		dods, items, excluded, err = ctrl.resolveWorkItemChecklist(workItem)
+	} else {
+		dods, items, excluded, err = ctrl.resolveChecklist(uint(projectID), kind, attributes)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evaluate applicability"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"kind":       kind,
+		"attributes": attributes,
+		"dods":       dods,
+		"items":      items,
+		"excluded":   excluded,
+	})
+}
+
+// loadRuleDoD resolves the :id route parameter to a project DoD and checks
+// the caller's permission, writing the error response itself.
+func (ctrl *Controller) loadRuleDoD(c *gin.Context, permission authz.Permission, message string) (*models.DoD, bool) {
+	dodID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid DoD ID"})
+		return nil, false
+	}
+
+	var dod models.DoD
+	if err := ctrl.DB.First(&dod, dodID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DoD not found"})
+		return nil, false
+	}
+
+	if !authz.Require(c, ctrl.DB, dod.ProjectID, permission, message) {
+		return nil, false
+	}
+
+	return &dod, true
+}
+
+func (ctrl *Controller) loadDoDRule(c *gin.Context) (*models.ApplicabilityRule, bool) {
+	dod, ok := ctrl.loadRuleDoD(c, authz.DoDUpdate, "No permission to edit this DoD")
+	if !ok {
+		return nil, false
+	}
+
+	ruleID, err := strconv.Atoi(c.Param("ruleId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return nil, false
+	}
+
+	var rule models.ApplicabilityRule
+	if err := ctrl.DB.Where("id = ? AND do_d_id = ?", ruleID, dod.ID).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		return nil, false
+	}
+
+	return &rule, true
+}
+
+// applyRuleRequest validates the conditions of a rule request and copies them
+// onto the rule, writing the error response itself.
+func (ctrl *Controller) applyRuleRequest(c *gin.Context, rule *models.ApplicabilityRule, req models.ApplicabilityRuleRequest) bool {
+	if req.ItemID != nil {
+		var item models.DoDItem
+		if err := ctrl.DB.Where("id = ? AND do_d_id = ?", *req.ItemID, rule.DoDID).First(&item).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Item does not belong to this DoD"})
+			return false
+		}
+	}
+
+	rule.Name = req.Name
+	rule.DoDItemID = req.ItemID
+	rule.MinSize = req.MinSize
+	rule.MaxSize = req.MaxSize
+	if err := rule.SetConditions(req.Types, req.Labels, req.Components); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	if len(rule.TypeList) == 0 && len(rule.LabelList) == 0 && len(rule.ComponentList) == 0 && rule.MinSize == nil && rule.MaxSize == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A rule needs at least one condition"})
+		return false
+	}
+	if rule.MinSize != nil && rule.MaxSize != nil && *rule.MinSize > *rule.MaxSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_size cannot exceed max_size"})
+		return false
+	}
+	return true
+}
+
+// Applicability helpers
+
+// rulesFor returns the rules of the given DoDs, split between the rules on a
+// whole DoD (by DoD) and the rules on single items (by item).
+func (ctrl *Controller) rulesFor(dodIDs []uint) (map[uint][]models.ApplicabilityRule, map[uint][]models.ApplicabilityRule, error) {
+	byDoD := map[uint][]models.ApplicabilityRule{}
+	byItem := map[uint][]models.ApplicabilityRule{}
+	if len(dodIDs) == 0 {
+		return byDoD, byItem, nil
+	}
+
+	var rules []models.ApplicabilityRule
+	if err := ctrl.DB.Where("do_d_id IN (?)", dodIDs).Order("id").Find(&rules).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, rule := range rules {
+		if rule.DoDItemID == nil {
+			byDoD[rule.DoDID] = append(byDoD[rule.DoDID], rule)
+		} else {
+			byItem[*rule.DoDItemID] = append(byItem[*rule.DoDItemID], rule)
+		}
+	}
+	return byDoD, byItem, nil
+}
+
+// matchRules tells whether a DoD or item with the given rules applies to a
+// work item, with the first matching rule and a human readable reason.
+func matchRules(rules []models.ApplicabilityRule, attributes models.WorkItemAttributes) (bool, *models.ApplicabilityRule, string) {
+	if len(rules) == 0 {
+		return true, nil, "no rule, applies to every work item"
+	}
+	for i := range rules {
+		if ruleMatches(rules[i], attributes) {
+			return true, &rules[i], "selected by " + describeRule(rules[i])
+		}
+	}
+	return false, nil, "no rule matches"
+}
+
+func ruleMatches(rule models.ApplicabilityRule, attributes models.WorkItemAttributes) bool {
+	if len(rule.TypeList) > 0 && !containsFold(rule.TypeList, attributes.Type) {
+		return false
+	}
+	if len(rule.ComponentList) > 0 && !containsFold(rule.ComponentList, attributes.Component) {
+		return false
+	}
+	if len(rule.LabelList) > 0 {
+		found := false
+		for _, label := range attributes.Labels {
+			found = found || containsFold(rule.LabelList, label)
+		}
+		if !found {
+			return false
+		}
+	}
+	if rule.MinSize != nil && (attributes.Size == nil || *attributes.Size < *rule.MinSize) {
+		return false
+	}
+	if rule.MaxSize != nil && (attributes.Size == nil || *attributes.Size > *rule.MaxSize) {
+		return false
+	}
+	return true
+}
+
+func describeRule(rule models.ApplicabilityRule) string {
+	conditions := []string{}
+	if len(rule.TypeList) > 0 {
+		conditions = append(conditions, "type in "+strings.Join(rule.TypeList, ", "))
+	}
+	if len(rule.LabelList) > 0 {
+		conditions = append(conditions, "label in "+strings.Join(rule.LabelList, ", "))
+	}
+	if len(rule.ComponentList) > 0 {
+		conditions = append(conditions, "component in "+strings.Join(rule.ComponentList, ", "))
+	}
+	if rule.MinSize != nil {
+		conditions = append(conditions, fmt.Sprintf("size >= %d", *rule.MinSize))
+	}
+	if rule.MaxSize != nil {
+		conditions = append(conditions, fmt.Sprintf("size <= %d", *rule.MaxSize))
+	}
+
+	name := fmt.Sprintf("rule #%d", rule.ID)
+	if rule.Name != "" {
+		name = fmt.Sprintf("rule %q (#%d)", rule.Name, rule.ID)
+	}
+	return name + ": " + strings.Join(conditions, ", ")
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveChecklist evaluates the active DoDs of a kind of the project against
+// work item attributes. It returns every DoD with whether it applies, the
+// resolved checklist and the items left out, each with its reason.
+func (ctrl *Controller) resolveChecklist(projectID uint, kind string, attributes models.WorkItemAttributes) ([]models.ResolvedDoD, []models.ResolvedItem, []models.ResolvedItem, error) {
+	var dods []models.DoD
+	err := ctrl.DB.Where("project_id = ? AND is_active = ? AND kind = ?", projectID, true, kind).
+		Preload("Items").
+		Order("id").
+		Find(&dods).Error
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	inherited, err := ctrl.inheritedItems(projectID, kind)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	dodIDs := make([]uint, 0, len(dods))
+	for _, dod := range dods {
+		dodIDs = append(dodIDs, dod.ID)
+	}
+	dodRules, itemRules, err := ctrl.rulesFor(dodIDs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	resolvedDoDs := []models.ResolvedDoD{}
+	items := []models.ResolvedItem{}
+	excluded := []models.ResolvedItem{}
+
+	// The organization baseline is not subject to project rules
+	for _, item := range inherited {
+		if item.NotApplicable {
+			excluded = append(excluded, models.ResolvedItem{
+				Item:      item.DoDItem,
+				Inherited: true,
+				Reason:    "marked not applicable: " + item.NotApplicableReason,
+			})
+			continue
+		}
+		items = append(items, models.ResolvedItem{
+			Item:      item.DoDItem,
+			Inherited: true,
+			Reason:    "inherited from the organization baseline",
+		})
+	}
+
+	for _, dod := range dods {
+		applies, rule, reason := matchRules(dodRules[dod.ID], attributes)
+		resolved := models.ResolvedDoD{DoDID: dod.ID, Title: dod.Title, Applies: applies, Reason: reason}
+		if rule != nil {
+			resolved.RuleID = &rule.ID
+		}
+		resolvedDoDs = append(resolvedDoDs, resolved)
+		if !applies {
+			continue
+		}
+
+		sort.SliceStable(dod.Items, func(i, j int) bool { return dod.Items[i].Order < dod.Items[j].Order })
+		for _, item := range dod.Items {
+			applies, rule, reason := matchRules(itemRules[item.ID], attributes)
+			if rule == nil && applies {
+				reason = "part of " + strconv.Quote(dod.Title) + ", " + resolved.Reason
+			}
+			entry := models.ResolvedItem{Item: item, Reason: reason}
+			if rule != nil {
+				entry.RuleID = &rule.ID
+			}
+			if applies {
+				items = append(items, entry)
+			} else {
+				excluded = append(excluded, entry)
+			}
+		}
+	}
+	return resolvedDoDs, items, excluded, nil
+}
+
+// resolveWorkItemChecklist explains the checklist of a work item: the items
+// workItemItems judges it against, those of its DoD as of the revision it is
+// pinned to, and the items of that DoD its rules leave out.
+func (ctrl *Controller) resolveWorkItemChecklist(workItem *models.WorkItem) ([]models.ResolvedDoD, []models.ResolvedItem, []models.ResolvedItem, error) {
+	effective, revision, err := ctrl.workItemItems(workItem)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	_, liveRules, err := ctrl.rulesFor([]uint{*workItem.DoDID})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	dod := workItem.DoD
+	resolved := models.ResolvedDoD{DoDID: dod.ID, Title: dod.Title, Applies: true, Reason: "attached to the work item"}
+	var candidates []models.DoDItem
+	candidateRules := liveRules
+	if revision != nil {
+		resolved.Reason = fmt.Sprintf("attached to the work item, as of revision %d", revision.Number)
+		candidates = make([]models.DoDItem, 0, len(revision.Items))
+		candidateRules = make(map[uint][]models.ApplicabilityRule, len(revision.Items))
+		for _, item := range revision.Items {
+			candidates = append(candidates, models.DoDItem{
+				ID:          item.ItemID,
+				DoDID:       revision.DoDID,
+				Title:       item.Title,
+				Description: item.Description,
+				IsRequired:  item.IsRequired,
+				Order:       item.Order,
+			})
+			candidateRules[item.ItemID] = item.Rules
+			if item.Rules == nil {
+				candidateRules[item.ItemID] = liveRules[item.ItemID]
+			}
+		}
+	} else if err := ctrl.DB.Where("do_d_id = ?", dod.ID).Find(&candidates).Error; err != nil {
+		return nil, nil, nil, err
+	}
+
+	items := []models.ResolvedItem{}
+	excluded := []models.ResolvedItem{}
+	kept := make(map[uint]bool, len(effective))
+	for _, item := range effective {
+		if !item.Inherited {
+			kept[item.ID] = true
+			continue
+		}
+		if item.NotApplicable {
+			excluded = append(excluded, models.ResolvedItem{
+				Item:      item.DoDItem,
+				Inherited: true,
+				Reason:    "marked not applicable: " + item.NotApplicableReason,
+			})
+			continue
+		}
+		items = append(items, models.ResolvedItem{
+			Item:      item.DoDItem,
+			Inherited: true,
+			Reason:    "inherited from the organization baseline",
+		})
+	}
+
+	attributes := workItem.Attributes()
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Order < candidates[j].Order })
+	for _, item := range candidates {
+		_, rule, reason := matchRules(candidateRules[item.ID], attributes)
+		if rule == nil && kept[item.ID] {
+			reason = "part of " + strconv.Quote(dod.Title) + ", " + resolved.Reason
+		}
+		entry := models.ResolvedItem{Item: item, Reason: reason}
+		if rule != nil {
+			entry.RuleID = &rule.ID
+		}
+		if kept[item.ID] {
+			items = append(items, entry)
+		} else {
+			excluded = append(excluded, entry)
+		}
+	}
+	return []models.ResolvedDoD{resolved}, items, excluded, nil
+}
+
+// applicableItems drops the items of the work item's own DoD that its rules
+// leave out for this work item. Inherited items are kept.
+func (ctrl *Controller) applicableItems(workItem *models.WorkItem, items []models.EffectiveDoDItem) ([]models.EffectiveDoDItem, error) {
+	_, itemRules, err := ctrl.rulesFor([]uint{*workItem.DoDID})
+	if err != nil || len(itemRules) == 0 {
+		return items, err
+	}
+
+	attributes := workItem.Attributes()
+	kept := make([]models.EffectiveDoDItem, 0, len(items))
+	for _, item := range items {
+		if !item.Inherited {
+			if applies, _, _ := matchRules(itemRules[item.ID], attributes); !applies {
+				continue
+			}
+		}
+		kept = append(kept, item)
+	}
+	return kept, nil
+}
+
+// selectDoD returns the DoD a new work item falls under when exactly one of
+// the project's active DoDs is selected by one of its rules.
+func (ctrl *Controller) selectDoD(projectID uint, attributes models.WorkItemAttributes) (*uint, error) {
+	var dodIDs []uint
+	err := ctrl.DB.Model(&models.DoD{}).
+		Where("project_id = ? AND is_active = ? AND kind = ?", projectID, true, models.DoDKindDone).
+		Pluck("id", &dodIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	dodRules, _, err := ctrl.rulesFor(dodIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []uint
+	for _, dodID := range dodIDs {
+		if _, rule, _ := matchRules(dodRules[dodID], attributes); rule != nil {
+			selected = append(selected, dodID)
+		}
+	}
+	if len(selected) != 1 {
+		return nil, nil
+	}
+	return &selected[0], nil
+}
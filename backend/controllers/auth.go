@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"dod-backend/middleware"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// issueSession returns a short-lived access token and a refresh token that
+// can be exchanged once for a new pair.
+func (ctrl *Controller) issueSession(db *gorm.DB, user *models.User) (gin.H, error) {
+	accessToken, err := middleware.GenerateJWT(user, ctrl.Cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, hash, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	stored := models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ctrl.Cfg.RefreshTokenTTL),
+	}
+	if err := db.Create(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    int(ctrl.Cfg.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+func (ctrl *Controller) RefreshSession(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var stored models.RefreshToken
+	if err := ctrl.DB.Where("token_hash = ?", middleware.HashToken(req.RefreshToken)).First(&stored).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	// A rotated token coming back means it leaked: end every session of the
+	// user, access tokens included. Tokens of sessions logged out, say from
+	// another tab, are merely refused.
+	if stored.RevokedAt != nil {
+		if stored.RevokedReason == models.RevokedLoggedOut {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token revoked"})
+			return
+		}
+		if err := ctrl.invalidateSessions(ctrl.DB, stored.UserID); err != nil {
+			log.Printf("Failed to invalidate sessions of user %d: %v", stored.UserID, err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token already used"})
+		return
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token expired"})
+		return
+	}
+
+	var user models.User
+	if err := ctrl.DB.First(&user, stored.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	result := tx.Model(&models.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", stored.ID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": models.RevokedRotated})
+	if result.Error != nil || result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token already used"})
+		return
+	}
+
+	session, err := ctrl.issueSession(tx, &user)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	session["message"] = "Token refreshed successfully"
+	c.JSON(http.StatusOK, session)
+}
+
+func (ctrl *Controller) Logout(c *gin.Context) {
+	var req models.LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	revoked := models.RevokedToken{
+		JTI:       c.GetString("jti"),
+		ExpiresAt: c.GetTime("token_expires_at"),
+	}
+
+	tx := ctrl.DB.Begin()
+	// Entries past their expiry are useless, the token is rejected anyway
+	if err := tx.Where("expires_at < ?", time.Now()).Delete(models.RevokedToken{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	if err := tx.Create(&revoked).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	if req.RefreshToken != "" {
+		err := tx.Model(&models.RefreshToken{}).
+			Where("token_hash = ? AND user_id = ? AND revoked_at IS NULL", middleware.HashToken(req.RefreshToken), userID).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": models.RevokedLoggedOut}).Error
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+func (ctrl *Controller) LogoutAllSessions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	tx := ctrl.DB.Begin()
+	if err := ctrl.invalidateSessions(tx, userID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions successfully"})
+}
+
+// invalidateSessions makes every access and refresh token of the user unusable.
+func (ctrl *Controller) invalidateSessions(db *gorm.DB, userID uint) error {
+	err := db.Model(&models.User{}).Where("id = ?", userID).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error
+	if err != nil {
+		return err
+	}
+	return ctrl.revokeRefreshTokens(db, userID)
+}
+
+func (ctrl *Controller) revokeRefreshTokens(db *gorm.DB, userID uint) error {
+	return db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": models.RevokedLoggedOut}).Error
+}
@@ -0,0 +1,249 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"dod-backend/authz"
+	"dod-backend/database"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// CloneProject creates a project owned by the caller with copies of the DoDs,
+// items and custom roles of the source, and optionally its participants and
+// team grants. Work items are not copied.
+func (ctrl *Controller) CloneProject(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var req models.CloneProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.GetBool("service_account") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Project API keys cannot create projects"})
+		return
+	}
+
+	var source models.Project
+	if err := ctrl.DB.First(&source, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, source.ID, authz.ProjectView, "No access to this project") {
+		return
+	}
+	if req.IncludeParticipants && !authz.Require(c, ctrl.DB, source.ID, authz.ParticipantManage, "No permission to copy participants") {
+		return
+	}
+
+	// The clone stays in the source's organization unless told otherwise
+	userID := c.GetUint("user_id")
+	organizationID := source.OrganizationID
+	if req.OrganizationID != nil {
+		organizationID = req.OrganizationID
+	}
+	if organizationID != nil {
+		role, err := authz.OrgRole(ctrl.DB, *organizationID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+		if role == "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this organization"})
+			return
+		}
+	}
+
+	clone := models.Project{
+		Name:           req.Name,
+		Description:    source.Description,
+		OwnerID:        userID,
+		OrganizationID: organizationID,
+	}
+	if req.Description != nil {
+		clone.Description = *req.Description
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := cloneProject(tx, &source, &clone, req.IncludeParticipants); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone project"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone project"})
+		return
+	}
+
+	err = ctrl.DB.Preload("Owner").
+		Preload("Participants.User").
+		Preload("Teams.Team").
+		Preload("DoDs.Items").
+		First(&clone, clone.ID).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch project"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Project cloned successfully",
+		"project": clone,
+	})
+}
+
+func cloneProject(tx *gorm.DB, source, clone *models.Project, includeParticipants bool) error {
+	if err := tx.Create(clone).Error; err != nil {
+		return err
+	}
+
+	owner := models.ProjectParticipant{
+		ProjectID: clone.ID,
+		UserID:    clone.OwnerID,
+		Role:      authz.RoleOwner,
+	}
+	if err := tx.Create(&owner).Error; err != nil {
+		return err
+	}
+
+	var roles []models.ProjectRole
+	if err := tx.Where("project_id = ?", source.ID).Find(&roles).Error; err != nil {
+		return err
+	}
+	for _, role := range roles {
+		copied := models.ProjectRole{
+			ProjectID:   clone.ID,
+			Name:        role.Name,
+			Description: role.Description,
+		}
+		copied.SetPermissions(role.PermissionList)
+		if err := tx.Create(&copied).Error; err != nil {
+			return err
+		}
+	}
+
+	var dods []models.DoD
+	if err := tx.Where("project_id = ?", source.ID).Preload("Items").Find(&dods).Error; err != nil {
+		return err
+	}
+	for _, dod := range dods {
+		copied := models.DoD{
+			Title:       dod.Title,
+			Description: dod.Description,
+			ProjectID:   clone.ID,
+			CreatedBy:   clone.OwnerID,
+			IsActive:    true,
+			Kind:        dod.Kind,
+		}
+		if err := tx.Create(&copied).Error; err != nil {
+			return err
+		}
+		// is_active defaults to true as well
+		if !dod.IsActive {
+			if err := tx.Model(&copied).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+		copiedItems := map[uint]uint{}
+		for _, item := range dod.Items {
+			copiedItem := models.DoDItem{
+				DoDID:       copied.ID,
+				Title:       item.Title,
+				Description: item.Description,
+				IsRequired:  item.IsRequired,
+				Order:       item.Order,
+
+				Verification:      item.Verification,
+				ApproverRole:      item.ApproverRole,
+				RequiredApprovals: item.RequiredApprovals,
+
+				AutoCheck:          item.AutoCheck,
+				AutoCheckThreshold: item.AutoCheckThreshold,
+			}
+			if err := database.CreateDoDItem(tx, &copiedItem); err != nil {
+				return err
+			}
+			copiedItems[item.ID] = copiedItem.ID
+		}
+
+		var rules []models.ApplicabilityRule
+		if err := tx.Where("do_d_id = ?", dod.ID).Find(&rules).Error; err != nil {
+			return err
+		}
+		for _, rule := range rules {
+			copiedRule := models.ApplicabilityRule{
+				DoDID:     copied.ID,
+				Name:      rule.Name,
+				MinSize:   rule.MinSize,
+				MaxSize:   rule.MaxSize,
+				CreatedBy: clone.OwnerID,
+			}
+			if err := copiedRule.SetConditions(rule.TypeList, rule.LabelList, rule.ComponentList); err != nil {
+				return err
+			}
+			if rule.DoDItemID != nil {
+				itemID := copiedItems[*rule.DoDItemID]
+				copiedRule.DoDItemID = &itemID
+			}
+			if err := tx.Create(&copiedRule).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	if !includeParticipants {
+		return nil
+	}
+
+	// API keys belong to the source project and are not copied
+	var participants []models.ProjectParticipant
+	err := tx.Select("project_participants.*").
+		Joins("JOIN users ON users.id = project_participants.user_id").
+		Where("project_participants.project_id = ? AND users.service_account = ?", source.ID, false).
+		Find(&participants).Error
+	if err != nil {
+		return err
+	}
+	for _, participant := range participants {
+		if participant.UserID == clone.OwnerID {
+			continue
+		}
+		role := participant.Role
+		if role == authz.RoleOwner {
+			role = authz.RoleEditor
+		}
+		copied := models.ProjectParticipant{
+			ProjectID: clone.ID,
+			UserID:    participant.UserID,
+			Role:      role,
+		}
+		if err := tx.Create(&copied).Error; err != nil {
+			return err
+		}
+	}
+
+	var teams []models.ProjectTeam
+	if err := tx.Where("project_id = ?", source.ID).Find(&teams).Error; err != nil {
+		return err
+	}
+	for _, team := range teams {
+		copied := models.ProjectTeam{
+			ProjectID: clone.ID,
+			TeamID:    team.TeamID,
+			Role:      team.Role,
+		}
+		if err := tx.Create(&copied).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,12 +1,22 @@
 package controllers
 
 import (
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 
+	"dod-backend/authz"
+	"dod-backend/chat"
 	"dod-backend/config"
+	"dod-backend/database"
+	"dod-backend/mailer"
 	"dod-backend/middleware"
 	"dod-backend/models"
+	"dod-backend/oidc"
+	"dod-backend/ratelimit"
+	"dod-backend/webhooks"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
@@ -14,12 +24,56 @@ import (
 )
 
 type Controller struct {
-	DB  *gorm.DB
-	Cfg *config.Config
+	DB     *gorm.DB
+	Cfg    *config.Config
+	Mailer mailer.Mailer
+	OIDC   *oidc.Provider // nil when OpenID Connect login is not configured
+
+	// Posts the deliveries of project webhooks
+	Webhooks *webhooks.Sender
+
+	// Posts notifications to the chat channels of projects
+	Chat *chat.Client
+
+	// Throttle the public auth endpoints per client IP, and login attempts
+	// per account. Nil limiters let everything through.
+	IPLimiter      *ratelimit.Limiter
+	AccountLimiter *ratelimit.Limiter
+
+	// Work started by requests and left running after them
+	pending sync.WaitGroup
 }
 
 func NewController(db *gorm.DB, cfg *config.Config) *Controller {
-	return &Controller{DB: db, Cfg: cfg}
+	ctrl := &Controller{DB: db, Cfg: cfg, Mailer: mailer.New(cfg), Webhooks: webhooks.NewSender(), Chat: chat.NewClient()}
+	if cfg.OIDCIssuer != "" {
+		ctrl.OIDC = oidc.NewProvider(cfg.OIDCIssuer, cfg.OIDCClientID, cfg.OIDCClientSecret, cfg.OIDCRedirectURL)
+	}
+	if cfg.RateLimitPerIP > 0 {
+		ctrl.IPLimiter = ratelimit.New(ratelimit.PerMinute(cfg.RateLimitPerIP), ratelimit.NewMemoryStore())
+	}
+	if cfg.RateLimitPerAccount > 0 {
+		ctrl.AccountLimiter = ratelimit.New(ratelimit.PerMinute(cfg.RateLimitPerAccount), ratelimit.NewMemoryStore())
+	}
+	return ctrl
+}
+
+// background runs fn once the request no longer waits for it, for calls to
+// services chosen by users, which can be slow or down: chats and code hosts.
+// They are best effort, their failure is only logged.
+func (ctrl *Controller) background(what string, fn func() error) {
+	ctrl.pending.Add(1)
+	go func() {
+		defer ctrl.pending.Done()
+		if err := fn(); err != nil {
+			log.Printf("Failed to %s: %v", what, err)
+		}
+	}()
+}
+
+// WaitBackground waits for the work started in the background by requests.
+func (ctrl *Controller) WaitBackground() {
+	ctrl.pending.Wait()
 }
 
 // Auth Controllers
@@ -30,6 +84,22 @@ func (ctrl *Controller) Register(c *gin.Context) {
 		return
 	}
 
+	// Only the invitation link proves the email belongs to the user, and lets
+	// them join the projects they were invited to
+	var invited bool
+	if req.InvitationToken != "" {
+		claims, err := middleware.ParseInvitationToken(req.InvitationToken, ctrl.Cfg)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation not found or expired"})
+			return
+		}
+		if !strings.EqualFold(claims.Email, req.Email) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The invitation was sent to another email address"})
+			return
+		}
+		invited = true
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
@@ -42,22 +112,39 @@ func (ctrl *Controller) Register(c *gin.Context) {
 		Password: string(hashedPassword),
 	}
 
-	if err := ctrl.DB.Create(&user).Error; err != nil {
+	tx := ctrl.DB.Begin()
+	if err := tx.Create(&user).Error; err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
 		return
 	}
 
-	token, err := middleware.GenerateJWT(&user, ctrl.Cfg)
+	joined := []uint{}
+	if invited {
+		if joined, err = ctrl.acceptPendingInvitations(tx, &user); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept pending invitations"})
+			return
+		}
+	}
+
+	session, err := ctrl.issueSession(tx, &user)
 	if err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "User created successfully",
-		"token":   token,
-		"user":    user,
-	})
+	ctrl.emitJoined(&user, joined)
+
+	session["message"] = "User created successfully"
+	session["user"] = user
+	session["joined_projects"] = joined
+	c.JSON(http.StatusCreated, session)
 }
 
 func (ctrl *Controller) Login(c *gin.Context) {
@@ -67,28 +154,45 @@ func (ctrl *Controller) Login(c *gin.Context) {
 		return
 	}
 
+	if ok, retryAfter := ctrl.AccountLimiter.Allow("login:" + strings.ToLower(req.Email)); !ok {
+		middleware.TooManyRequests(c, retryAfter, "Too many login attempts, try again later")
+		return
+	}
+
 	var user models.User
 	if err := ctrl.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
+	// Locked accounts are refused before the password is even looked at
+	if !ctrl.checkLoginLock(c, &user) {
+		return
+	}
+
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		ctrl.recordLoginFailure(c, &user)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	token, err := middleware.GenerateJWT(&user, ctrl.Cfg)
+	// With 2FA the password only earns a challenge to exchange with a code
+	if user.TwoFactorEnabled {
+		ctrl.challengeSecondFactor(c, &user)
+		return
+	}
+
+	ctrl.resetLoginFailures(&user)
+
+	session, err := ctrl.issueSession(ctrl.DB, &user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   token,
-		"user":    user,
-	})
+	session["message"] = "Login successful"
+	session["user"] = user
+	c.JSON(http.StatusOK, session)
 }
 
 // Project Controllers
@@ -99,11 +203,29 @@ func (ctrl *Controller) CreateProject(c *gin.Context) {
 		return
 	}
 
+	if c.GetBool("service_account") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Project API keys cannot create projects"})
+		return
+	}
+
 	userID := c.GetUint("user_id")
+	if req.OrganizationID != nil {
+		role, err := authz.OrgRole(ctrl.DB, *req.OrganizationID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+		if role == "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this organization"})
+			return
+		}
+	}
+
 	project := models.Project{
-		Name:        req.Name,
-		Description: req.Description,
-		OwnerID:     userID,
+		Name:           req.Name,
+		Description:    req.Description,
+		OwnerID:        userID,
+		OrganizationID: req.OrganizationID,
 	}
 
 	if err := ctrl.DB.Create(&project).Error; err != nil {
@@ -114,7 +236,7 @@ func (ctrl *Controller) CreateProject(c *gin.Context) {
 	participant := models.ProjectParticipant{
 		ProjectID: project.ID,
 		UserID:    userID,
-		Role:      "owner",
+		Role:      authz.RoleOwner,
 	}
 	ctrl.DB.Create(&participant)
 
@@ -127,10 +249,34 @@ func (ctrl *Controller) CreateProject(c *gin.Context) {
 func (ctrl *Controller) GetUserProjects(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
+	// Projects reached through a team, and for organization admins every
+	// project of their organizations, are listed too
+	participating := ctrl.DB.Table("project_participants").
+		Select("project_id").
+		Where("user_id = ?", userID).
+		SubQuery()
+	teamGranted := ctrl.DB.Table("project_teams").
+		Select("project_id").
+		Where("team_id IN ?", ctrl.DB.Table("team_members").Select("team_id").Where("user_id = ?", userID).SubQuery()).
+		SubQuery()
+	administered := ctrl.DB.Table("organization_members").
+		Select("organization_id").
+		Where("user_id = ? AND role = ?", userID, authz.OrgRoleAdmin).
+		SubQuery()
+
+	query := ctrl.DB.Where("projects.id IN ? OR projects.id IN ? OR projects.organization_id IN ?", participating, teamGranted, administered)
+	if organizationID := c.Query("organization_id"); organizationID != "" {
+		id, err := strconv.Atoi(organizationID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+			return
+		}
+		query = query.Where("projects.organization_id = ?", id)
+	}
+
 	var projects []models.Project
-	err := ctrl.DB.Joins("JOIN project_participants ON projects.id = project_participants.project_id").
-		Where("project_participants.user_id = ?", userID).
-		Preload("Owner").
+	err := query.Preload("Owner").
+		Preload("Organization").
 		Find(&projects).Error
 
 	if err != nil {
@@ -141,6 +287,105 @@ func (ctrl *Controller) GetUserProjects(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"projects": projects})
 }
 
+func (ctrl *Controller) GetProject(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, uint(projectID), authz.ProjectView, "No access to this project") {
+		return
+	}
+
+	var project models.Project
+	err = ctrl.DB.Preload("Owner").
+		Preload("Organization").
+		Preload("Participants.User").
+		Preload("Teams.Team").
+		Preload("DoDs.Items").
+		First(&project, projectID).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"project": project})
+}
+
+func (ctrl *Controller) UpdateProject(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var req models.UpdateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var project models.Project
+	if err := ctrl.DB.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, project.ID, authz.ProjectUpdate, "No permission to edit this project") {
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+
+	if err := ctrl.DB.Model(&project).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Project updated successfully",
+		"project": project,
+	})
+}
+
+func (ctrl *Controller) DeleteProject(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var project models.Project
+	if err := ctrl.DB.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, project.ID, authz.ProjectDelete, "Only project owner can delete the project") {
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := deleteProjectCascade(tx, project.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Project deleted successfully"})
+}
+
 func (ctrl *Controller) AddProjectParticipant(c *gin.Context) {
 	projectID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -154,23 +399,23 @@ func (ctrl *Controller) AddProjectParticipant(c *gin.Context) {
 		return
 	}
 
-	// Check if user is project owner
-	currentUserID := c.GetUint("user_id")
 	var project models.Project
 	if err := ctrl.DB.First(&project, projectID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 		return
 	}
 
-	if project.OwnerID != currentUserID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only project owner can add participants"})
+	if !authz.Require(c, ctrl.DB, project.ID, authz.ParticipantManage, "No permission to manage participants") {
+		return
+	}
+	if !ctrl.checkAssignableRole(c, project.ID, req.Role) {
 		return
 	}
 
-	// Find user by email
+	// Find user by email, inviting them when they have no account yet
 	var user models.User
 	if err := ctrl.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		ctrl.inviteParticipant(c, &project, req)
 		return
 	}
 
@@ -185,6 +430,7 @@ func (ctrl *Controller) AddProjectParticipant(c *gin.Context) {
 		c.JSON(http.StatusConflict, gin.H{"error": "User already participant"})
 		return
 	}
+	ctrl.emitFrom(c, participant.ProjectID, models.EventParticipantAdded, participant)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message":     "Participant added successfully",
@@ -201,14 +447,7 @@ func (ctrl *Controller) CreateDoD(c *gin.Context) {
 	}
 
 	userID := c.GetUint("user_id")
-	
-	// Check if user can edit this project
-	var participant models.ProjectParticipant
-	err := ctrl.DB.Where("project_id = ? AND user_id = ? AND role IN (?)", 
-		req.ProjectID, userID, []string{"owner", "editor"}).First(&participant).Error
-	
-	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "No permission to create DoD for this project"})
+	if !authz.Require(c, ctrl.DB, req.ProjectID, authz.DoDCreate, "No permission to create DoD for this project") {
 		return
 	}
 
@@ -218,12 +457,14 @@ func (ctrl *Controller) CreateDoD(c *gin.Context) {
 		ProjectID:   req.ProjectID,
 		CreatedBy:   userID,
 		IsActive:    true,
+		Kind:        dodKind(req.Kind),
 	}
 
 	if err := ctrl.DB.Create(&dod).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create DoD"})
 		return
 	}
+	ctrl.emitFrom(c, dod.ProjectID, models.EventDoDCreated, dod)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "DoD created successfully",
@@ -238,18 +479,21 @@ func (ctrl *Controller) GetProjectDoDs(c *gin.Context) {
 		return
 	}
 
-	userID := c.GetUint("user_id")
-	
-	// Check if user can view this project
-	var participant models.ProjectParticipant
-	err = ctrl.DB.Where("project_id = ? AND user_id = ?", projectID, userID).First(&participant).Error
-	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "No access to this project"})
+	if !authz.Require(c, ctrl.DB, uint(projectID), authz.ProjectView, "No access to this project") {
 		return
 	}
 
+	query := ctrl.DB.Where("project_id = ?", projectID)
+	if kind := c.Query("kind"); kind != "" {
+		if !validDoDKind(kind) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid checklist kind"})
+			return
+		}
+		query = query.Where("kind = ?", kind)
+	}
+
 	var dods []models.DoD
-	err = ctrl.DB.Where("project_id = ?", projectID).
+	err = query.
 		Preload("Items").
 		Preload("Creator").
 		Find(&dods).Error
@@ -262,6 +506,140 @@ func (ctrl *Controller) GetProjectDoDs(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"dods": dods})
 }
 
+func (ctrl *Controller) GetDoD(c *gin.Context) {
+	dodID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid DoD ID"})
+		return
+	}
+
+	var dod models.DoD
+	if err := ctrl.DB.Preload("Items").Preload("Creator").First(&dod, dodID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DoD not found"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, dod.ProjectID, authz.ProjectView, "No access to this project") {
+		return
+	}
+
+	// The live items are a draft until published
+	latest, err := latestRevision(ctrl.DB, dod.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+	publishedRevision, unpublishedChanges := 0, true
+	if latest != nil {
+		draft, err := ctrl.draftItems(dod.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch DoD items"})
+			return
+		}
+		publishedRevision = latest.Number
+		unpublishedChanges = !diffEmpty(diffRevisionItems(latest.Items, draft))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dod":                 dod,
+		"published_revision":  publishedRevision,
+		"unpublished_changes": unpublishedChanges,
+	})
+}
+
+func (ctrl *Controller) UpdateDoD(c *gin.Context) {
+	dodID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid DoD ID"})
+		return
+	}
+
+	var req models.UpdateDoDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var dod models.DoD
+	if err := ctrl.DB.First(&dod, dodID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DoD not found"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, dod.ProjectID, authz.DoDUpdate, "No permission to edit this DoD") {
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Title != nil {
+		updates["title"] = *req.Title
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	if req.Kind != nil && *req.Kind != dod.Kind {
+		// Work items are judged against their DoD, which a ready checklist cannot be
+		if *req.Kind == models.DoDKindReady {
+			var attached int
+			if err := ctrl.DB.Model(&models.WorkItem{}).Where("do_d_id = ?", dod.ID).Count(&attached).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update DoD"})
+				return
+			}
+			if attached > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "Work items are attached to this DoD"})
+				return
+			}
+		}
+		updates["kind"] = *req.Kind
+	}
+
+	if err := ctrl.DB.Model(&dod).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update DoD"})
+		return
+	}
+	ctrl.emitFrom(c, dod.ProjectID, models.EventDoDUpdated, dod)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "DoD updated successfully",
+		"dod":     dod,
+	})
+}
+
+func (ctrl *Controller) DeleteDoD(c *gin.Context) {
+	dodID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid DoD ID"})
+		return
+	}
+
+	var dod models.DoD
+	if err := ctrl.DB.First(&dod, dodID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DoD not found"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, dod.ProjectID, authz.DoDDelete, "No permission to delete this DoD") {
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := deleteDoDsCascade(tx, []uint{dod.ID}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DoD"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DoD"})
+		return
+	}
+	ctrl.emitFrom(c, dod.ProjectID, models.EventDoDDeleted, dod)
+
+	c.JSON(http.StatusOK, gin.H{"message": "DoD deleted successfully"})
+}
+
 func (ctrl *Controller) AddDoDItem(c *gin.Context) {
 	dodID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -275,8 +653,6 @@ func (ctrl *Controller) AddDoDItem(c *gin.Context) {
 		return
 	}
 
-	userID := c.GetUint("user_id")
-
 	// Check permissions
 	var dod models.DoD
 	err = ctrl.DB.Preload("Project").First(&dod, dodID).Error
@@ -285,12 +661,7 @@ func (ctrl *Controller) AddDoDItem(c *gin.Context) {
 		return
 	}
 
-	var participant models.ProjectParticipant
-	err = ctrl.DB.Where("project_id = ? AND user_id = ? AND role IN (?)", 
-		dod.ProjectID, userID, []string{"owner", "editor"}).First(&participant).Error
-	
-	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "No permission to edit this DoD"})
+	if !authz.Require(c, ctrl.DB, dod.ProjectID, authz.DoDUpdate, "No permission to edit this DoD") {
 		return
 	}
 
@@ -301,14 +672,313 @@ func (ctrl *Controller) AddDoDItem(c *gin.Context) {
 		IsRequired:  req.IsRequired,
 		Order:       req.Order,
 	}
+	if !ctrl.setVerification(c, &item, &req.Verification, &req.ApproverRole, &req.RequiredApprovals) {
+		return
+	}
+	if !setAutoCheck(c, &item, &req.AutoCheck, req.AutoCheckThreshold) {
+		return
+	}
 
-	if err := ctrl.DB.Create(&item).Error; err != nil {
+	if err := database.CreateDoDItem(ctrl.DB, &item); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create DoD item"})
 		return
 	}
+	ctrl.emitFrom(c, dod.ProjectID, models.EventItemAdded, item)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "DoD item added successfully",
 		"item":    item,
 	})
-}
\ No newline at end of file
+}
+func (ctrl *Controller) UpdateDoDItem(c *gin.Context) {
+	item, ok := ctrl.loadEditableDoDItem(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateDoDItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Title != nil {
+		updates["title"] = *req.Title
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.IsRequired != nil {
+		updates["is_required"] = *req.IsRequired
+	}
+	if req.Order != nil {
+		updates["order"] = *req.Order
+	}
+	if req.Verification != nil || req.ApproverRole != nil || req.RequiredApprovals != nil {
+		if !ctrl.setVerification(c, item, req.Verification, req.ApproverRole, req.RequiredApprovals) {
+			return
+		}
+		updates["verification"] = item.Verification
+		updates["approver_role"] = item.ApproverRole
+		updates["required_approvals"] = item.RequiredApprovals
+	}
+	if req.AutoCheck != nil || req.AutoCheckThreshold != nil {
+		if !setAutoCheck(c, item, req.AutoCheck, req.AutoCheckThreshold) {
+			return
+		}
+		updates["auto_check"] = item.AutoCheck
+		updates["auto_check_threshold"] = item.AutoCheckThreshold
+	}
+
+	if err := ctrl.DB.Model(item).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update DoD item"})
+		return
+	}
+	ctrl.emitItem(c, models.EventItemUpdated, item)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "DoD item updated successfully",
+		"item":    item,
+	})
+}
+
+func (ctrl *Controller) DeleteDoDItem(c *gin.Context) {
+	item, ok := ctrl.loadEditableDoDItem(c)
+	if !ok {
+		return
+	}
+
+	// Published revisions may still list the item, so completions are kept
+	// for the work items pinned to them
+	var published int
+	ctrl.DB.Model(&models.DoDRevision{}).Where("do_d_id = ?", item.DoDID).Count(&published)
+
+	tx := ctrl.DB.Begin()
+	if published == 0 {
+		if err := tx.Where("do_d_item_id = ?", item.ID).Delete(models.DoDItemCompletion{}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DoD item"})
+			return
+		}
+		if err := tx.Where("do_d_item_id = ?", item.ID).Delete(models.ItemDecision{}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DoD item"})
+			return
+		}
+		if err := tx.Where("do_d_item_id = ?", item.ID).Delete(models.Waiver{}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DoD item"})
+			return
+		}
+	}
+	if err := tx.Where("do_d_item_id = ?", item.ID).Delete(models.ApplicabilityRule{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DoD item"})
+		return
+	}
+	if err := tx.Delete(item).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DoD item"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DoD item"})
+		return
+	}
+	ctrl.emitItem(c, models.EventItemDeleted, item)
+
+	c.JSON(http.StatusOK, gin.H{"message": "DoD item deleted successfully"})
+}
+
+// loadEditableDoDItem resolves the :id/:itemId route parameters and checks that
+// the caller may edit the owning DoD, writing the error response itself.
+func (ctrl *Controller) loadEditableDoDItem(c *gin.Context) (*models.DoDItem, bool) {
+	dodID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid DoD ID"})
+		return nil, false
+	}
+
+	itemID, err := strconv.Atoi(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid DoD item ID"})
+		return nil, false
+	}
+
+	var dod models.DoD
+	if err := ctrl.DB.First(&dod, dodID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DoD not found"})
+		return nil, false
+	}
+
+	if !authz.Require(c, ctrl.DB, dod.ProjectID, authz.DoDUpdate, "No permission to edit this DoD") {
+		return nil, false
+	}
+
+	var item models.DoDItem
+	if err := ctrl.DB.Where("id = ? AND do_d_id = ?", itemID, dod.ID).First(&item).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DoD item not found"})
+		return nil, false
+	}
+
+	return &item, true
+}
+
+// Permission helpers
+
+// checkAssignableRole validates a role about to be given to someone: it must
+// exist on the project, cannot be ownership, and cannot grant more than the
+// caller holds. It writes the error response itself.
+func (ctrl *Controller) checkAssignableRole(c *gin.Context, projectID uint, role string) bool {
+	if role == authz.RoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use the ownership transfer to make someone owner"})
+		return false
+	}
+
+	permissions, known, err := authz.RolePermissions(ctrl.DB, projectID, role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return false
+	}
+	if !known {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+		return false
+	}
+
+	return ctrl.checkCovered(c, projectID, permissions, "Cannot grant a role with more permissions than your own")
+}
+
+// checkCovered checks that the caller holds all the permissions on the
+// project, writing the error response itself when they do not.
+func (ctrl *Controller) checkCovered(c *gin.Context, projectID uint, permissions []authz.Permission, message string) bool {
+	grant, err := authz.For(c, ctrl.DB, projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return false
+	}
+	if !grant.Covers(permissions) {
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+		return false
+	}
+	return true
+}
+
+// Cascade helpers
+func deleteProjectCascade(tx *gorm.DB, projectID uint) error {
+	var workItemIDs []uint
+	if err := tx.Model(&models.WorkItem{}).Where("project_id = ?", projectID).Pluck("id", &workItemIDs).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("work_item_id IN (?)", workItemIDs).Delete(models.DoDItemCompletion{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("work_item_id IN (?)", workItemIDs).Delete(models.ItemDecision{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id = ?", projectID).Delete(models.Waiver{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id = ?", projectID).Delete(models.Alert{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id = ?", projectID).Delete(models.Evidence{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id = ?", projectID).Delete(models.PullRequest{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id = ?", projectID).Delete(models.WebhookReceipt{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id = ?", projectID).Delete(models.Repository{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id = ?", projectID).Delete(models.WebhookDelivery{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id = ?", projectID).Delete(models.Webhook{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id = ?", projectID).Delete(models.NotificationChannel{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id = ?", projectID).Delete(models.WorkItem{}).Error; err != nil {
+		return err
+	}
+
+	var dodIDs []uint
+	if err := tx.Model(&models.DoD{}).Where("project_id = ?", projectID).Pluck("id", &dodIDs).Error; err != nil {
+		return err
+	}
+	if err := deleteDoDsCascade(tx, dodIDs); err != nil {
+		return err
+	}
+
+	var apiKeys []models.APIToken
+	if err := tx.Where("project_id = ?", projectID).Find(&apiKeys).Error; err != nil {
+		return err
+	}
+	if err := deleteProjectAPIKeys(tx, apiKeys); err != nil {
+		return err
+	}
+
+	if err := tx.Where("project_id = ?", projectID).Delete(models.NotApplicableItem{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id = ?", projectID).Delete(models.ProjectTeam{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id = ?", projectID).Delete(models.ProjectParticipant{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id = ?", projectID).Delete(models.ProjectInvitation{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id = ?", projectID).Delete(models.ProjectRole{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("id = ?", projectID).Delete(models.Project{}).Error
+}
+
+// deleteDoDsCascade removes the DoDs with their items and completions, and
+// detaches any work item that was judged against them.
+func deleteDoDsCascade(tx *gorm.DB, dodIDs []uint) error {
+	if len(dodIDs) == 0 {
+		return nil
+	}
+
+	var itemIDs []uint
+	if err := tx.Model(&models.DoDItem{}).Where("do_d_id IN (?)", dodIDs).Pluck("id", &itemIDs).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("do_d_item_id IN (?)", itemIDs).Delete(models.DoDItemCompletion{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("do_d_item_id IN (?)", itemIDs).Delete(models.ItemDecision{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("do_d_item_id IN (?)", itemIDs).Delete(models.Waiver{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("do_d_item_id IN (?)", itemIDs).Delete(models.NotApplicableItem{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("do_d_id IN (?)", dodIDs).Delete(models.DoDItem{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("do_d_id IN (?)", dodIDs).Delete(models.DoDRevision{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("do_d_id IN (?)", dodIDs).Delete(models.ApplicabilityRule{}).Error; err != nil {
+		return err
+	}
+	err := tx.Model(&models.WorkItem{}).Where("do_d_id IN (?)", dodIDs).Updates(map[string]interface{}{
+		"do_d_id":          nil,
+		"do_d_revision_id": nil,
+	}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Where("id IN (?)", dodIDs).Delete(models.DoD{}).Error
+}
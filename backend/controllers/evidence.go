@@ -0,0 +1,286 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dod-backend/authz"
+	"dod-backend/models"
+	"dod-backend/reports"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxReportSize bounds the reports CI pipelines upload.
+const maxReportSize = 10 << 20
+
+// reportChecks is the automated check each report format feeds.
+var reportChecks = map[string]string{
+	reports.JUnit:     models.AutoCheckTests,
+	reports.Cobertura: models.AutoCheckCoverage,
+	reports.LCOV:      models.AutoCheckCoverage,
+	reports.SARIF:     models.AutoCheckFindings,
+}
+
+// Evidence Controllers
+//
+// CI pipelines upload their reports for a work item as the raw request body.
+// Each is kept as evidence and evaluated against the automated checks of the
+// work item's items: passing ticks the item (or requests its sign-off),
+// failing unticks it.
+func (ctrl *Controller) UploadEvidence(c *gin.Context) {
+	workItem, ok := ctrl.loadWorkItem(c)
+	if !ok {
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if !authz.Require(c, ctrl.DB, workItem.ProjectID, authz.ItemCheck, "No permission to check items of this work item") {
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxReportSize))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Report is too large"})
+		return
+	}
+
+	format := c.Query("format")
+	if format != "" && reportChecks[format] == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown report format, use junit, cobertura, lcov or sarif"})
+		return
+	}
+	summary, err := reports.Parse(format, data)
+	if err == reports.ErrUnknownFormat {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not detect the report format, set it with ?format="})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report: " + err.Error()})
+		return
+	}
+
+	evidence := models.Evidence{
+		ProjectID:    workItem.ProjectID,
+		WorkItemID:   workItem.ID,
+		Format:       summary.Format,
+		Name:         c.Query("name"),
+		Content:      string(data),
+		Tests:        summary.Tests,
+		Failures:     summary.Failures,
+		Skipped:      summary.Skipped,
+		LinesValid:   summary.LinesValid,
+		LinesCovered: summary.LinesCovered,
+		Coverage:     summary.Coverage,
+		Findings:     summary.Findings,
+		HighFindings: summary.HighFindings,
+		UploadedBy:   userID,
+	}
+
+	items, err := ctrl.checklistItems(workItem)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch DoD items"})
+		return
+	}
+	var checked []models.DoDItem
+	for _, item := range items {
+		policy := ctrl.itemPolicy(item.DoDItem)
+		if !item.NotApplicable && policy.AutoCheck == reportChecks[summary.Format] {
+			checked = append(checked, policy)
+		}
+	}
+
+	wasMet := ctrl.dodMet(workItem)
+	tx := ctrl.DB.Begin()
+	if err := tx.Create(&evidence).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store report"})
+		return
+	}
+
+	results := []models.CheckResult{}
+	for _, item := range checked {
+		result := evaluateAutoCheck(item, summary)
+		results = append(results, result)
+
+		var completion models.DoDItemCompletion
+		tx.Where("work_item_id = ? AND do_d_item_id = ?", workItem.ID, item.ID).
+			FirstOrInit(&completion, models.DoDItemCompletion{WorkItemID: workItem.ID, DoDItemID: item.ID})
+
+		decision := applyCheckResult(&completion, item, result, userID)
+		completion.EvidenceID = &evidence.ID
+		completion.Note = result.Detail
+		if err := tx.Save(&completion).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update DoD item completion"})
+			return
+		}
+		if decision != "" {
+			if err := recordDecision(tx, workItem.ID, item.ID, userID, decision, result.Detail); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update DoD item completion"})
+				return
+			}
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store report"})
+		return
+	}
+	ctrl.refreshPullRequests(workItem.ID)
+
+	if workItem.Status, err = ctrl.workItemStatus(workItem); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute work item status"})
+		return
+	}
+	ctrl.emitCompleted(workItem, wasMet, userID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Report uploaded successfully",
+		"evidence":  evidence,
+		"results":   results,
+		"work_item": workItem,
+	})
+}
+
+func (ctrl *Controller) GetWorkItemEvidence(c *gin.Context) {
+	workItem, ok := ctrl.loadWorkItem(c)
+	if !ok {
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, workItem.ProjectID, authz.ProjectView, "No access to this project") {
+		return
+	}
+
+	var evidence []models.Evidence
+	err := ctrl.DB.Select("id, project_id, work_item_id, format, name, tests, failures, skipped, lines_valid, lines_covered, coverage, findings, high_findings, uploaded_by, created_at").
+		Where("work_item_id = ?", workItem.ID).
+		Order("id desc").
+		Find(&evidence).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch evidence"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"evidence": evidence})
+}
+
+// DownloadEvidence returns the report as it was uploaded.
+func (ctrl *Controller) DownloadEvidence(c *gin.Context) {
+	workItem, ok := ctrl.loadWorkItem(c)
+	if !ok {
+		return
+	}
+
+	evidenceID, err := strconv.Atoi(c.Param("evidenceId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid evidence ID"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, workItem.ProjectID, authz.ProjectView, "No access to this project") {
+		return
+	}
+
+	var evidence models.Evidence
+	if err := ctrl.DB.Where("id = ? AND work_item_id = ?", evidenceID, workItem.ID).First(&evidence).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Evidence not found"})
+		return
+	}
+
+	contentType := "application/xml"
+	switch evidence.Format {
+	case reports.SARIF:
+		contentType = "application/json"
+	case reports.LCOV:
+		contentType = "text/plain"
+	}
+	c.Data(http.StatusOK, contentType+"; charset=utf-8", []byte(evidence.Content))
+}
+
+// Automated check helpers
+
+// evaluateAutoCheck holds the report against the item's automated check.
+func evaluateAutoCheck(item models.DoDItem, summary *reports.Summary) models.CheckResult {
+	result := models.CheckResult{Item: item, Threshold: item.AutoCheckLimit()}
+	switch item.AutoCheck {
+	case models.AutoCheckCoverage:
+		if summary.Coverage == nil {
+			result.Detail = "No lines measured"
+			break
+		}
+		result.Actual = *summary.Coverage
+		result.Passed = result.Actual >= result.Threshold
+		result.Detail = fmt.Sprintf("Line coverage %.1f%% (at least %.1f%% required)", result.Actual, result.Threshold)
+	case models.AutoCheckTests:
+		result.Actual = float64(summary.Failures)
+		result.Passed = summary.Tests > 0 && result.Actual <= result.Threshold
+		result.Detail = fmt.Sprintf("%d of %d tests failed (at most %g allowed)", summary.Failures, summary.Tests, result.Threshold)
+		if summary.Tests == 0 {
+			result.Detail = "No tests ran"
+		}
+	case models.AutoCheckFindings:
+		result.Actual = float64(summary.HighFindings)
+		result.Passed = result.Actual <= result.Threshold
+		result.Detail = fmt.Sprintf("%d high-severity findings out of %d (at most %g allowed)", summary.HighFindings, summary.Findings, result.Threshold)
+	}
+	return result
+}
+
+// applyCheckResult ticks or unticks the completion after an automated check,
+// the way ticking it by hand would, and returns the sign-off decision to
+// record, if any. A passing check leaves a tick or a pending sign-off alone.
+func applyCheckResult(completion *models.DoDItemCompletion, policy models.DoDItem, result models.CheckResult, userID uint) string {
+	needsApproval := policy.ApprovalsRequired() > 0
+	if result.Passed {
+		if completion.Checked || completion.PendingApproval {
+			return ""
+		}
+		now := time.Now()
+		completion.Checked = !needsApproval
+		completion.CheckedBy = &userID
+		completion.CheckedAt = &now
+		completion.PendingApproval = needsApproval
+		completion.Approvals = 0
+		if needsApproval {
+			return models.DecisionRequested
+		}
+		return ""
+	}
+
+	var decision string
+	if needsApproval && (completion.Checked || completion.PendingApproval) {
+		decision = models.DecisionWithdrawn
+	}
+	completion.Checked = false
+	completion.CheckedBy = nil
+	completion.CheckedAt = nil
+	completion.PendingApproval = false
+	completion.Approvals = 0
+	return decision
+}
+
+// setAutoCheck applies a change of automated check to the item. It writes
+// the error response itself.
+func setAutoCheck(c *gin.Context, item *models.DoDItem, kind *string, threshold *float64) bool {
+	if kind != nil {
+		item.AutoCheck = *kind
+	}
+	if threshold != nil {
+		item.AutoCheckThreshold = threshold
+	}
+	if item.AutoCheck == "" {
+		item.AutoCheckThreshold = nil
+		return true
+	}
+
+	if item.AutoCheck == models.AutoCheckCoverage && item.AutoCheckThreshold != nil && *item.AutoCheckThreshold > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Coverage threshold cannot exceed 100"})
+		return false
+	}
+	return true
+}
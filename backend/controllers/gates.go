@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dod-backend/authz"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Gate Controllers
+//
+// CI pipelines ask whether a work item, named by its external reference or
+// its ID, meets its DoD. Project API keys reach their own project; other
+// credentials name it with ?project_id=. A verdict is always answered with
+// 200, so that only the verdict decides whether the pipeline goes on.
+func (ctrl *Controller) GetGate(c *gin.Context) {
+	projectID, ok := ctrl.gateProject(c)
+	if !ok {
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, projectID, authz.ProjectView, "No access to this project") {
+		return
+	}
+
+	workItem, ok := ctrl.loadGateWorkItem(c, projectID)
+	if !ok {
+		return
+	}
+
+	verdict, err := ctrl.gateVerdict(workItem)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute work item status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, verdict)
+}
+
+// gateProject returns the project the gate is checked in: the one of the
+// project API key, or the project_id query parameter otherwise. It writes
+// the error response itself.
+func (ctrl *Controller) gateProject(c *gin.Context) (uint, bool) {
+	var keyProject *uint
+	if tokenID := c.GetUint("api_token_id"); tokenID != 0 {
+		var apiToken models.APIToken
+		if err := ctrl.DB.First(&apiToken, tokenID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API key"})
+			return 0, false
+		}
+		keyProject = apiToken.ProjectID
+	}
+
+	param := c.Query("project_id")
+	if param == "" {
+		if keyProject == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "project_id is required unless using a project API key"})
+			return 0, false
+		}
+		return *keyProject, true
+	}
+
+	projectID, err := strconv.Atoi(param)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return 0, false
+	}
+	if keyProject != nil && *keyProject != uint(projectID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This API key belongs to another project"})
+		return 0, false
+	}
+	return uint(projectID), true
+}
+
+// loadGateWorkItem finds the work item by external reference, falling back to
+// its ID. It writes the error response itself.
+func (ctrl *Controller) loadGateWorkItem(c *gin.Context, projectID uint) (*models.WorkItem, bool) {
+	ref := c.Param("workItemRef")
+
+	var workItems []models.WorkItem
+	if err := ctrl.DB.Where("project_id = ? AND external_ref = ?", projectID, ref).Find(&workItems).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch work item"})
+		return nil, false
+	}
+	if len(workItems) > 1 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Several work items share this reference, use the work item ID"})
+		return nil, false
+	}
+	if len(workItems) == 1 {
+		return &workItems[0], true
+	}
+
+	if workItemID, err := strconv.Atoi(ref); err == nil {
+		var workItem models.WorkItem
+		if err := ctrl.DB.Where("id = ? AND project_id = ?", workItemID, projectID).First(&workItem).Error; err == nil {
+			return &workItem, true
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Work item not found"})
+	return nil, false
+}
+
+// gateVerdict judges the work item against its DoD: pass when every required
+// item is checked, waived when some are only met through a waiver, fail
+// otherwise.
+func (ctrl *Controller) gateVerdict(workItem *models.WorkItem) (*models.GateVerdict, error) {
+	verdict := &models.GateVerdict{
+		Verdict:     models.GateFail,
+		ProjectID:   workItem.ProjectID,
+		WorkItemID:  workItem.ID,
+		ExternalRef: workItem.ExternalRef,
+		Title:       workItem.Title,
+		DoDID:       workItem.DoDID,
+		Unmet:       []models.GateItem{},
+		Waived:      []models.GateItem{},
+		Reasons:     []string{},
+		EvaluatedAt: time.Now(),
+	}
+	if workItem.DoDID == nil {
+		verdict.Reasons = append(verdict.Reasons, "No DoD is attached to the work item")
+		return verdict, nil
+	}
+
+	status, err := ctrl.workItemStatus(workItem)
+	if err != nil {
+		return nil, err
+	}
+	verdict.Revision = status.Revision
+	verdict.RequiredTotal = status.RequiredTotal
+	verdict.RequiredMet = status.RequiredMet
+
+	for _, criterion := range status.Criteria {
+		item := criterion.Item
+		if !item.IsRequired || criterion.Checked {
+			continue
+		}
+
+		gateItem := models.GateItem{
+			ID:        item.ID,
+			Title:     item.Title,
+			Inherited: criterion.Inherited,
+		}
+		switch {
+		case criterion.WaiverID != nil:
+			gateItem.WaiverID = criterion.WaiverID
+			gateItem.WaivedUntil = criterion.WaivedUntil
+			gateItem.Reason = "waived until " + criterion.WaivedUntil.Format(time.RFC3339)
+			verdict.Waived = append(verdict.Waived, gateItem)
+		case criterion.PendingApproval:
+			policy := ctrl.itemPolicy(item)
+			gateItem.Reason = fmt.Sprintf("awaiting sign-off (%d of %d approvals)", criterion.Approvals, policy.ApprovalsRequired())
+			verdict.Unmet = append(verdict.Unmet, gateItem)
+		default:
+			gateItem.Reason = "not checked"
+			verdict.Unmet = append(verdict.Unmet, gateItem)
+		}
+		verdict.Reasons = append(verdict.Reasons, fmt.Sprintf("%q is %s", item.Title, gateItem.Reason))
+	}
+
+	switch {
+	case !status.Done:
+		verdict.Verdict = models.GateFail
+	case len(verdict.Waived) > 0:
+		verdict.Verdict = models.GateWaived
+	default:
+		verdict.Verdict = models.GatePass
+	}
+	verdict.Pass = verdict.Verdict != models.GateFail
+	return verdict, nil
+}
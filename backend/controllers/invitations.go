@@ -0,0 +1,279 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"dod-backend/authz"
+	"dod-backend/mailer"
+	"dod-backend/middleware"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// Invitation Controllers
+
+// inviteParticipant records a pending invitation for an email without an
+// account and emails a signed link to sign up. The email is sent once the
+// invitation is stored; when it cannot be sent, the invitation keeps the
+// error and inviting the email again replaces it.
+func (ctrl *Controller) inviteParticipant(c *gin.Context, project *models.Project, req models.AddParticipantRequest) {
+	email := strings.ToLower(req.Email)
+
+	var pending models.ProjectInvitation
+	err := ctrl.DB.Where("project_id = ? AND email = ? AND accepted_at IS NULL AND expires_at > ?",
+		project.ID, email, time.Now()).First(&pending).Error
+	if err == nil {
+		if pending.SendError == "" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Invitation already pending"})
+			return
+		}
+		if err := ctrl.DB.Delete(&pending).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+			return
+		}
+	}
+
+	invitation := models.ProjectInvitation{
+		ProjectID: project.ID,
+		Email:     email,
+		Role:      req.Role,
+		InvitedBy: c.GetUint("user_id"),
+		ExpiresAt: time.Now().Add(middleware.InvitationTTL),
+	}
+	if err := ctrl.DB.Create(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	token, err := middleware.GenerateInvitationToken(&invitation, ctrl.Cfg)
+	if err != nil {
+		ctrl.DB.Delete(&invitation)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invitation token"})
+		return
+	}
+
+	msg := mailer.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("You have been invited to %s on DoD Manager", project.Name),
+		Body: fmt.Sprintf("%s invited you to join the project %q as %s.\n\n"+
+			"Create your account to accept the invitation:\n%s/register?invitation=%s\n\n"+
+			"This invitation expires on %s.\n",
+			c.GetString("username"), project.Name, invitation.Role,
+			ctrl.Cfg.AppURL, url.QueryEscape(token),
+			invitation.ExpiresAt.Format("January 2, 2006")),
+	}
+	if err := ctrl.Mailer.Send(msg); err != nil {
+		log.Printf("Failed to send invitation %d: %v", invitation.ID, err)
+		invitation.SendError = "Failed to send invitation email"
+		if err := ctrl.DB.Model(&invitation).Update("send_error", invitation.SendError).Error; err != nil {
+			log.Printf("Failed to record invitation %d failure: %v", invitation.ID, err)
+		}
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":      "Failed to send invitation email",
+			"invitation": invitation,
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "Invitation sent successfully",
+		"invitation": invitation,
+	})
+}
+
+func (ctrl *Controller) GetProjectInvitations(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var project models.Project
+	if err := ctrl.DB.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, project.ID, authz.ParticipantManage, "No permission to manage invitations") {
+		return
+	}
+
+	var invitations []models.ProjectInvitation
+	err = ctrl.DB.Where("project_id = ? AND accepted_at IS NULL", project.ID).
+		Find(&invitations).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+func (ctrl *Controller) RevokeProjectInvitation(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	invitationID, err := strconv.Atoi(c.Param("invitationId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	var project models.Project
+	if err := ctrl.DB.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, project.ID, authz.ParticipantManage, "No permission to manage invitations") {
+		return
+	}
+
+	var invitation models.ProjectInvitation
+	err = ctrl.DB.Where("id = ? AND project_id = ? AND accepted_at IS NULL", invitationID, project.ID).
+		First(&invitation).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	if err := ctrl.DB.Delete(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
+
+// GetInvitation lets the sign-up page display who is being invited where,
+// from the token in the emailed link. It is public.
+func (ctrl *Controller) GetInvitation(c *gin.Context) {
+	claims, err := middleware.ParseInvitationToken(c.Param("token"), ctrl.Cfg)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found or expired"})
+		return
+	}
+
+	var invitation models.ProjectInvitation
+	err = ctrl.DB.Where("id = ? AND email = ? AND accepted_at IS NULL", claims.InvitationID, claims.Email).
+		Preload("Project").
+		Preload("Inviter").
+		First(&invitation).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found or expired"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invitation": gin.H{
+			"email":      invitation.Email,
+			"role":       invitation.Role,
+			"project":    invitation.Project.Name,
+			"invited_by": invitation.Inviter.Username,
+			"expires_at": invitation.ExpiresAt,
+		},
+	})
+}
+
+// AcceptInvitation lets a signed-in user join the projects they were invited
+// to from the token of the emailed link, when the address already had an
+// account or registered without the link.
+func (ctrl *Controller) AcceptInvitation(c *gin.Context) {
+	var req models.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := middleware.ParseInvitationToken(req.Token, ctrl.Cfg)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found or expired"})
+		return
+	}
+
+	var user models.User
+	if err := ctrl.DB.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if !strings.EqualFold(claims.Email, user.Email) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The invitation was sent to another email address"})
+		return
+	}
+
+	var invitation models.ProjectInvitation
+	err = ctrl.DB.Where("id = ? AND email = ? AND accepted_at IS NULL AND expires_at > ?", claims.InvitationID, claims.Email, time.Now()).
+		First(&invitation).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found or expired"})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	joined, err := ctrl.acceptPendingInvitations(tx, &user)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept pending invitations"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept pending invitations"})
+		return
+	}
+
+	ctrl.emitJoined(&user, joined)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Invitation accepted successfully",
+		"joined_projects": joined,
+	})
+}
+
+// acceptPendingInvitations turns the unexpired invitations sent to the user's
+// email into project participations and returns the joined project IDs. The
+// caller must have checked that the email is the user's: through the emailed
+// invitation link, or an identity provider that verified it. It runs within
+// the caller's transaction.
+func (ctrl *Controller) acceptPendingInvitations(tx *gorm.DB, user *models.User) ([]uint, error) {
+	joined := []uint{}
+
+	var invitations []models.ProjectInvitation
+	err := tx.Where("email = ? AND accepted_at IS NULL AND expires_at > ?",
+		strings.ToLower(user.Email), time.Now()).Find(&invitations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, invitation := range invitations {
+		var existing models.ProjectParticipant
+		if tx.Where("project_id = ? AND user_id = ?", invitation.ProjectID, user.ID).First(&existing).RecordNotFound() {
+			participant := models.ProjectParticipant{
+				ProjectID: invitation.ProjectID,
+				UserID:    user.ID,
+				Role:      invitation.Role,
+			}
+			if err := tx.Create(&participant).Error; err != nil {
+				return nil, err
+			}
+			joined = append(joined, invitation.ProjectID)
+		}
+
+		if err := tx.Model(&invitation).Update("accepted_at", now).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return joined, nil
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"dod-backend/middleware"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// Failed logins past loginDelayAfter delay the next attempt, doubling from
+// loginDelayBase, until LockoutThreshold locks the account for
+// LockoutDuration. Each further failure relocks it until a login succeeds.
+const (
+	loginDelayAfter = 3
+	loginDelayBase  = time.Second
+
+	// Doublings past this would overflow time.Duration
+	loginDelayMaxDoublings = 30
+)
+
+// checkLoginLock writes a 429 and returns false while the account is locked.
+func (ctrl *Controller) checkLoginLock(c *gin.Context, user *models.User) bool {
+	if user.LockedUntil == nil || !time.Now().Before(*user.LockedUntil) {
+		return true
+	}
+
+	middleware.TooManyRequests(c, time.Until(*user.LockedUntil), "Too many failed login attempts, try again later")
+	return false
+}
+
+func (ctrl *Controller) recordLoginFailure(c *gin.Context, user *models.User) {
+	if ctrl.Cfg.LockoutThreshold <= 0 {
+		return
+	}
+
+	// Counted in the database, so that concurrent failures all add up
+	err := ctrl.DB.Model(&models.User{}).Where("id = ?", user.ID).
+		UpdateColumn("failed_logins", gorm.Expr("failed_logins + 1")).Error
+	if err != nil {
+		log.Printf("failed to record login failure for user %d: %v", user.ID, err)
+		return
+	}
+	var counts []int
+	if err := ctrl.DB.Model(&models.User{}).Where("id = ?", user.ID).Pluck("failed_logins", &counts).Error; err != nil || len(counts) == 0 {
+		log.Printf("failed to read login failures for user %d: %v", user.ID, err)
+		return
+	}
+	failures := counts[0]
+
+	var delay time.Duration
+	locked := failures >= ctrl.Cfg.LockoutThreshold
+	if locked {
+		delay = ctrl.Cfg.LockoutDuration
+	} else if failures >= loginDelayAfter {
+		delay = loginDelayBase << uint(min(failures-loginDelayAfter, loginDelayMaxDoublings))
+		if delay > ctrl.Cfg.LockoutDuration {
+			delay = ctrl.Cfg.LockoutDuration
+		}
+	}
+	if delay == 0 {
+		return
+	}
+
+	err = ctrl.DB.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("locked_until", time.Now().Add(delay)).Error
+	if err != nil {
+		log.Printf("failed to record login failure for user %d: %v", user.ID, err)
+		return
+	}
+
+	if locked {
+		ctrl.audit(ctrl.DB, c, models.AuditAccountLocked, &user.ID,
+			fmt.Sprintf("locked for %s after %d failed login attempts", delay, failures))
+	}
+}
+
+func (ctrl *Controller) resetLoginFailures(user *models.User) {
+	if user.FailedLogins == 0 && user.LockedUntil == nil {
+		return
+	}
+
+	err := ctrl.DB.Model(&models.User{}).Where("id = ?", user.ID).
+		UpdateColumns(map[string]interface{}{"failed_logins": 0, "locked_until": nil}).Error
+	if err != nil {
+		log.Printf("failed to reset login failures for user %d: %v", user.ID, err)
+	}
+}
+
+// audit records a security event. Failing to do so must not fail the request
+// it happens in, so errors are only logged.
+func (ctrl *Controller) audit(db *gorm.DB, c *gin.Context, action string, userID *uint, details string) {
+	entry := models.AuditLog{
+		Action:  action,
+		UserID:  userID,
+		IP:      c.ClientIP(),
+		Details: details,
+	}
+	if err := db.Create(&entry).Error; err != nil {
+		log.Printf("failed to write audit entry %q: %v", action, err)
+	}
+}
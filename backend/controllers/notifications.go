@@ -0,0 +1,368 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"dod-backend/chat"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// waiverReminderWindow is how long before their expiry waivers are
+// announced to chat channels.
+const waiverReminderWindow = 24 * time.Hour
+
+// Notification Channel Controllers
+//
+// Project owners route events to the chat incoming webhooks of their team,
+// Slack or Microsoft Teams, each channel with its own selection of events.
+func (ctrl *Controller) GetProjectNotificationChannels(c *gin.Context) {
+	project, ok := ctrl.loadIntegrationProject(c)
+	if !ok {
+		return
+	}
+
+	var channels []models.NotificationChannel
+	if err := ctrl.DB.Where("project_id = ?", project.ID).Order("id").Find(&channels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification channels"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"channels": channels, "events": models.NotificationEvents})
+}
+
+func (ctrl *Controller) CreateProjectNotificationChannel(c *gin.Context) {
+	project, ok := ctrl.loadIntegrationProject(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateNotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !checkEvents(c, req.Events, models.NotificationEvents) {
+		return
+	}
+
+	channel := models.NotificationChannel{
+		ProjectID: project.ID,
+		Name:      req.Name,
+		Kind:      req.Kind,
+		Active:    true,
+		CreatedBy: c.GetUint("user_id"),
+	}
+	channel.SetWebhookURL(req.WebhookURL)
+	if err := channel.SetEvents(req.Events); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := ctrl.DB.Create(&channel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create notification channel"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Notification channel created successfully",
+		"channel": channel,
+	})
+}
+
+func (ctrl *Controller) UpdateProjectNotificationChannel(c *gin.Context) {
+	channel, ok := ctrl.loadNotificationChannel(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateNotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name != nil {
+		channel.Name = *req.Name
+	}
+	if req.WebhookURL != nil {
+		channel.SetWebhookURL(*req.WebhookURL)
+		channel.LastError = ""
+	}
+	if req.Events != nil {
+		if !checkEvents(c, req.Events, models.NotificationEvents) {
+			return
+		}
+		if err := channel.SetEvents(req.Events); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Active != nil {
+		channel.Active = *req.Active
+	}
+
+	if err := ctrl.DB.Save(channel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification channel"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Notification channel updated successfully",
+		"channel": channel,
+	})
+}
+
+func (ctrl *Controller) DeleteProjectNotificationChannel(c *gin.Context) {
+	channel, ok := ctrl.loadNotificationChannel(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.DB.Delete(channel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notification channel"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification channel deleted successfully"})
+}
+
+// TestProjectNotificationChannel sends a sample message right away, whatever
+// the events of the channel and even when it is inactive.
+func (ctrl *Controller) TestProjectNotificationChannel(c *gin.Context) {
+	channel, ok := ctrl.loadNotificationChannel(c)
+	if !ok {
+		return
+	}
+
+	var project models.Project
+	if err := ctrl.DB.First(&project, channel.ProjectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	notification := chat.Notification{
+		Title: "Test notification from DoD Manager",
+		Text:  fmt.Sprintf("Channel %q of project %q is set up.", channel.Name, project.Name),
+		Facts: []chat.Fact{
+			{Name: "Events", Value: strings.Join(channel.EventList, ", ")},
+			{Name: "Sent by", Value: c.GetString("username")},
+		},
+		URL: ctrl.projectURL(project.ID),
+	}
+	if err := ctrl.sendNotification(channel, notification); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send test notification: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Test notification sent",
+		"channel": channel,
+	})
+}
+
+// Notification helpers
+
+// loadNotificationChannel resolves the :id/:channelId route parameters,
+// writing the error response itself.
+func (ctrl *Controller) loadNotificationChannel(c *gin.Context) (*models.NotificationChannel, bool) {
+	project, ok := ctrl.loadIntegrationProject(c)
+	if !ok {
+		return nil, false
+	}
+
+	channelID, err := strconv.Atoi(c.Param("channelId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification channel ID"})
+		return nil, false
+	}
+
+	var channel models.NotificationChannel
+	if err := ctrl.DB.Where("id = ? AND project_id = ?", channelID, project.ID).First(&channel).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification channel not found"})
+		return nil, false
+	}
+	return &channel, true
+}
+
+// sendNotification posts to the channel and records the outcome on it.
+func (ctrl *Controller) sendNotification(channel *models.NotificationChannel, notification chat.Notification) error {
+	client := ctrl.Chat
+	if client == nil {
+		client = chat.NewClient()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := client.Send(ctx, channel.Kind, channel.WebhookURL, notification)
+	// The webhook URL is the secret of the channel, keep it out of the error
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	updates := map[string]interface{}{"last_error": ""}
+	if err != nil {
+		updates["last_error"] = err.Error()
+	} else {
+		updates["last_sent_at"] = time.Now()
+	}
+	if err := ctrl.DB.Model(channel).Updates(updates).Error; err != nil {
+		log.Printf("Failed to record delivery to notification channel %d: %v", channel.ID, err)
+	}
+	return err
+}
+
+// notify posts the notification to the active channels of the project
+// subscribed to the event, in the background. Failures are also kept on the
+// channel, as its last error.
+func (ctrl *Controller) notify(projectID uint, event string, notification chat.Notification) {
+	ctrl.background(fmt.Sprintf("notify project %d of %s", projectID, event), func() error {
+		var channels []models.NotificationChannel
+		if err := ctrl.DB.Where("project_id = ? AND active = ?", projectID, true).Find(&channels).Error; err != nil {
+			return err
+		}
+
+		var errs []error
+		for i := range channels {
+			if !channels[i].Subscribes(event) {
+				continue
+			}
+			if err := ctrl.sendNotification(&channels[i], notification); err != nil {
+				errs = append(errs, fmt.Errorf("channel %d: %w", channels[i].ID, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// notifyWorkItemBlocked announces a work item held back by unmet required
+// items.
+func (ctrl *Controller) notifyWorkItemBlocked(workItem *models.WorkItem, reason string, unmet []string) {
+	ctrl.notify(workItem.ProjectID, models.NotifyWorkItemBlocked, chat.Notification{
+		Title: "Work item blocked: " + workItemName(workItem),
+		Text:  reason,
+		Facts: []chat.Fact{
+			{Name: "Project", Value: ctrl.projectName(workItem.ProjectID)},
+			{Name: "Unmet required items", Value: strings.Join(unmet, ", ")},
+		},
+		URL: ctrl.workItemURL(workItem),
+	})
+}
+
+// notifyWaiver announces a waiver about to expire, or expired.
+func (ctrl *Controller) notifyWaiver(event string, waiver *models.Waiver, workItem *models.WorkItem) {
+	title, text := "Waiver expiring: ", "The waiver on %q for %s expires on %s."
+	if event == models.NotifyWaiverExpired {
+		title, text = "Waiver expired: ", "The waiver on %q for %s expired on %s."
+	}
+
+	facts := []chat.Fact{
+		{Name: "Project", Value: ctrl.projectName(waiver.ProjectID)},
+		{Name: "Justification", Value: waiver.Justification},
+		{Name: "Approved by", Value: waiver.Approver.Username},
+	}
+	if waiver.FollowUpRef != "" {
+		facts = append(facts, chat.Fact{Name: "Follow-up", Value: waiver.FollowUpRef})
+	}
+
+	ctrl.notify(waiver.ProjectID, event, chat.Notification{
+		Title: title + waiver.Item.Title,
+		Text:  fmt.Sprintf(text, waiver.Item.Title, workItemName(workItem), waiver.ExpiresAt.Format("January 2, 2006 15:04 MST")),
+		Facts: facts,
+		URL:   ctrl.workItemURL(workItem),
+	})
+}
+
+// notifyDoDPublished announces a new revision of a DoD.
+func (ctrl *Controller) notifyDoDPublished(dod *models.DoD, revision *models.DoDRevision, publisher string) {
+	text := revision.Changelog
+	if text == "" {
+		text = "No changelog was given."
+	}
+
+	ctrl.notify(dod.ProjectID, models.NotifyDoDPublished, chat.Notification{
+		Title: fmt.Sprintf("DoD published: %s (revision %d)", dod.Title, revision.Number),
+		Text:  text,
+		Facts: []chat.Fact{
+			{Name: "Project", Value: ctrl.projectName(dod.ProjectID)},
+			{Name: "Items", Value: strconv.Itoa(len(revision.Items))},
+			{Name: "Published by", Value: publisher},
+		},
+		URL: ctrl.projectURL(dod.ProjectID),
+	})
+}
+
+// RemindExpiringWaivers announces once the approved waivers running out
+// within waiverReminderWindow of now.
+func (ctrl *Controller) RemindExpiringWaivers(now time.Time) (int, error) {
+	var waivers []models.Waiver
+	err := ctrl.DB.Where("status = ? AND reminded_at IS NULL AND expires_at > ? AND expires_at <= ?",
+		models.WaiverApproved, now, now.Add(waiverReminderWindow)).
+		Preload("Approver").
+		Preload("Item").
+		Find(&waivers).Error
+	if err != nil {
+		return 0, err
+	}
+
+	reminded := 0
+	for i := range waivers {
+		if err := ctrl.remindWaiver(&waivers[i], now); err != nil {
+			log.Printf("Failed to remind of waiver %d: %v", waivers[i].ID, err)
+			continue
+		}
+		reminded++
+	}
+	return reminded, nil
+}
+
+func (ctrl *Controller) remindWaiver(waiver *models.Waiver, now time.Time) error {
+	var workItem models.WorkItem
+	if err := ctrl.DB.First(&workItem, waiver.WorkItemID).Error; err != nil {
+		return err
+	}
+	if err := ctrl.DB.Model(waiver).Update("reminded_at", now).Error; err != nil {
+		return err
+	}
+	ctrl.notifyWaiver(models.NotifyWaiverExpiring, waiver, &workItem)
+	return nil
+}
+
+func itemTitles(items []models.DoDItem) []string {
+	titles := make([]string, 0, len(items))
+	for _, item := range items {
+		titles = append(titles, item.Title)
+	}
+	return titles
+}
+
+func workItemName(workItem *models.WorkItem) string {
+	if workItem.ExternalRef != "" {
+		return workItem.ExternalRef + " " + workItem.Title
+	}
+	return workItem.Title
+}
+
+func (ctrl *Controller) projectName(projectID uint) string {
+	var project models.Project
+	if err := ctrl.DB.Select("name").First(&project, projectID).Error; err != nil {
+		return fmt.Sprintf("#%d", projectID)
+	}
+	return project.Name
+}
+
+func (ctrl *Controller) projectURL(projectID uint) string {
+	return fmt.Sprintf("%s/projects/%d", ctrl.Cfg.AppURL, projectID)
+}
+
+func (ctrl *Controller) workItemURL(workItem *models.WorkItem) string {
+	return fmt.Sprintf("%s/projects/%d/work-items/%d", ctrl.Cfg.AppURL, workItem.ProjectID, workItem.ID)
+}
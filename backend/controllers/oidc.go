@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"dod-backend/middleware"
+	"dod-backend/models"
+	"dod-backend/oidc"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+// OpenID Connect Controllers
+
+// OIDCLogin starts the authorization code flow. The frontend keeps the
+// returned login binding and sends the browser to the returned URL; the
+// provider redirects back to the frontend, which posts the code and state to
+// OIDCCallback along with the binding. Without it, a code obtained by someone
+// else could be completed in the victim's browser, signing them into the
+// attacker's account.
+func (ctrl *Controller) OIDCLogin(c *gin.Context) {
+	if ctrl.OIDC == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "OpenID Connect login is not configured"})
+		return
+	}
+
+	loginState := models.OIDCLoginState{ExpiresAt: time.Now().Add(oidcStateTTL)}
+	for _, value := range []*string{&loginState.State, &loginState.Nonce, &loginState.CodeVerifier} {
+		random, err := oidc.RandomString()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+			return
+		}
+		*value = random
+	}
+
+	binding, bindingHash, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+	loginState.BindingHash = bindingHash
+
+	authURL, err := ctrl.OIDC.AuthCodeURL(loginState.State, loginState.Nonce, loginState.CodeVerifier)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider unavailable"})
+		return
+	}
+
+	ctrl.DB.Where("expires_at < ?", time.Now()).Delete(models.OIDCLoginState{})
+	if err := ctrl.DB.Create(&loginState).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL, "login_binding": binding})
+}
+
+func (ctrl *Controller) OIDCCallback(c *gin.Context) {
+	if ctrl.OIDC == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "OpenID Connect login is not configured"})
+		return
+	}
+
+	var req models.OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var loginState models.OIDCLoginState
+	if err := ctrl.DB.Where("state = ? AND expires_at > ?", req.State, time.Now()).First(&loginState).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login state"})
+		return
+	}
+	result := ctrl.DB.Where("id = ?", loginState.ID).Delete(models.OIDCLoginState{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login state"})
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(middleware.HashToken(req.LoginBinding)), []byte(loginState.BindingHash)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "This login was started in another browser"})
+		return
+	}
+
+	claims, err := ctrl.OIDC.Exchange(req.Code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Identity provider rejected the login"})
+		return
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		c.JSON(http.StatusForbidden, gin.H{"error": "A verified email is required"})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	user, err := ctrl.findOrProvisionOIDCUser(tx, claims)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	// Accounts with 2FA keep it, whatever the identity provider enforces
+	if user.TwoFactorEnabled {
+		if err := tx.Commit().Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+			return
+		}
+		ctrl.challengeSecondFactor(c, user)
+		return
+	}
+
+	session, err := ctrl.issueSession(tx, user)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	session["message"] = "Login successful"
+	session["user"] = user
+	c.JSON(http.StatusOK, session)
+}
+
+// findOrProvisionOIDCUser resolves the user linked to the external identity,
+// linking an existing account with the same verified email on first login,
+// or creating a new account.
+func (ctrl *Controller) findOrProvisionOIDCUser(tx *gorm.DB, claims *oidc.Claims) (*models.User, error) {
+	var user models.User
+
+	var identity models.UserIdentity
+	err := tx.Where("issuer = ? AND subject = ?", ctrl.OIDC.Issuer, claims.Subject).First(&identity).Error
+	if err == nil {
+		return &user, tx.First(&user, identity.UserID).Error
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	err = tx.Where("LOWER(email) = ?", strings.ToLower(claims.Email)).First(&user).Error
+	if gorm.IsRecordNotFoundError(err) {
+		if err := ctrl.provisionOIDCUser(tx, &user, claims); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	identity = models.UserIdentity{
+		UserID:  user.ID,
+		Issuer:  ctrl.OIDC.Issuer,
+		Subject: claims.Subject,
+	}
+	if err := tx.Create(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+var usernameUnsafe = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func (ctrl *Controller) provisionOIDCUser(tx *gorm.DB, user *models.User, claims *oidc.Claims) error {
+	base := claims.PreferredUsername
+	if base == "" {
+		base = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	base = usernameUnsafe.ReplaceAllString(base, "")
+	if len(base) < 3 {
+		base = "user"
+	}
+
+	username := base
+	for i := 2; ; i++ {
+		var existing models.User
+		if tx.Where("username = ?", username).First(&existing).RecordNotFound() {
+			break
+		}
+		username = fmt.Sprintf("%s%d", base, i)
+	}
+
+	// No local password: the account can only sign in through the provider
+	// until its owner goes through the password reset flow.
+	random, _, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(random), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	*user = models.User{
+		Username: username,
+		Email:    claims.Email,
+		Password: string(hashedPassword),
+	}
+	if err := tx.Create(user).Error; err != nil {
+		return err
+	}
+
+	_, err = ctrl.acceptPendingInvitations(tx, user)
+	return err
+}
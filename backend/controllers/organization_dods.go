@@ -0,0 +1,549 @@
+package controllers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"dod-backend/authz"
+	"dod-backend/database"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Organization DoD Controllers
+//
+// Organization DoDs are the baseline inherited by every project of the
+// organization: their items are merged into the checklist of each project
+// DoD, required ones locked and optional ones possibly not applicable.
+func (ctrl *Controller) GetOrganizationDoDs(c *gin.Context) {
+	organization, _, ok := ctrl.loadOrganization(c, false)
+	if !ok {
+		return
+	}
+
+	var dods []models.DoD
+	err := ctrl.DB.Where("organization_id = ?", organization.ID).
+		Preload("Items").
+		Preload("Creator").
+		Find(&dods).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch DoDs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dods": dods})
+}
+
+func (ctrl *Controller) CreateOrganizationDoD(c *gin.Context) {
+	organization, _, ok := ctrl.loadOrganization(c, true)
+	if !ok {
+		return
+	}
+
+	var req models.CreateOrganizationDoDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dod := models.DoD{
+		Title:          req.Title,
+		Description:    req.Description,
+		OrganizationID: &organization.ID,
+		CreatedBy:      c.GetUint("user_id"),
+		IsActive:       true,
+		Kind:           dodKind(req.Kind),
+	}
+
+	if err := ctrl.DB.Create(&dod).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create DoD"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "DoD created successfully",
+		"dod":     dod,
+	})
+}
+
+func (ctrl *Controller) UpdateOrganizationDoD(c *gin.Context) {
+	dod, ok := ctrl.loadOrganizationDoD(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateDoDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Title != nil {
+		updates["title"] = *req.Title
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	if req.Kind != nil {
+		updates["kind"] = *req.Kind
+	}
+
+	if err := ctrl.DB.Model(dod).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update DoD"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "DoD updated successfully",
+		"dod":     dod,
+	})
+}
+
+func (ctrl *Controller) DeleteOrganizationDoD(c *gin.Context) {
+	dod, ok := ctrl.loadOrganizationDoD(c)
+	if !ok {
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := deleteDoDsCascade(tx, []uint{dod.ID}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DoD"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DoD"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "DoD deleted successfully"})
+}
+
+func (ctrl *Controller) AddOrganizationDoDItem(c *gin.Context) {
+	dod, ok := ctrl.loadOrganizationDoD(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateDoDItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item := models.DoDItem{
+		DoDID:       dod.ID,
+		Title:       req.Title,
+		Description: req.Description,
+		IsRequired:  req.IsRequired,
+		Order:       req.Order,
+	}
+	if !ctrl.setVerification(c, &item, &req.Verification, &req.ApproverRole, &req.RequiredApprovals) {
+		return
+	}
+	if !setAutoCheck(c, &item, &req.AutoCheck, req.AutoCheckThreshold) {
+		return
+	}
+
+	if err := database.CreateDoDItem(ctrl.DB, &item); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create DoD item"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "DoD item added successfully",
+		"item":    item,
+	})
+}
+
+func (ctrl *Controller) UpdateOrganizationDoDItem(c *gin.Context) {
+	item, ok := ctrl.loadOrganizationDoDItem(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateDoDItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Title != nil {
+		updates["title"] = *req.Title
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.IsRequired != nil {
+		updates["is_required"] = *req.IsRequired
+	}
+	if req.Order != nil {
+		updates["order"] = *req.Order
+	}
+	if req.Verification != nil || req.ApproverRole != nil || req.RequiredApprovals != nil {
+		if !ctrl.setVerification(c, item, req.Verification, req.ApproverRole, req.RequiredApprovals) {
+			return
+		}
+		updates["verification"] = item.Verification
+		updates["approver_role"] = item.ApproverRole
+		updates["required_approvals"] = item.RequiredApprovals
+	}
+	if req.AutoCheck != nil || req.AutoCheckThreshold != nil {
+		if !setAutoCheck(c, item, req.AutoCheck, req.AutoCheckThreshold) {
+			return
+		}
+		updates["auto_check"] = item.AutoCheck
+		updates["auto_check_threshold"] = item.AutoCheckThreshold
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := tx.Model(item).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update DoD item"})
+		return
+	}
+	// An item made required can no longer be waived by projects
+	if item.IsRequired {
+		if err := tx.Where("do_d_item_id = ?", item.ID).Delete(models.NotApplicableItem{}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update DoD item"})
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update DoD item"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "DoD item updated successfully",
+		"item":    item,
+	})
+}
+
+func (ctrl *Controller) DeleteOrganizationDoDItem(c *gin.Context) {
+	item, ok := ctrl.loadOrganizationDoDItem(c)
+	if !ok {
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := tx.Where("do_d_item_id = ?", item.ID).Delete(models.DoDItemCompletion{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DoD item"})
+		return
+	}
+	if err := tx.Where("do_d_item_id = ?", item.ID).Delete(models.ItemDecision{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DoD item"})
+		return
+	}
+	if err := tx.Where("do_d_item_id = ?", item.ID).Delete(models.Waiver{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DoD item"})
+		return
+	}
+	if err := tx.Where("do_d_item_id = ?", item.ID).Delete(models.NotApplicableItem{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DoD item"})
+		return
+	}
+	if err := tx.Delete(item).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DoD item"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DoD item"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "DoD item deleted successfully"})
+}
+
+// loadOrganizationDoD resolves the :id/:dodId route parameters for the
+// organization admins, writing the error response itself.
+func (ctrl *Controller) loadOrganizationDoD(c *gin.Context) (*models.DoD, bool) {
+	organization, _, ok := ctrl.loadOrganization(c, true)
+	if !ok {
+		return nil, false
+	}
+
+	dodID, err := strconv.Atoi(c.Param("dodId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid DoD ID"})
+		return nil, false
+	}
+
+	var dod models.DoD
+	if err := ctrl.DB.Where("id = ? AND organization_id = ?", dodID, organization.ID).First(&dod).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DoD not found"})
+		return nil, false
+	}
+
+	return &dod, true
+}
+
+func (ctrl *Controller) loadOrganizationDoDItem(c *gin.Context) (*models.DoDItem, bool) {
+	dod, ok := ctrl.loadOrganizationDoD(c)
+	if !ok {
+		return nil, false
+	}
+
+	itemID, err := strconv.Atoi(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid DoD item ID"})
+		return nil, false
+	}
+
+	var item models.DoDItem
+	if err := ctrl.DB.Where("id = ? AND do_d_id = ?", itemID, dod.ID).First(&item).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DoD item not found"})
+		return nil, false
+	}
+
+	return &item, true
+}
+
+// inheritedItems returns the items of the active baselines of the given kind
+// (any kind when empty) of the project's organization, with the optional ones
+// the project marked not applicable.
+func (ctrl *Controller) inheritedItems(projectID uint, kind string) ([]models.EffectiveDoDItem, error) {
+	items, err := ctrl.baselineItems(projectID, kind)
+	if err != nil {
+		return nil, err
+	}
+	return ctrl.inherit(projectID, items)
+}
+
+// baselineItems returns the items of the active baselines of the given kind
+// (any kind when empty) of the project's organization, as they are now.
+func (ctrl *Controller) baselineItems(projectID uint, kind string) ([]models.DoDItem, error) {
+	var project models.Project
+	if err := ctrl.DB.Select("id, organization_id").First(&project, projectID).Error; err != nil {
+		return nil, err
+	}
+	if project.OrganizationID == nil {
+		return []models.DoDItem{}, nil
+	}
+
+	query := ctrl.DB.Model(&models.DoD{}).
+		Where("organization_id = ? AND is_active = ?", *project.OrganizationID, true)
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	var dodIDs []uint
+	err := query.Pluck("id", &dodIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var items []models.DoDItem
+	if err := ctrl.DB.Where("do_d_id IN (?)", dodIDs).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].DoDID != items[j].DoDID {
+			return items[i].DoDID < items[j].DoDID
+		}
+		return items[i].Order < items[j].Order
+	})
+	return items, nil
+}
+
+// inherit marks baseline items as inherited by the project, with the optional
+// ones the project marked not applicable.
+func (ctrl *Controller) inherit(projectID uint, items []models.DoDItem) ([]models.EffectiveDoDItem, error) {
+	var marks []models.NotApplicableItem
+	if err := ctrl.DB.Where("project_id = ?", projectID).Find(&marks).Error; err != nil {
+		return nil, err
+	}
+	reasons := make(map[uint]string, len(marks))
+	for _, mark := range marks {
+		reasons[mark.DoDItemID] = mark.Reason
+	}
+
+	inherited := make([]models.EffectiveDoDItem, 0, len(items))
+	for _, item := range items {
+		reason, notApplicable := reasons[item.ID]
+		notApplicable = notApplicable && !item.IsRequired
+		if !notApplicable {
+			reason = ""
+		}
+		inherited = append(inherited, models.EffectiveDoDItem{
+			DoDItem:             item,
+			Inherited:           true,
+			Locked:              item.IsRequired,
+			NotApplicable:       notApplicable,
+			NotApplicableReason: reason,
+		})
+	}
+	return inherited, nil
+}
+
+// effectiveItems merges the inherited baseline of the given kind with the
+// items of the given project DoDs, inherited items first.
+func (ctrl *Controller) effectiveItems(projectID uint, kind string, dodIDs []uint) ([]models.EffectiveDoDItem, error) {
+	effective, err := ctrl.inheritedItems(projectID, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []models.DoDItem
+	if err := ctrl.DB.Where("do_d_id IN (?)", dodIDs).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Order < items[j].Order })
+
+	for _, item := range items {
+		effective = append(effective, models.EffectiveDoDItem{DoDItem: item})
+	}
+	return effective, nil
+}
+
+// Inheritance Controllers
+func (ctrl *Controller) GetEffectiveDoD(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, uint(projectID), authz.ProjectView, "No access to this project") {
+		return
+	}
+
+	// Either one DoD of the project, or all its active ones of a kind
+	kind := c.DefaultQuery("kind", models.DoDKindDone)
+	if !validDoDKind(kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid checklist kind"})
+		return
+	}
+
+	var dodIDs []uint
+	if dodParam := c.Query("dod_id"); dodParam != "" {
+		dodID, err := strconv.Atoi(dodParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid DoD ID"})
+			return
+		}
+		var dod models.DoD
+		if err := ctrl.DB.Where("id = ? AND project_id = ?", dodID, projectID).First(&dod).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "DoD does not belong to this project"})
+			return
+		}
+		kind = dod.Kind
+		dodIDs = []uint{dod.ID}
+	} else {
+		err := ctrl.DB.Model(&models.DoD{}).
+			Where("project_id = ? AND is_active = ? AND kind = ?", projectID, true, kind).
+			Pluck("id", &dodIDs).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch DoDs"})
+			return
+		}
+	}
+
+	items, err := ctrl.effectiveItems(uint(projectID), kind, dodIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute effective DoD"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"project_id": projectID,
+		"kind":       kind,
+		"dod_ids":    dodIDs,
+		"items":      items,
+	})
+}
+
+func (ctrl *Controller) MarkItemNotApplicable(c *gin.Context) {
+	projectID, item, ok := ctrl.loadInheritedItem(c)
+	if !ok {
+		return
+	}
+
+	var req models.MarkNotApplicableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if item.Locked {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Required organization items are locked"})
+		return
+	}
+
+	var mark models.NotApplicableItem
+	ctrl.DB.Where("project_id = ? AND do_d_item_id = ?", projectID, item.ID).
+		FirstOrInit(&mark, models.NotApplicableItem{ProjectID: projectID, DoDItemID: item.ID})
+	mark.Reason = req.Reason
+	mark.CreatedBy = c.GetUint("user_id")
+
+	if err := ctrl.DB.Save(&mark).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark item not applicable"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Item marked not applicable",
+		"not_applicable": mark,
+	})
+}
+
+func (ctrl *Controller) ClearItemNotApplicable(c *gin.Context) {
+	projectID, item, ok := ctrl.loadInheritedItem(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.DB.Where("project_id = ? AND do_d_item_id = ?", projectID, item.ID).Delete(models.NotApplicableItem{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore item"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Item applies again"})
+}
+
+// loadInheritedItem resolves the :id/:itemId route parameters to an item
+// inherited by the project, writing the error response itself.
+func (ctrl *Controller) loadInheritedItem(c *gin.Context) (uint, *models.EffectiveDoDItem, bool) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return 0, nil, false
+	}
+
+	itemID, err := strconv.Atoi(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid DoD item ID"})
+		return 0, nil, false
+	}
+
+	if !authz.Require(c, ctrl.DB, uint(projectID), authz.DoDUpdate, "No permission to edit this project's DoDs") {
+		return 0, nil, false
+	}
+
+	inherited, err := ctrl.inheritedItems(uint(projectID), "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch inherited items"})
+		return 0, nil, false
+	}
+	for i := range inherited {
+		if inherited[i].ID == uint(itemID) {
+			return uint(projectID), &inherited[i], true
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Inherited item not found"})
+	return 0, nil, false
+}
@@ -0,0 +1,379 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"dod-backend/authz"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// Organization Controllers
+func (ctrl *Controller) CreateOrganization(c *gin.Context) {
+	var req models.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	organization := models.Organization{
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedBy:   userID,
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := tx.Create(&organization).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+		return
+	}
+
+	member := models.OrganizationMember{
+		OrganizationID: organization.ID,
+		UserID:         userID,
+		Role:           authz.OrgRoleAdmin,
+	}
+	if err := tx.Create(&member).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Organization created successfully",
+		"organization": organization,
+	})
+}
+
+// GetUserOrganizations lists the organizations of the caller along with
+// their role in each.
+func (ctrl *Controller) GetUserOrganizations(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var memberships []models.OrganizationMember
+	if err := ctrl.DB.Where("user_id = ?", userID).Find(&memberships).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organizations"})
+		return
+	}
+
+	organizations := []gin.H{}
+	for _, membership := range memberships {
+		var organization models.Organization
+		if err := ctrl.DB.First(&organization, membership.OrganizationID).Error; err != nil {
+			continue
+		}
+		organizations = append(organizations, gin.H{
+			"organization": organization,
+			"role":         membership.Role,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organizations": organizations})
+}
+
+func (ctrl *Controller) GetOrganization(c *gin.Context) {
+	organization, _, ok := ctrl.loadOrganization(c, false)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.DB.Preload("Members.User").First(organization, organization.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organization"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organization": organization})
+}
+
+func (ctrl *Controller) UpdateOrganization(c *gin.Context) {
+	organization, _, ok := ctrl.loadOrganization(c, true)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+
+	if err := ctrl.DB.Model(organization).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update organization"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Organization updated successfully",
+		"organization": organization,
+	})
+}
+
+// DeleteOrganization only removes empty organizations, so that projects are
+// never deleted as a side effect.
+func (ctrl *Controller) DeleteOrganization(c *gin.Context) {
+	organization, _, ok := ctrl.loadOrganization(c, true)
+	if !ok {
+		return
+	}
+
+	var projectCount int
+	ctrl.DB.Model(&models.Project{}).Where("organization_id = ?", organization.ID).Count(&projectCount)
+	if projectCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Move or delete the organization's projects first"})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := deleteOrganizationCascade(tx, organization.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Organization deleted successfully"})
+}
+
+// Organization Member Controllers
+func (ctrl *Controller) AddOrganizationMember(c *gin.Context) {
+	organization, _, ok := ctrl.loadOrganization(c, true)
+	if !ok {
+		return
+	}
+
+	var req models.AddOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := ctrl.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.ServiceAccount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API keys cannot join organizations"})
+		return
+	}
+
+	member := models.OrganizationMember{
+		OrganizationID: organization.ID,
+		UserID:         user.ID,
+		Role:           req.Role,
+	}
+	if err := ctrl.DB.Create(&member).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User already member"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Member added successfully",
+		"member":  member,
+	})
+}
+
+func (ctrl *Controller) UpdateOrganizationMember(c *gin.Context) {
+	organization, _, ok := ctrl.loadOrganization(c, true)
+	if !ok {
+		return
+	}
+
+	member, ok := ctrl.loadOrganizationMember(c, organization.ID)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if member.Role == authz.OrgRoleAdmin && req.Role != authz.OrgRoleAdmin && ctrl.lastOrganizationAdmin(organization.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An organization needs at least one admin"})
+		return
+	}
+
+	if err := ctrl.DB.Model(member).Update("role", req.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member role updated successfully",
+		"member":  member,
+	})
+}
+
+// RemoveOrganizationMember lets admins remove anyone, and members leave.
+func (ctrl *Controller) RemoveOrganizationMember(c *gin.Context) {
+	organization, role, ok := ctrl.loadOrganization(c, false)
+	if !ok {
+		return
+	}
+
+	member, ok := ctrl.loadOrganizationMember(c, organization.ID)
+	if !ok {
+		return
+	}
+
+	if role != authz.OrgRoleAdmin && member.UserID != c.GetUint("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only organization admins can remove members"})
+		return
+	}
+
+	if member.Role == authz.OrgRoleAdmin && ctrl.lastOrganizationAdmin(organization.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An organization needs at least one admin"})
+		return
+	}
+
+	if err := ctrl.DB.Delete(member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// MoveProjectToOrganization hands a project over to an organization the
+// caller belongs to.
+func (ctrl *Controller) MoveProjectToOrganization(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var req models.MoveProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var project models.Project
+	if err := ctrl.DB.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, project.ID, authz.ProjectTransfer, "Only project owner can move the project") {
+		return
+	}
+
+	role, err := authz.OrgRole(ctrl.DB, req.OrganizationID, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return
+	}
+	if role == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this organization"})
+		return
+	}
+
+	if err := ctrl.DB.Model(&project).Update("organization_id", req.OrganizationID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Project moved successfully",
+		"project": project,
+	})
+}
+
+// loadOrganization resolves the :id route parameter for members of the
+// organization, or its admins only, writing the error response itself.
+func (ctrl *Controller) loadOrganization(c *gin.Context, adminOnly bool) (*models.Organization, string, bool) {
+	organizationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return nil, "", false
+	}
+
+	var organization models.Organization
+	if err := ctrl.DB.First(&organization, organizationID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return nil, "", false
+	}
+
+	role, err := authz.OrgRole(ctrl.DB, organization.ID, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return nil, "", false
+	}
+	if role == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "No access to this organization"})
+		return nil, "", false
+	}
+	if adminOnly && role != authz.OrgRoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only organization admins can manage the organization"})
+		return nil, "", false
+	}
+
+	return &organization, role, true
+}
+
+func (ctrl *Controller) loadOrganizationMember(c *gin.Context, organizationID uint) (*models.OrganizationMember, bool) {
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+
+	var member models.OrganizationMember
+	if err := ctrl.DB.Where("organization_id = ? AND user_id = ?", organizationID, userID).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return nil, false
+	}
+
+	return &member, true
+}
+
+func (ctrl *Controller) lastOrganizationAdmin(organizationID uint) bool {
+	var admins int
+	ctrl.DB.Model(&models.OrganizationMember{}).
+		Where("organization_id = ? AND role = ?", organizationID, authz.OrgRoleAdmin).
+		Count(&admins)
+	return admins <= 1
+}
+
+func deleteOrganizationCascade(tx *gorm.DB, organizationID uint) error {
+	var dodIDs []uint
+	if err := tx.Model(&models.DoD{}).Where("organization_id = ?", organizationID).Pluck("id", &dodIDs).Error; err != nil {
+		return err
+	}
+	if err := deleteDoDsCascade(tx, dodIDs); err != nil {
+		return err
+	}
+	// Shared templates go back to their creators
+	if err := tx.Model(&models.DoDTemplate{}).Where("organization_id = ?", organizationID).Update("organization_id", nil).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("organization_id = ?", organizationID).Delete(models.OrganizationMember{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("id = ?", organizationID).Delete(models.Organization{}).Error
+}
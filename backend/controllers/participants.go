@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"dod-backend/authz"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Participant Controllers
+func (ctrl *Controller) GetProjectParticipants(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, uint(projectID), authz.ProjectView, "No access to this project") {
+		return
+	}
+
+	var participants []models.ProjectParticipant
+	err = ctrl.DB.Where("project_id = ?", projectID).
+		Preload("User").
+		Find(&participants).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch participants"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"participants": participants})
+}
+
+func (ctrl *Controller) UpdateParticipantRole(c *gin.Context) {
+	project, participant, ok := ctrl.loadManagedParticipant(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateParticipantRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if participant.UserID == project.OwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Transfer ownership before changing the owner's role"})
+		return
+	}
+
+	if !ctrl.checkAssignableRole(c, project.ID, req.Role) {
+		return
+	}
+
+	if err := ctrl.DB.Model(participant).Update("role", req.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update participant"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Participant role updated successfully",
+		"participant": participant,
+	})
+}
+
+func (ctrl *Controller) RemoveProjectParticipant(c *gin.Context) {
+	project, participant, ok := ctrl.loadManagedParticipant(c)
+	if !ok {
+		return
+	}
+
+	if participant.UserID == project.OwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Project owner cannot be removed"})
+		return
+	}
+
+	if err := ctrl.DB.Delete(participant).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove participant"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Participant removed successfully"})
+}
+
+func (ctrl *Controller) LeaveProject(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var project models.Project
+	if err := ctrl.DB.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if project.OwnerID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Transfer ownership before leaving the project"})
+		return
+	}
+
+	var participant models.ProjectParticipant
+	if err := ctrl.DB.Where("project_id = ? AND user_id = ?", project.ID, userID).First(&participant).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not a participant of this project"})
+		return
+	}
+
+	if err := ctrl.DB.Delete(&participant).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left project successfully"})
+}
+
+func (ctrl *Controller) TransferProjectOwnership(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var req models.TransferOwnershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var project models.Project
+	if err := ctrl.DB.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, project.ID, authz.ProjectTransfer, "Only project owner can transfer ownership") {
+		return
+	}
+
+	// Organization admins may transfer on behalf of the owner
+	previousOwnerID := project.OwnerID
+	if req.UserID == previousOwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User already owns this project"})
+		return
+	}
+
+	// The new owner must already be on the project so that no one is granted
+	// access implicitly.
+	var newOwner models.ProjectParticipant
+	if err := ctrl.DB.Where("project_id = ? AND user_id = ?", project.ID, req.UserID).First(&newOwner).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New owner must be a participant of this project"})
+		return
+	}
+
+	if ctrl.isServiceAccount(newOwner.UserID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ownership cannot be transferred to an API key"})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := tx.Model(&project).Update("owner_id", req.UserID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer ownership"})
+		return
+	}
+	if err := tx.Model(&newOwner).Update("role", authz.RoleOwner).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer ownership"})
+		return
+	}
+	err = tx.Model(&models.ProjectParticipant{}).
+		Where("project_id = ? AND user_id = ?", project.ID, previousOwnerID).
+		Update("role", authz.RoleEditor).Error
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer ownership"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer ownership"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Ownership transferred successfully",
+		"project": project,
+	})
+}
+
+// loadManagedParticipant resolves the :id/:userId route parameters for
+// endpoints reserved to participant managers, who must hold all the
+// permissions of the participant's role. It writes the error response itself.
+func (ctrl *Controller) loadManagedParticipant(c *gin.Context) (*models.Project, *models.ProjectParticipant, bool) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return nil, nil, false
+	}
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, nil, false
+	}
+
+	var project models.Project
+	if err := ctrl.DB.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil, nil, false
+	}
+
+	if !authz.Require(c, ctrl.DB, project.ID, authz.ParticipantManage, "No permission to manage participants") {
+		return nil, nil, false
+	}
+
+	var participant models.ProjectParticipant
+	if err := ctrl.DB.Where("project_id = ? AND user_id = ?", project.ID, userID).First(&participant).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found"})
+		return nil, nil, false
+	}
+
+	if ctrl.isServiceAccount(participant.UserID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API key participants are managed through the project API keys"})
+		return nil, nil, false
+	}
+
+	// Nobody changes the standing of someone granted more than them
+	permissions, _, err := authz.RolePermissions(ctrl.DB, project.ID, participant.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return nil, nil, false
+	}
+	if !ctrl.checkCovered(c, project.ID, permissions, "Cannot manage a participant with more permissions than your own") {
+		return nil, nil, false
+	}
+
+	return &project, &participant, true
+}
+
+func (ctrl *Controller) isServiceAccount(userID uint) bool {
+	var user models.User
+	err := ctrl.DB.Where("id = ? AND service_account = ?", userID, true).First(&user).Error
+	return err == nil
+}
+
+// personBehind returns who answers for the actions of the user: the creator
+// of the project API key for service accounts, the user themselves otherwise.
+func (ctrl *Controller) personBehind(userID uint) uint {
+	if !ctrl.isServiceAccount(userID) {
+		return userID
+	}
+	var apiKey models.APIToken
+	if err := ctrl.DB.Where("user_id = ?", userID).First(&apiKey).Error; err != nil {
+		return userID
+	}
+	return apiKey.CreatedBy
+}
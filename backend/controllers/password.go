@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"dod-backend/mailer"
+	"dod-backend/middleware"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const passwordResetTTL = time.Hour
+
+// Password Controllers
+func (ctrl *Controller) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Same answer whether or not the email is known, so it cannot be probed
+	response := gin.H{"message": "If this email is registered, a reset link has been sent"}
+
+	var user models.User
+	if err := ctrl.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	token, hash, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate reset token"})
+		return
+	}
+
+	resetToken := models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}
+
+	tx := ctrl.DB.Begin()
+	// Only the most recent link stays usable
+	if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(models.PasswordResetToken{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reset token"})
+		return
+	}
+	if err := tx.Create(&resetToken).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reset token"})
+		return
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your DoD Manager password",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Someone asked to reset your password. Choose a new one here:\n%s/reset-password?token=%s\n\n"+
+			"This link can be used once and expires in %d minutes. "+
+			"If you did not ask for it, you can ignore this email.\n",
+			user.Username, ctrl.Cfg.AppURL, url.QueryEscape(token), int(passwordResetTTL.Minutes())),
+	}
+	if err := ctrl.Mailer.Send(msg); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send reset email"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reset token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (ctrl *Controller) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var resetToken models.PasswordResetToken
+	err := ctrl.DB.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?",
+		middleware.HashToken(req.Token), time.Now()).First(&resetToken).Error
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	result := tx.Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", resetToken.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	if err := ctrl.setPassword(tx, resetToken.UserID, req.NewPassword); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+func (ctrl *Controller) ChangePassword(c *gin.Context) {
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := ctrl.DB.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := ctrl.setPassword(tx, user.ID, req.NewPassword); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	// Every other session is now logged out; hand the caller a fresh one
+	if err := tx.First(&user, user.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+	session, err := ctrl.issueSession(tx, &user)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	session["message"] = "Password changed successfully"
+	c.JSON(http.StatusOK, session)
+}
+
+// setPassword stores the new hash and invalidates everything that was
+// granted with the old password: sessions and pending reset links.
+func (ctrl *Controller) setPassword(tx *gorm.DB, userID uint, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	// A new password also lifts any lockout, the old one being out of play
+	err = tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"password":      string(hashedPassword),
+		"failed_logins": 0,
+		"locked_until":  nil,
+	}).Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ? AND used_at IS NULL", userID).Delete(models.PasswordResetToken{}).Error; err != nil {
+		return err
+	}
+	return ctrl.invalidateSessions(tx, userID)
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"net/http"
+
+	"dod-backend/authz"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Work Item State Controllers
+//
+// A work item leaves todo, for progress or straight to done, only once the
+// Definition of Ready of its project is met: every required item of the
+// active ready checklists, inherited ones included, must be checked.
+func (ctrl *Controller) UpdateWorkItemState(c *gin.Context) {
+	workItem, ok := ctrl.loadWorkItem(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWorkItemStateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, workItem.ProjectID, authz.WorkItemUpdate, "No permission to edit this work item") {
+		return
+	}
+
+	var err error
+	if workItem.Readiness, err = ctrl.workItemReadiness(workItem); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute work item status"})
+		return
+	}
+
+	starting := workItem.State == models.WorkItemTodo && req.State != models.WorkItemTodo
+	if starting && workItem.Readiness != nil && !workItem.Readiness.Done {
+		ctrl.notifyWorkItemBlocked(workItem, "The work item cannot start: its Definition of Ready is not met.", itemTitles(workItem.Readiness.UnmetRequired))
+		c.JSON(http.StatusConflict, gin.H{
+			"error":          "Work item is not ready",
+			"unmet_required": workItem.Readiness.UnmetRequired,
+		})
+		return
+	}
+
+	// Starting pins the work item to the revision of its DoD in force
+	updates := map[string]interface{}{"state": req.State}
+	if starting && workItem.DoDID != nil && workItem.DoDRevisionID == nil {
+		if workItem.DoDRevisionID, err = ctrl.revisionInForce(*workItem.DoDID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+			return
+		}
+		updates["do_d_revision_id"] = workItem.DoDRevisionID
+	}
+
+	finishing := req.State == models.WorkItemDone && workItem.State != models.WorkItemDone
+	workItem.State = req.State
+	if err := ctrl.DB.Model(workItem).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update work item state"})
+		return
+	}
+
+	if workItem.Status, err = ctrl.workItemStatus(workItem); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute work item status"})
+		return
+	}
+	if finishing && workItem.Status != nil && !workItem.Status.Done {
+		ctrl.notifyWorkItemBlocked(workItem, "The work item was moved to done without meeting its DoD.", itemTitles(workItem.Status.UnmetRequired))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Work item state updated successfully",
+		"work_item": workItem,
+	})
+}
+
+// readyItems returns the Definition of Ready of a work item: the ready
+// baseline of its organization and the items of the project's active ready
+// checklists that apply to it.
+func (ctrl *Controller) readyItems(workItem *models.WorkItem) ([]models.EffectiveDoDItem, error) {
+	_, resolved, _, err := ctrl.resolveChecklist(workItem.ProjectID, models.DoDKindReady, workItem.Attributes())
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.EffectiveDoDItem, 0, len(resolved))
+	for _, item := range resolved {
+		items = append(items, models.EffectiveDoDItem{DoDItem: item.Item, Inherited: item.Inherited})
+	}
+	return items, nil
+}
+
+// workItemReadiness evaluates a work item against the Definition of Ready of
+// its project, or returns nil when the project has none.
+func (ctrl *Controller) workItemReadiness(workItem *models.WorkItem) (*models.WorkItemStatus, error) {
+	items, err := ctrl.readyItems(workItem)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+
+	readiness := &models.WorkItemStatus{
+		Criteria:      []models.WorkItemCriterion{},
+		UnmetRequired: []models.DoDItem{},
+		Waived:        []models.DoDItem{},
+	}
+	return readiness, ctrl.evaluateItems(readiness, workItem.ID, items)
+}
+
+func (ctrl *Controller) isReadyChecklist(dodID uint) bool {
+	var dod models.DoD
+	return ctrl.DB.Where("id = ? AND kind = ?", dodID, models.DoDKindReady).First(&dod).Error == nil
+}
+
+// dodKind defaults an omitted checklist kind to done.
+func dodKind(kind string) string {
+	if kind == "" {
+		return models.DoDKindDone
+	}
+	return kind
+}
+
+func validDoDKind(kind string) bool {
+	switch kind {
+	case models.DoDKindReady, models.DoDKindDone, models.DoDKindRelease, models.DoDKindCustom:
+		return true
+	}
+	return false
+}
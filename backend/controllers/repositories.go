@@ -0,0 +1,508 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"dod-backend/authz"
+	"dod-backend/models"
+	"dod-backend/scm"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// maxWebhookSize bounds the webhooks code hosts send.
+const maxWebhookSize = 5 << 20
+
+// commitStatusContext names the commit status carrying the DoD verdict.
+const commitStatusContext = "dod/definition-of-done"
+
+// Repository Controllers
+//
+// Project owners connect their GitHub and GitLab repositories: their
+// webhooks link pull requests to the work items they reference, tick review
+// checks as approvals arrive, and get the DoD verdict back as a commit status.
+func (ctrl *Controller) GetProjectRepositories(c *gin.Context) {
+	project, ok := ctrl.loadIntegrationProject(c)
+	if !ok {
+		return
+	}
+
+	var repositories []models.Repository
+	if err := ctrl.DB.Where("project_id = ?", project.ID).Order("id").Find(&repositories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch repositories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"repositories": repositories})
+}
+
+func (ctrl *Controller) CreateProjectRepository(c *gin.Context) {
+	project, ok := ctrl.loadIntegrationProject(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateRepositoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The access token is sent to the API URL
+	if !ctrl.apiHostAllowed(req.APIURL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The API URL is not one of the allowed code hosts"})
+		return
+	}
+
+	fullName := strings.Trim(req.FullName, "/")
+	var existing int
+	err := ctrl.DB.Model(&models.Repository{}).
+		Where("project_id = ? AND provider = ? AND LOWER(full_name) = LOWER(?)", project.ID, req.Provider, fullName).
+		Count(&existing).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check repositories"})
+		return
+	}
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This repository is already connected to the project"})
+		return
+	}
+
+	repository := models.Repository{
+		ProjectID:     project.ID,
+		Provider:      req.Provider,
+		FullName:      fullName,
+		APIURL:        strings.TrimSuffix(req.APIURL, "/"),
+		WebhookSecret: req.WebhookSecret,
+		AccessToken:   req.AccessToken,
+		CreatedBy:     c.GetUint("user_id"),
+	}
+	if err := ctrl.DB.Create(&repository).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect repository"})
+		return
+	}
+	repository.HasAccessToken = repository.AccessToken != ""
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Repository connected successfully",
+		"repository":  repository,
+		"webhook_url": fmt.Sprintf("/api/v1/repositories/%d/webhook", repository.ID),
+	})
+}
+
+func (ctrl *Controller) DeleteProjectRepository(c *gin.Context) {
+	project, ok := ctrl.loadIntegrationProject(c)
+	if !ok {
+		return
+	}
+
+	repositoryID, err := strconv.Atoi(c.Param("repositoryId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid repository ID"})
+		return
+	}
+
+	var repository models.Repository
+	if err := ctrl.DB.Where("id = ? AND project_id = ?", repositoryID, project.ID).First(&repository).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Repository not found"})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := tx.Where("repository_id = ?", repository.ID).Delete(models.PullRequest{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disconnect repository"})
+		return
+	}
+	if err := tx.Where("repository_id = ?", repository.ID).Delete(models.WebhookReceipt{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disconnect repository"})
+		return
+	}
+	if err := tx.Delete(&repository).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disconnect repository"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disconnect repository"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Repository disconnected successfully"})
+}
+
+func (ctrl *Controller) GetProjectPullRequests(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, uint(projectID), authz.ProjectView, "No access to this project") {
+		return
+	}
+
+	query := ctrl.DB.Where("project_id = ?", projectID)
+	if workItemID := c.Query("work_item_id"); workItemID != "" {
+		query = query.Where("work_item_id = ?", workItemID)
+	}
+
+	var pullRequests []models.PullRequest
+	if err := query.Order("id desc").Find(&pullRequests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pull requests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pull_requests": pullRequests})
+}
+
+// ReceiveRepositoryWebhook is called by the code host, authenticated by the
+// webhook secret of the repository rather than by a user.
+func (ctrl *Controller) ReceiveRepositoryWebhook(c *gin.Context) {
+	repositoryID, err := strconv.Atoi(c.Param("repositoryId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid repository ID"})
+		return
+	}
+
+	var repository models.Repository
+	if err := ctrl.DB.First(&repository, repositoryID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Repository not found"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookSize))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Webhook is too large"})
+		return
+	}
+	if err := scm.Verify(repository.Provider, repository.WebhookSecret, c.Request.Header, body); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook signature"})
+		return
+	}
+	if !ctrl.recordDelivery(c, &repository) {
+		return
+	}
+
+	event, err := scm.Parse(repository.Provider, c.Request.Header, body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook payload"})
+		return
+	}
+	// Our own commit statuses come back as status events
+	if event.Ignored || !strings.EqualFold(event.Repository, repository.FullName) || event.CIName == commitStatusContext {
+		c.JSON(http.StatusOK, gin.H{"message": "Event ignored"})
+		return
+	}
+
+	if event.Kind == scm.EventStatus {
+		err := ctrl.DB.Model(&models.PullRequest{}).
+			Where("repository_id = ? AND head_sha = ?", repository.ID, event.SHA).
+			Update("ci_state", event.CIState).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pull requests"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Commit status recorded"})
+		return
+	}
+
+	var pullRequest models.PullRequest
+	ctrl.DB.Where("repository_id = ? AND number = ?", repository.ID, event.Number).
+		FirstOrInit(&pullRequest, models.PullRequest{ProjectID: repository.ProjectID, RepositoryID: repository.ID, Number: event.Number})
+
+	if pullRequest.HeadSHA != event.HeadSHA {
+		pullRequest.CIState = ""
+	}
+	pullRequest.Title = event.Title
+	pullRequest.Branch = event.Branch
+	pullRequest.HeadSHA = event.HeadSHA
+	pullRequest.URL = event.URL
+	pullRequest.State = event.State
+	if event.Kind == scm.EventReview {
+		if err := pullRequest.SetApproval(event.Reviewer, event.Approved); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	previous := pullRequest.WorkItemID
+	workItem, err := ctrl.referencedWorkItem(repository.ProjectID, scm.References(event.Branch, event.Title))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link pull request"})
+		return
+	}
+	pullRequest.WorkItemID = nil
+	if workItem != nil {
+		pullRequest.WorkItemID = &workItem.ID
+	}
+
+	if err := ctrl.DB.Save(&pullRequest).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save pull request"})
+		return
+	}
+
+	// Approvals move from the work item the pull request no longer references
+	if previous != nil && (workItem == nil || *previous != workItem.ID) {
+		if err := ctrl.syncReviewChecks(*previous, repository.CreatedBy); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update review checks"})
+			return
+		}
+	}
+	if workItem != nil {
+		if err := ctrl.syncReviewChecks(workItem.ID, repository.CreatedBy); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update review checks"})
+			return
+		}
+	}
+
+	ctrl.background(fmt.Sprintf("post commit status to repository %d", repository.ID), func() error {
+		return ctrl.postCommitStatus(&repository, &pullRequest)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Pull request updated",
+		"pull_request": pullRequest,
+	})
+}
+
+// Repository helpers
+
+func (ctrl *Controller) loadIntegrationProject(c *gin.Context) (*models.Project, bool) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return nil, false
+	}
+
+	var project models.Project
+	if err := ctrl.DB.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil, false
+	}
+
+	if !authz.Require(c, ctrl.DB, project.ID, authz.IntegrationManage, "No permission to manage integrations") {
+		return nil, false
+	}
+
+	return &project, true
+}
+
+// recordDelivery turns down deliveries received before, replayed ones,
+// writing the error response itself.
+func (ctrl *Controller) recordDelivery(c *gin.Context, repository *models.Repository) bool {
+	deliveryID := scm.DeliveryID(repository.Provider, c.Request.Header)
+	if deliveryID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing webhook delivery ID"})
+		return false
+	}
+
+	var received int
+	err := ctrl.DB.Model(&models.WebhookReceipt{}).
+		Where("repository_id = ? AND delivery_id = ?", repository.ID, deliveryID).
+		Count(&received).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check webhook deliveries"})
+		return false
+	}
+	if received > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This delivery was already received"})
+		return false
+	}
+
+	receipt := models.WebhookReceipt{ProjectID: repository.ProjectID, RepositoryID: repository.ID, DeliveryID: deliveryID}
+	if err := ctrl.DB.Create(&receipt).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record webhook delivery"})
+		return false
+	}
+	return true
+}
+
+// referencedWorkItem returns the first work item of the project whose
+// external reference is among refs, or nil.
+func (ctrl *Controller) referencedWorkItem(projectID uint, refs []string) (*models.WorkItem, error) {
+	for _, ref := range refs {
+		var workItem models.WorkItem
+		err := ctrl.DB.Where("project_id = ? AND UPPER(external_ref) = ?", projectID, ref).Order("id").First(&workItem).Error
+		if err == nil {
+			return &workItem, nil
+		}
+		if !gorm.IsRecordNotFoundError(err) {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// syncReviewChecks ticks or unticks the review checks of the work item from
+// the approvals of its least approved pull request, closed ones aside: every
+// pull request linked to the work item must be approved.
+func (ctrl *Controller) syncReviewChecks(workItemID, userID uint) error {
+	var workItem models.WorkItem
+	if err := ctrl.DB.First(&workItem, workItemID).Error; err != nil {
+		return err
+	}
+
+	var pullRequests []models.PullRequest
+	err := ctrl.DB.Where("work_item_id = ? AND state <> ?", workItem.ID, scm.PullRequestClosed).Find(&pullRequests).Error
+	if err != nil {
+		return err
+	}
+	approvals := 0
+	for i, pullRequest := range pullRequests {
+		if i == 0 || len(pullRequest.ApproverList) < approvals {
+			approvals = len(pullRequest.ApproverList)
+		}
+	}
+
+	items, err := ctrl.checklistItems(&workItem)
+	if err != nil {
+		return err
+	}
+	var checked []models.DoDItem
+	for _, item := range items {
+		policy := ctrl.itemPolicy(item.DoDItem)
+		if !item.NotApplicable && policy.AutoCheck == models.AutoCheckReview {
+			checked = append(checked, policy)
+		}
+	}
+	if len(checked) == 0 {
+		return nil
+	}
+
+	wasMet := ctrl.dodMet(&workItem)
+	tx := ctrl.DB.Begin()
+	for _, item := range checked {
+		result := models.CheckResult{Item: item, Threshold: item.AutoCheckLimit(), Actual: float64(approvals)}
+		result.Passed = result.Actual >= result.Threshold
+		result.Detail = fmt.Sprintf("%d approvals on linked pull requests (at least %g required)", approvals, result.Threshold)
+
+		var completion models.DoDItemCompletion
+		tx.Where("work_item_id = ? AND do_d_item_id = ?", workItem.ID, item.ID).
+			FirstOrInit(&completion, models.DoDItemCompletion{WorkItemID: workItem.ID, DoDItemID: item.ID})
+
+		decision := applyCheckResult(&completion, item, result, userID)
+		completion.Note = result.Detail
+		if err := tx.Save(&completion).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		if decision != "" {
+			if err := recordDecision(tx, workItem.ID, item.ID, userID, decision, result.Detail); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	if workItem.Status, err = ctrl.workItemStatus(&workItem); err != nil {
+		return err
+	}
+	ctrl.emitCompleted(&workItem, wasMet, userID)
+	return nil
+}
+
+// apiHostAllowed tells whether the API URL, empty for the provider's public
+// API, is on one of the code hosts the configuration allows.
+func (ctrl *Controller) apiHostAllowed(apiURL string) bool {
+	if apiURL == "" {
+		return true
+	}
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return false
+	}
+	for _, host := range ctrl.Cfg.SCMAPIHosts {
+		if strings.EqualFold(u.Hostname(), strings.TrimSpace(host)) {
+			return true
+		}
+	}
+	return false
+}
+
+// postCommitStatus reports the DoD verdict of the work item the pull request
+// references on its head commit: pending while it references none.
+func (ctrl *Controller) postCommitStatus(repository *models.Repository, pullRequest *models.PullRequest) error {
+	if repository.AccessToken == "" || pullRequest.HeadSHA == "" || pullRequest.State != scm.PullRequestOpen {
+		return nil
+	}
+	// The allowed hosts may have changed since the repository was connected
+	if !ctrl.apiHostAllowed(repository.APIURL) {
+		return fmt.Errorf("%s is not one of the allowed code hosts", repository.APIURL)
+	}
+
+	status := scm.CommitStatus{
+		State:       scm.StatePending,
+		Context:     commitStatusContext,
+		Description: "No work item referenced in the branch name or title",
+		TargetURL:   fmt.Sprintf("%s/projects/%d", ctrl.Cfg.AppURL, repository.ProjectID),
+	}
+	if pullRequest.WorkItemID != nil {
+		var workItem models.WorkItem
+		if err := ctrl.DB.First(&workItem, *pullRequest.WorkItemID).Error; err != nil {
+			return err
+		}
+		verdict, err := ctrl.gateVerdict(&workItem)
+		if err != nil {
+			return err
+		}
+
+		status.TargetURL = fmt.Sprintf("%s/projects/%d/work-items/%d", ctrl.Cfg.AppURL, workItem.ProjectID, workItem.ID)
+		switch verdict.Verdict {
+		case models.GatePass:
+			status.State = scm.StateSuccess
+			status.Description = "Definition of Done met"
+		case models.GateWaived:
+			status.State = scm.StateSuccess
+			status.Description = fmt.Sprintf("Definition of Done met, %d items waived", len(verdict.Waived))
+		default:
+			status.State = scm.StateFailure
+			status.Description = strings.Join(verdict.Reasons, "; ")
+		}
+	}
+
+	client, err := scm.NewClient(repository.Provider, repository.APIURL, repository.AccessToken)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return client.SetCommitStatus(ctx, repository.FullName, pullRequest.HeadSHA, status)
+}
+
+// refreshPullRequests posts the verdict again on the open pull requests of
+// the work item, after its checklist changed, in the background.
+func (ctrl *Controller) refreshPullRequests(workItemID uint) {
+	ctrl.background(fmt.Sprintf("refresh pull requests of work item %d", workItemID), func() error {
+		var pullRequests []models.PullRequest
+		err := ctrl.DB.Where("work_item_id = ? AND state = ?", workItemID, scm.PullRequestOpen).Find(&pullRequests).Error
+		if err != nil {
+			return err
+		}
+
+		var errs []error
+		for i := range pullRequests {
+			var repository models.Repository
+			if err := ctrl.DB.First(&repository, pullRequests[i].RepositoryID).Error; err != nil {
+				continue
+			}
+			if err := ctrl.postCommitStatus(&repository, &pullRequests[i]); err != nil {
+				errs = append(errs, fmt.Errorf("repository %d: %w", repository.ID, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
@@ -0,0 +1,347 @@
+package controllers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"dod-backend/authz"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// DoD Revision Controllers
+
+// PublishDoD snapshots the live items of the DoD, and the organization
+// baseline they are inherited with, into a new immutable revision. Work items
+// that started before the DoD was first published are pinned to it.
+func (ctrl *Controller) PublishDoD(c *gin.Context) {
+	dod, ok := ctrl.loadDoDForRevisions(c, authz.DoDUpdate)
+	if !ok {
+		return
+	}
+
+	var req models.PublishDoDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	draft, err := ctrl.draftItems(dod.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch DoD items"})
+		return
+	}
+	baseline, err := ctrl.draftBaseline(dod)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch DoD items"})
+		return
+	}
+
+	latest, err := latestRevision(ctrl.DB, dod.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+
+	revision := models.DoDRevision{
+		DoDID:       dod.ID,
+		Number:      1,
+		Changelog:   req.Changelog,
+		PublishedBy: c.GetUint("user_id"),
+		PublishedAt: time.Now(),
+	}
+	if latest != nil {
+		baselineChanged := latest.Inherited == nil || !diffEmpty(diffRevisionItems(latest.Inherited, baseline))
+		if diff := diffRevisionItems(latest.Items, draft); diffEmpty(diff) && !baselineChanged {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No changes since revision " + strconv.Itoa(latest.Number)})
+			return
+		}
+		revision.Number = latest.Number + 1
+	}
+	if err := revision.SetItems(draft); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish DoD"})
+		return
+	}
+	if err := revision.SetInherited(baseline); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish DoD"})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := tx.Create(&revision).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "DoD was published concurrently, try again"})
+		return
+	}
+	err = tx.Model(&models.WorkItem{}).
+		Where("do_d_id = ? AND do_d_revision_id IS NULL AND state <> ?", dod.ID, models.WorkItemTodo).
+		Update("do_d_revision_id", revision.ID).Error
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish DoD"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish DoD"})
+		return
+	}
+	ctrl.emitFrom(c, dod.ProjectID, models.EventDoDPublished, revision)
+	ctrl.notifyDoDPublished(dod, &revision, c.GetString("username"))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "DoD published successfully",
+		"revision": revision,
+	})
+}
+
+func (ctrl *Controller) GetDoDRevisions(c *gin.Context) {
+	dod, ok := ctrl.loadDoDForRevisions(c, authz.ProjectView)
+	if !ok {
+		return
+	}
+
+	var revisions []models.DoDRevision
+	err := ctrl.DB.Where("do_d_id = ?", dod.ID).
+		Preload("Publisher").
+		Order("number").
+		Find(&revisions).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+func (ctrl *Controller) GetDoDRevision(c *gin.Context) {
+	dod, ok := ctrl.loadDoDForRevisions(c, authz.ProjectView)
+	if !ok {
+		return
+	}
+
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return
+	}
+
+	var revision models.DoDRevision
+	if err := ctrl.DB.Where("do_d_id = ? AND number = ?", dod.ID, number).Preload("Publisher").First(&revision).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revision": revision})
+}
+
+// DiffDoDRevisions compares revision ?from= with revision ?to=, or with the
+// live draft when ?to= is left out.
+func (ctrl *Controller) DiffDoDRevisions(c *gin.Context) {
+	dod, ok := ctrl.loadDoDForRevisions(c, authz.ProjectView)
+	if !ok {
+		return
+	}
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from revision"})
+		return
+	}
+
+	var before models.DoDRevision
+	if err := ctrl.DB.Where("do_d_id = ? AND number = ?", dod.ID, from).First(&before).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	var after []models.RevisionItem
+	to := "draft"
+	if c.Query("to") != "" {
+		number, err := strconv.Atoi(c.Query("to"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to revision"})
+			return
+		}
+		var revision models.DoDRevision
+		if err := ctrl.DB.Where("do_d_id = ? AND number = ?", dod.ID, number).First(&revision).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+			return
+		}
+		after = revision.Items
+		to = strconv.Itoa(number)
+	} else {
+		if after, err = ctrl.draftItems(dod.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch DoD items"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from": from,
+		"to":   to,
+		"diff": diffRevisionItems(before.Items, after),
+	})
+}
+
+// loadDoDForRevisions resolves the :id route parameter to a project DoD on
+// which the caller holds the permission, writing the error response itself.
+func (ctrl *Controller) loadDoDForRevisions(c *gin.Context, permission authz.Permission) (*models.DoD, bool) {
+	dodID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid DoD ID"})
+		return nil, false
+	}
+
+	var dod models.DoD
+	if err := ctrl.DB.First(&dod, dodID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DoD not found"})
+		return nil, false
+	}
+
+	if !authz.Require(c, ctrl.DB, dod.ProjectID, permission, "No permission on this DoD") {
+		return nil, false
+	}
+
+	return &dod, true
+}
+
+// draftItems returns the live items of the DoD, and their rules, in revision
+// form.
+func (ctrl *Controller) draftItems(dodID uint) ([]models.RevisionItem, error) {
+	var items []models.DoDItem
+	if err := ctrl.DB.Where("do_d_id = ?", dodID).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Order < items[j].Order })
+	_, itemRules, err := ctrl.rulesFor([]uint{dodID})
+	if err != nil {
+		return nil, err
+	}
+
+	draft := make([]models.RevisionItem, 0, len(items))
+	for _, item := range items {
+		draft = append(draft, models.RevisionItem{
+			ItemID:      item.ID,
+			Title:       item.Title,
+			Description: item.Description,
+			IsRequired:  item.IsRequired,
+			Order:       item.Order,
+			Rules:       append([]models.ApplicabilityRule{}, itemRules[item.ID]...),
+		})
+	}
+	return draft, nil
+}
+
+// draftBaseline returns the organization baseline the items of the DoD are
+// inherited with, in revision form.
+func (ctrl *Controller) draftBaseline(dod *models.DoD) ([]models.RevisionItem, error) {
+	items, err := ctrl.baselineItems(dod.ProjectID, dod.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	baseline := make([]models.RevisionItem, 0, len(items))
+	for _, item := range items {
+		baseline = append(baseline, models.RevisionItem{
+			ItemID:      item.ID,
+			DoDID:       item.DoDID,
+			Title:       item.Title,
+			Description: item.Description,
+			IsRequired:  item.IsRequired,
+			Order:       item.Order,
+		})
+	}
+	return baseline, nil
+}
+
+// latestRevision returns the last published revision of the DoD, nil when it
+// was never published.
+func latestRevision(db *gorm.DB, dodID uint) (*models.DoDRevision, error) {
+	var revision models.DoDRevision
+	err := db.Where("do_d_id = ?", dodID).Order("number desc").First(&revision).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// diffRevisionItems matches items by ID across the two sides.
+func diffRevisionItems(before, after []models.RevisionItem) models.RevisionDiff {
+	diff := models.RevisionDiff{
+		Added:   []models.RevisionItem{},
+		Removed: []models.RevisionItem{},
+		Changed: []models.RevisionItemChange{},
+	}
+
+	previous := make(map[uint]models.RevisionItem, len(before))
+	for _, item := range before {
+		previous[item.ItemID] = item
+	}
+
+	for _, item := range after {
+		old, ok := previous[item.ItemID]
+		if !ok {
+			diff.Added = append(diff.Added, item)
+			continue
+		}
+		delete(previous, item.ItemID)
+
+		var fields []string
+		if old.Title != item.Title {
+			fields = append(fields, "title")
+		}
+		if old.Description != item.Description {
+			fields = append(fields, "description")
+		}
+		if old.IsRequired != item.IsRequired {
+			fields = append(fields, "is_required")
+		}
+		if old.Order != item.Order {
+			fields = append(fields, "order")
+		}
+		if old.Rules != nil && !sameRules(old.Rules, item.Rules) {
+			fields = append(fields, "rules")
+		}
+		if len(fields) == 0 {
+			diff.Unchanged++
+			continue
+		}
+		diff.Changed = append(diff.Changed, models.RevisionItemChange{
+			ItemID: item.ItemID,
+			Fields: fields,
+			Before: old,
+			After:  item,
+		})
+	}
+
+	// Keep removed items in their previous order
+	for _, item := range before {
+		if _, ok := previous[item.ItemID]; ok {
+			diff.Removed = append(diff.Removed, item)
+		}
+	}
+	return diff
+}
+
+func sameRules(before, after []models.ApplicabilityRule) bool {
+	if len(before) != len(after) {
+		return false
+	}
+	for i := range before {
+		if describeRule(before[i]) != describeRule(after[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func diffEmpty(diff models.RevisionDiff) bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0
+}
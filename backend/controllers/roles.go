@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"dod-backend/authz"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Role Controllers
+func (ctrl *Controller) GetProjectRoles(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, uint(projectID), authz.ProjectView, "No access to this project") {
+		return
+	}
+
+	var roles []models.ProjectRole
+	if err := ctrl.DB.Where("project_id = ?", projectID).Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return
+	}
+
+	builtIn := []gin.H{}
+	for _, name := range []string{authz.RoleOwner, authz.RoleEditor, authz.RoleViewer} {
+		builtIn = append(builtIn, gin.H{"name": name, "permissions": authz.Matrix[name]})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"built_in_roles": builtIn,
+		"roles":          roles,
+		"permissions":    authz.Permissions,
+	})
+}
+
+// GetProjectPermissions tells the caller what they may do on the project.
+func (ctrl *Controller) GetProjectPermissions(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	grant, err := authz.For(c, ctrl.DB, uint(projectID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return
+	}
+	if !grant.Participant() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "No access to this project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"role":        grant.Role,
+		"permissions": grant.Permissions(),
+	})
+}
+
+func (ctrl *Controller) CreateProjectRole(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var req models.CreateProjectRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if authz.IsReservedRole(req.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role name is reserved"})
+		return
+	}
+
+	if !ctrl.checkGrantablePermissions(c, uint(projectID), req.Permissions) {
+		return
+	}
+
+	role := models.ProjectRole{
+		ProjectID:   uint(projectID),
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	role.SetPermissions(req.Permissions)
+
+	if err := ctrl.DB.Create(&role).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Role already exists"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Role created successfully",
+		"role":    role,
+	})
+}
+
+// UpdateProjectRole changes what a custom role grants. Roles are referenced
+// by name, so they cannot be renamed.
+func (ctrl *Controller) UpdateProjectRole(c *gin.Context) {
+	role, ok := ctrl.loadProjectRole(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateProjectRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Permissions != nil {
+		if !ctrl.checkGrantablePermissions(c, role.ProjectID, req.Permissions) {
+			return
+		}
+		role.SetPermissions(req.Permissions)
+	}
+	if req.Description != nil {
+		role.Description = *req.Description
+	}
+
+	if err := ctrl.DB.Save(role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role updated successfully",
+		"role":    role,
+	})
+}
+
+func (ctrl *Controller) DeleteProjectRole(c *gin.Context) {
+	role, ok := ctrl.loadProjectRole(c)
+	if !ok {
+		return
+	}
+
+	var inUse int
+	ctrl.DB.Model(&models.ProjectParticipant{}).Where("project_id = ? AND role = ?", role.ProjectID, role.Name).Count(&inUse)
+	if inUse == 0 {
+		ctrl.DB.Model(&models.ProjectTeam{}).Where("project_id = ? AND role = ?", role.ProjectID, role.Name).Count(&inUse)
+	}
+	if inUse == 0 {
+		ctrl.DB.Model(&models.ProjectInvitation{}).
+			Where("project_id = ? AND role = ? AND accepted_at IS NULL", role.ProjectID, role.Name).Count(&inUse)
+	}
+	if inUse > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Role is still assigned to participants, teams or invitations"})
+		return
+	}
+
+	if err := ctrl.DB.Delete(role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+}
+
+// loadProjectRole resolves the :id/:roleId route parameters. Permission
+// checks are left to the route middleware.
+func (ctrl *Controller) loadProjectRole(c *gin.Context) (*models.ProjectRole, bool) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return nil, false
+	}
+
+	roleID, err := strconv.Atoi(c.Param("roleId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return nil, false
+	}
+
+	var role models.ProjectRole
+	if err := ctrl.DB.Where("id = ? AND project_id = ?", roleID, projectID).First(&role).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return nil, false
+	}
+
+	return &role, true
+}
+
+// checkGrantablePermissions validates the permissions of a custom role: each
+// must be known and not reserved to the owner, and the caller must hold them
+// all. It writes the error response itself.
+func (ctrl *Controller) checkGrantablePermissions(c *gin.Context, projectID uint, names []string) bool {
+	permissions := make([]authz.Permission, 0, len(names))
+	for _, name := range names {
+		if !authz.Grantable(authz.Permission(name)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Permission '" + name + "' cannot be granted"})
+			return false
+		}
+		permissions = append(permissions, authz.Permission(name))
+	}
+
+	grant, err := authz.For(c, ctrl.DB, projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return false
+	}
+	if !grant.Covers(permissions) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot grant a role with more permissions than your own"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,354 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"dod-backend/authz"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// Sign-off Controllers
+//
+// Items with a peer, role or quorum verification policy are ticked in two
+// steps: whoever ticks them requests a sign-off, and other participants
+// approve or reject it. Every step is kept in the item's decision history.
+func (ctrl *Controller) ApproveWorkItemItem(c *gin.Context) {
+	workItem, item, policy, ok := ctrl.loadSignOff(c, authz.ItemCheck, "No permission to approve items of this work item")
+	if !ok {
+		return
+	}
+
+	var req models.SignOffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	completion, ok := ctrl.loadPendingSignOff(c, workItem, item, policy)
+	if !ok {
+		return
+	}
+
+	// Approvals only count towards the latest request
+	var request models.ItemDecision
+	err := ctrl.DB.Where("work_item_id = ? AND do_d_item_id = ? AND decision = ?", workItem.ID, item.ID, models.DecisionRequested).
+		Order("id desc").
+		First(&request).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch decisions"})
+		return
+	}
+	var approved int
+	err = ctrl.DB.Model(&models.ItemDecision{}).
+		Where("work_item_id = ? AND do_d_item_id = ? AND user_id = ? AND decision = ? AND id > ?", workItem.ID, item.ID, userID, models.DecisionApproved, request.ID).
+		Count(&approved).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch decisions"})
+		return
+	}
+	if approved > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already approved this item"})
+		return
+	}
+
+	completion.Approvals++
+	if completion.Approvals >= policy.ApprovalsRequired() {
+		completion.Checked = true
+		completion.PendingApproval = false
+	}
+
+	ctrl.saveSignOff(c, workItem, completion, models.DecisionApproved, req.Comment, "Item approved")
+}
+
+func (ctrl *Controller) RejectWorkItemItem(c *gin.Context) {
+	workItem, item, policy, ok := ctrl.loadSignOff(c, authz.ItemCheck, "No permission to reject items of this work item")
+	if !ok {
+		return
+	}
+
+	var req models.CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	completion, ok := ctrl.loadPendingSignOff(c, workItem, item, policy)
+	if !ok {
+		return
+	}
+
+	// The item has to be ticked again, which requests a new sign-off
+	completion.Checked = false
+	completion.CheckedBy = nil
+	completion.CheckedAt = nil
+	completion.PendingApproval = false
+	completion.Approvals = 0
+
+	ctrl.saveSignOff(c, workItem, completion, models.DecisionRejected, req.Comment, "Item rejected")
+}
+
+func (ctrl *Controller) CommentWorkItemItem(c *gin.Context) {
+	workItem, item, _, ok := ctrl.loadSignOff(c, authz.ProjectView, "No access to this project")
+	if !ok {
+		return
+	}
+
+	var req models.CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	decision := models.ItemDecision{
+		WorkItemID: workItem.ID,
+		DoDItemID:  item.ID,
+		UserID:     c.GetUint("user_id"),
+		Decision:   models.DecisionCommented,
+		Comment:    req.Comment,
+	}
+	if err := ctrl.DB.Create(&decision).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Comment added successfully",
+		"decision": decision,
+	})
+}
+
+func (ctrl *Controller) GetWorkItemItemDecisions(c *gin.Context) {
+	workItem, item, policy, ok := ctrl.loadSignOff(c, authz.ProjectView, "No access to this project")
+	if !ok {
+		return
+	}
+
+	var decisions []models.ItemDecision
+	err := ctrl.DB.Where("work_item_id = ? AND do_d_item_id = ?", workItem.ID, item.ID).
+		Preload("User").
+		Order("id").
+		Find(&decisions).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch decisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"item":      policy,
+		"decisions": decisions,
+	})
+}
+
+// loadSignOff resolves the :id/:workItemId/:itemId route parameters to a work
+// item, one of its checklist items and the item's current policy, checking
+// the caller's permission. It writes the error response itself.
+func (ctrl *Controller) loadSignOff(c *gin.Context, permission authz.Permission, message string) (*models.WorkItem, *models.EffectiveDoDItem, models.DoDItem, bool) {
+	workItem, ok := ctrl.loadWorkItem(c)
+	if !ok {
+		return nil, nil, models.DoDItem{}, false
+	}
+
+	itemID, err := strconv.Atoi(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid DoD item ID"})
+		return nil, nil, models.DoDItem{}, false
+	}
+
+	if !authz.Require(c, ctrl.DB, workItem.ProjectID, permission, message) {
+		return nil, nil, models.DoDItem{}, false
+	}
+
+	item, err := ctrl.checklistItem(workItem, uint(itemID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch DoD items"})
+		return nil, nil, models.DoDItem{}, false
+	}
+	if item == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DoD item not found"})
+		return nil, nil, models.DoDItem{}, false
+	}
+
+	return workItem, item, ctrl.itemPolicy(item.DoDItem), true
+}
+
+// loadPendingSignOff returns the completion awaiting the caller's decision,
+// checking that the caller is allowed to take it: never an API key nor
+// whoever ticked the item, even through a key they created, and only holders
+// of the approver role under the role policy.
+func (ctrl *Controller) loadPendingSignOff(c *gin.Context, workItem *models.WorkItem, item *models.EffectiveDoDItem, policy models.DoDItem) (*models.DoDItemCompletion, bool) {
+	var completion models.DoDItemCompletion
+	err := ctrl.DB.Where("work_item_id = ? AND do_d_item_id = ?", workItem.ID, item.ID).First(&completion).Error
+	if err != nil || !completion.PendingApproval {
+		c.JSON(http.StatusConflict, gin.H{"error": "No sign-off pending on this item"})
+		return nil, false
+	}
+
+	userID := c.GetUint("user_id")
+	if ctrl.isServiceAccount(userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Items cannot be signed off with an API key"})
+		return nil, false
+	}
+	if completion.CheckedBy != nil && ctrl.personBehind(*completion.CheckedBy) == userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Items cannot be signed off by whoever ticked them"})
+		return nil, false
+	}
+
+	if policy.Verification == models.VerificationRole {
+		holds, err := authz.HoldsRole(ctrl.DB, workItem.ProjectID, userID, policy.ApproverRole)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return nil, false
+		}
+		if !holds {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Signing off this item requires the %s role", policy.ApproverRole)})
+			return nil, false
+		}
+	}
+
+	return &completion, true
+}
+
+// saveSignOff stores the completion with the decision that changed it and
+// responds with the updated work item.
+func (ctrl *Controller) saveSignOff(c *gin.Context, workItem *models.WorkItem, completion *models.DoDItemCompletion, decision, comment, message string) {
+	wasMet := ctrl.dodMet(workItem)
+	tx := ctrl.DB.Begin()
+	if err := tx.Save(completion).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+	if err := recordDecision(tx, workItem.ID, completion.DoDItemID, c.GetUint("user_id"), decision, comment); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+	ctrl.refreshPullRequests(workItem.ID)
+
+	var err error
+	if workItem.Status, err = ctrl.workItemStatus(workItem); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute work item status"})
+		return
+	}
+	ctrl.emitCompleted(workItem, wasMet, c.GetUint("user_id"))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    message,
+		"completion": completion,
+		"work_item":  workItem,
+	})
+}
+
+// Sign-off helpers
+
+func recordDecision(tx *gorm.DB, workItemID, itemID, userID uint, decision, comment string) error {
+	return tx.Create(&models.ItemDecision{
+		WorkItemID: workItemID,
+		DoDItemID:  itemID,
+		UserID:     userID,
+		Decision:   decision,
+		Comment:    comment,
+	}).Error
+}
+
+// checklistItem finds an item the work item can be ticked against: from its
+// DoD, inherited from the organization, or from the Definition of Ready. It
+// returns nil when there is none, or when it is not applicable.
+func (ctrl *Controller) checklistItem(workItem *models.WorkItem, itemID uint) (*models.EffectiveDoDItem, error) {
+	items, err := ctrl.checklistItems(workItem)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		if items[i].ID == itemID && !items[i].NotApplicable {
+			return &items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// checklistItems returns the ready items of the work item followed by the
+// items of its DoD.
+func (ctrl *Controller) checklistItems(workItem *models.WorkItem) ([]models.EffectiveDoDItem, error) {
+	items, err := ctrl.readyItems(workItem)
+	if err != nil {
+		return nil, err
+	}
+	if workItem.DoDID != nil {
+		done, _, err := ctrl.workItemItems(workItem)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, done...)
+	}
+	return items, nil
+}
+
+// itemPolicy returns the item as it currently is, for its sign-off policy.
+// Items of a pinned revision may have been deleted since, and are then
+// self-checked.
+func (ctrl *Controller) itemPolicy(item models.DoDItem) models.DoDItem {
+	var live models.DoDItem
+	if err := ctrl.DB.First(&live, item.ID).Error; err != nil {
+		item.Verification = models.VerificationSelf
+		return item
+	}
+	return live
+}
+
+// setVerification applies a change of sign-off policy to the item, checking
+// that the role policy names a role of the project (a built-in one for
+// organization baselines). It writes the error response itself.
+func (ctrl *Controller) setVerification(c *gin.Context, item *models.DoDItem, verification, approverRole *string, requiredApprovals *int) bool {
+	if verification != nil && *verification != "" {
+		item.Verification = *verification
+	}
+	if approverRole != nil {
+		item.ApproverRole = *approverRole
+	}
+	if requiredApprovals != nil && *requiredApprovals > 0 {
+		item.RequiredApprovals = *requiredApprovals
+	}
+	if item.Verification == "" {
+		item.Verification = models.VerificationSelf
+	}
+	if item.RequiredApprovals < 1 {
+		item.RequiredApprovals = 1
+	}
+
+	if item.Verification != models.VerificationRole {
+		item.ApproverRole = ""
+		return true
+	}
+	if item.ApproverRole == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "approver_role is required by the role policy"})
+		return false
+	}
+
+	var dod models.DoD
+	if err := ctrl.DB.Select("id, project_id").First(&dod, item.DoDID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check roles"})
+		return false
+	}
+	_, known, err := authz.RolePermissions(ctrl.DB, dod.ProjectID, item.ApproverRole)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check roles"})
+		return false
+	}
+	if !known {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,485 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"dod-backend/authz"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// Team Controllers
+func (ctrl *Controller) CreateTeam(c *gin.Context) {
+	var req models.CreateTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.GetBool("service_account") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Project API keys cannot create teams"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	team := models.Team{
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedBy:   userID,
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := tx.Create(&team).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create team"})
+		return
+	}
+
+	member := models.TeamMember{
+		TeamID: team.ID,
+		UserID: userID,
+		Role:   authz.TeamRoleMaintainer,
+	}
+	if err := tx.Create(&member).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create team"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create team"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Team created successfully",
+		"team":    team,
+	})
+}
+
+// GetUserTeams lists the teams of the caller along with their role in each.
+func (ctrl *Controller) GetUserTeams(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var memberships []models.TeamMember
+	if err := ctrl.DB.Where("user_id = ?", userID).Find(&memberships).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch teams"})
+		return
+	}
+
+	teams := []gin.H{}
+	for _, membership := range memberships {
+		var team models.Team
+		if err := ctrl.DB.First(&team, membership.TeamID).Error; err != nil {
+			continue
+		}
+		teams = append(teams, gin.H{
+			"team": team,
+			"role": membership.Role,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"teams": teams})
+}
+
+func (ctrl *Controller) GetTeam(c *gin.Context) {
+	team, _, ok := ctrl.loadTeam(c, false)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.DB.Preload("Members.User").First(team, team.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team"})
+		return
+	}
+
+	var projects []models.ProjectTeam
+	if err := ctrl.DB.Where("team_id = ?", team.ID).Find(&projects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"team":     team,
+		"projects": projects,
+	})
+}
+
+func (ctrl *Controller) UpdateTeam(c *gin.Context) {
+	team, _, ok := ctrl.loadTeam(c, true)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+
+	if err := ctrl.DB.Model(team).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Team updated successfully",
+		"team":    team,
+	})
+}
+
+// DeleteTeam also revokes the project access granted to the team.
+func (ctrl *Controller) DeleteTeam(c *gin.Context) {
+	team, _, ok := ctrl.loadTeam(c, true)
+	if !ok {
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := deleteTeamCascade(tx, team.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete team"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete team"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team deleted successfully"})
+}
+
+// Team Member Controllers
+func (ctrl *Controller) AddTeamMember(c *gin.Context) {
+	team, _, ok := ctrl.loadTeam(c, true)
+	if !ok {
+		return
+	}
+
+	var req models.AddTeamMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := ctrl.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.ServiceAccount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API keys cannot join teams"})
+		return
+	}
+
+	member := models.TeamMember{
+		TeamID: team.ID,
+		UserID: user.ID,
+		Role:   req.Role,
+	}
+	if err := ctrl.DB.Create(&member).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User already member"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Member added successfully",
+		"member":  member,
+	})
+}
+
+func (ctrl *Controller) UpdateTeamMember(c *gin.Context) {
+	team, _, ok := ctrl.loadTeam(c, true)
+	if !ok {
+		return
+	}
+
+	member, ok := ctrl.loadTeamMember(c, team.ID)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateTeamMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if member.Role == authz.TeamRoleMaintainer && req.Role != authz.TeamRoleMaintainer && ctrl.lastTeamMaintainer(team.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A team needs at least one maintainer"})
+		return
+	}
+
+	if err := ctrl.DB.Model(member).Update("role", req.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member role updated successfully",
+		"member":  member,
+	})
+}
+
+// RemoveTeamMember lets maintainers remove anyone, and members leave.
+func (ctrl *Controller) RemoveTeamMember(c *gin.Context) {
+	team, role, ok := ctrl.loadTeam(c, false)
+	if !ok {
+		return
+	}
+
+	member, ok := ctrl.loadTeamMember(c, team.ID)
+	if !ok {
+		return
+	}
+
+	if role != authz.TeamRoleMaintainer && member.UserID != c.GetUint("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only team maintainers can remove members"})
+		return
+	}
+
+	if member.Role == authz.TeamRoleMaintainer && ctrl.lastTeamMaintainer(team.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A team needs at least one maintainer"})
+		return
+	}
+
+	if err := ctrl.DB.Delete(member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// Project Team Controllers
+func (ctrl *Controller) GetProjectTeams(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, uint(projectID), authz.ProjectView, "No access to this project") {
+		return
+	}
+
+	var teams []models.ProjectTeam
+	err = ctrl.DB.Where("project_id = ?", projectID).
+		Preload("Team").
+		Find(&teams).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch teams"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"teams": teams})
+}
+
+func (ctrl *Controller) AddProjectTeam(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var req models.AddProjectTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var project models.Project
+	if err := ctrl.DB.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, project.ID, authz.ParticipantManage, "No permission to manage participants") {
+		return
+	}
+
+	var team models.Team
+	if err := ctrl.DB.First(&team, req.TeamID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	// Teams have no organization: only their members can bring them in, not
+	// anyone guessing the ID of a team of strangers
+	teamRole, err := authz.TeamRole(ctrl.DB, team.ID, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return
+	}
+	if teamRole == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only members of the team can add it to a project"})
+		return
+	}
+
+	if !ctrl.checkAssignableRole(c, project.ID, req.Role) {
+		return
+	}
+
+	projectTeam := models.ProjectTeam{
+		ProjectID: project.ID,
+		TeamID:    team.ID,
+		Role:      req.Role,
+	}
+	if err := ctrl.DB.Create(&projectTeam).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Team already has access to this project"})
+		return
+	}
+	projectTeam.Team = team
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Team added successfully",
+		"team":    projectTeam,
+	})
+}
+
+func (ctrl *Controller) UpdateProjectTeam(c *gin.Context) {
+	projectTeam, ok := ctrl.loadManagedProjectTeam(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateProjectTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !ctrl.checkAssignableRole(c, projectTeam.ProjectID, req.Role) {
+		return
+	}
+
+	if err := ctrl.DB.Model(projectTeam).Update("role", req.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Team role updated successfully",
+		"team":    projectTeam,
+	})
+}
+
+func (ctrl *Controller) RemoveProjectTeam(c *gin.Context) {
+	projectTeam, ok := ctrl.loadManagedProjectTeam(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.DB.Delete(projectTeam).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove team"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team removed successfully"})
+}
+
+// loadTeam resolves the :id route parameter for members of the team, or its
+// maintainers only, writing the error response itself.
+func (ctrl *Controller) loadTeam(c *gin.Context, maintainerOnly bool) (*models.Team, string, bool) {
+	teamID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid team ID"})
+		return nil, "", false
+	}
+
+	var team models.Team
+	if err := ctrl.DB.First(&team, teamID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return nil, "", false
+	}
+
+	role, err := authz.TeamRole(ctrl.DB, team.ID, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return nil, "", false
+	}
+	if role == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "No access to this team"})
+		return nil, "", false
+	}
+	if maintainerOnly && role != authz.TeamRoleMaintainer {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only team maintainers can manage the team"})
+		return nil, "", false
+	}
+
+	return &team, role, true
+}
+
+func (ctrl *Controller) loadTeamMember(c *gin.Context, teamID uint) (*models.TeamMember, bool) {
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+
+	var member models.TeamMember
+	if err := ctrl.DB.Where("team_id = ? AND user_id = ?", teamID, userID).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return nil, false
+	}
+
+	return &member, true
+}
+
+// loadManagedProjectTeam resolves the :id/:teamId route parameters for
+// participant managers, writing the error response itself.
+func (ctrl *Controller) loadManagedProjectTeam(c *gin.Context) (*models.ProjectTeam, bool) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return nil, false
+	}
+
+	teamID, err := strconv.Atoi(c.Param("teamId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid team ID"})
+		return nil, false
+	}
+
+	if !authz.Require(c, ctrl.DB, uint(projectID), authz.ParticipantManage, "No permission to manage participants") {
+		return nil, false
+	}
+
+	var projectTeam models.ProjectTeam
+	if err := ctrl.DB.Where("project_id = ? AND team_id = ?", projectID, teamID).First(&projectTeam).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found on this project"})
+		return nil, false
+	}
+
+	return &projectTeam, true
+}
+
+func (ctrl *Controller) lastTeamMaintainer(teamID uint) bool {
+	var maintainers int
+	ctrl.DB.Model(&models.TeamMember{}).
+		Where("team_id = ? AND role = ?", teamID, authz.TeamRoleMaintainer).
+		Count(&maintainers)
+	return maintainers <= 1
+}
+
+func deleteTeamCascade(tx *gorm.DB, teamID uint) error {
+	if err := tx.Where("team_id = ?", teamID).Delete(models.ProjectTeam{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("team_id = ?", teamID).Delete(models.TeamMember{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("id = ?", teamID).Delete(models.Team{}).Error
+}
@@ -0,0 +1,347 @@
+package controllers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"dod-backend/authz"
+	"dod-backend/database"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// DoD Template Controllers
+func (ctrl *Controller) GetDoDTemplates(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	templates := []models.DoDTemplate{}
+	for _, builtin := range database.BuiltinTemplates {
+		template, _ := database.BuiltinTemplate(builtin.Key)
+		templates = append(templates, *template)
+	}
+
+	// Own templates, and those shared with the caller's organizations
+	var custom []models.DoDTemplate
+	organizations := ctrl.DB.Table("organization_members").
+		Select("organization_id").
+		Where("user_id = ?", userID).
+		SubQuery()
+	err := ctrl.DB.Where("created_by = ? OR organization_id IN ?", userID, organizations).
+		Preload("Items").
+		Find(&custom).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch templates"})
+		return
+	}
+	for i := range custom {
+		sortTemplateItems(&custom[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"templates": append(templates, custom...)})
+}
+
+func (ctrl *Controller) GetDoDTemplate(c *gin.Context) {
+	template, ok := ctrl.loadDoDTemplate(c, false)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"template": template})
+}
+
+func (ctrl *Controller) CreateDoDTemplate(c *gin.Context) {
+	var req models.CreateDoDTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.GetBool("service_account") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Project API keys cannot create templates"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if req.OrganizationID != nil {
+		role, err := authz.OrgRole(ctrl.DB, *req.OrganizationID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+		if role == "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this organization"})
+			return
+		}
+	}
+
+	items := templateItems(req.Items)
+	if req.DoDID != nil {
+		var dod models.DoD
+		if err := ctrl.DB.Preload("Items").First(&dod, *req.DoDID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "DoD not found"})
+			return
+		}
+		if !authz.Require(c, ctrl.DB, dod.ProjectID, authz.ProjectView, "No access to this DoD") {
+			return
+		}
+		for _, item := range dod.Items {
+			items = append(items, models.DoDTemplateItem{
+				Title:       item.Title,
+				Description: item.Description,
+				IsRequired:  item.IsRequired,
+				Order:       item.Order,
+
+				AutoCheck:          item.AutoCheck,
+				AutoCheckThreshold: item.AutoCheckThreshold,
+			})
+		}
+	}
+
+	template := models.DoDTemplate{
+		Name:           req.Name,
+		Description:    req.Description,
+		CreatedBy:      userID,
+		OrganizationID: req.OrganizationID,
+		Items:          items,
+	}
+
+	// Items are created along with the template
+	if err := ctrl.DB.Create(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create template"})
+		return
+	}
+	sortTemplateItems(&template)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Template created successfully",
+		"template": template,
+	})
+}
+
+// UpdateDoDTemplate replaces the items of the template when they are given.
+func (ctrl *Controller) UpdateDoDTemplate(c *gin.Context) {
+	template, ok := ctrl.loadDoDTemplate(c, true)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateDoDTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := tx.Model(template).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update template"})
+		return
+	}
+	if req.Items != nil {
+		if err := replaceTemplateItems(tx, template, templateItems(req.Items)); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update template"})
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Template updated successfully",
+		"template": template,
+	})
+}
+
+func (ctrl *Controller) DeleteDoDTemplate(c *gin.Context) {
+	template, ok := ctrl.loadDoDTemplate(c, true)
+	if !ok {
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := tx.Where("template_id = ?", template.ID).Delete(models.DoDTemplateItem{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete template"})
+		return
+	}
+	if err := tx.Delete(template).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete template"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template deleted successfully"})
+}
+
+func (ctrl *Controller) CreateDoDFromTemplate(c *gin.Context) {
+	var req models.CreateDoDFromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if (req.Template == "") == (req.TemplateID == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Give either a built-in template or a template ID"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, req.ProjectID, authz.DoDCreate, "No permission to create DoD for this project") {
+		return
+	}
+
+	var template *models.DoDTemplate
+	if req.Template != "" {
+		builtin, ok := database.BuiltinTemplate(req.Template)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+			return
+		}
+		template = builtin
+	} else {
+		custom, ok := ctrl.findDoDTemplate(c, *req.TemplateID, false)
+		if !ok {
+			return
+		}
+		template = custom
+	}
+
+	dod := models.DoD{
+		Title:       template.Name,
+		Description: template.Description,
+		ProjectID:   req.ProjectID,
+		CreatedBy:   c.GetUint("user_id"),
+		IsActive:    true,
+		Kind:        dodKind(req.Kind),
+	}
+	if req.Title != "" {
+		dod.Title = req.Title
+	}
+	if req.Description != "" {
+		dod.Description = req.Description
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := tx.Create(&dod).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create DoD"})
+		return
+	}
+	if err := database.ApplyTemplate(tx, dod.ID, template); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create DoD"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create DoD"})
+		return
+	}
+
+	if err := ctrl.DB.Preload("Items").First(&dod, dod.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch DoD"})
+		return
+	}
+	ctrl.emitFrom(c, dod.ProjectID, models.EventDoDCreated, dod)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "DoD created successfully",
+		"dod":     dod,
+	})
+}
+
+// loadDoDTemplate resolves the :id route parameter, writing the error
+// response itself.
+func (ctrl *Controller) loadDoDTemplate(c *gin.Context, edit bool) (*models.DoDTemplate, bool) {
+	templateID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return nil, false
+	}
+
+	return ctrl.findDoDTemplate(c, uint(templateID), edit)
+}
+
+// findDoDTemplate loads a user-defined template. Templates are used by their
+// creator and the members of the organization they are shared with, and
+// edited by their creator and the organization admins.
+func (ctrl *Controller) findDoDTemplate(c *gin.Context, templateID uint, edit bool) (*models.DoDTemplate, bool) {
+	var template models.DoDTemplate
+	if err := ctrl.DB.Preload("Items").First(&template, templateID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return nil, false
+	}
+	sortTemplateItems(&template)
+
+	userID := c.GetUint("user_id")
+	if template.CreatedBy == userID {
+		return &template, true
+	}
+
+	role := ""
+	if template.OrganizationID != nil {
+		var err error
+		if role, err = authz.OrgRole(ctrl.DB, *template.OrganizationID, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return nil, false
+		}
+	}
+	if role == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return nil, false
+	}
+	if edit && role != authz.OrgRoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the creator and organization admins can edit this template"})
+		return nil, false
+	}
+
+	return &template, true
+}
+
+func templateItems(requests []models.DoDTemplateItemRequest) []models.DoDTemplateItem {
+	items := make([]models.DoDTemplateItem, 0, len(requests))
+	for _, req := range requests {
+		items = append(items, models.DoDTemplateItem{
+			Title:       req.Title,
+			Description: req.Description,
+			IsRequired:  req.IsRequired,
+			Order:       req.Order,
+		})
+	}
+	return items
+}
+
+func replaceTemplateItems(tx *gorm.DB, template *models.DoDTemplate, items []models.DoDTemplateItem) error {
+	if err := tx.Where("template_id = ?", template.ID).Delete(models.DoDTemplateItem{}).Error; err != nil {
+		return err
+	}
+	for i := range items {
+		items[i].TemplateID = template.ID
+		if err := tx.Create(&items[i]).Error; err != nil {
+			return err
+		}
+	}
+	template.Items = items
+	sortTemplateItems(template)
+	return nil
+}
+
+func sortTemplateItems(template *models.DoDTemplate) {
+	sort.SliceStable(template.Items, func(i, j int) bool { return template.Items[i].Order < template.Items[j].Order })
+}
@@ -0,0 +1,281 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"net/http"
+	"strings"
+	"time"
+
+	"dod-backend/middleware"
+	"dod-backend/models"
+	"dod-backend/totp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	totpIssuer        = "DoD Manager"
+	recoveryCodeCount = 10
+)
+
+// Two-Factor Controllers
+func (ctrl *Controller) SetupTwoFactor(c *gin.Context) {
+	var user models.User
+	if err := ctrl.DB.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.TwoFactorEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	// The secret stays pending until a first code proves the app is set up
+	if err := ctrl.DB.Model(&user).Updates(map[string]interface{}{"totp_secret": secret, "totp_last_counter": 0}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": totp.URI(totpIssuer, user.Email, secret),
+	})
+}
+
+func (ctrl *Controller) EnableTwoFactor(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := ctrl.DB.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.TwoFactorEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start two-factor setup first"})
+		return
+	}
+
+	counter, ok := totp.Validate(user.TOTPSecret, req.Code, time.Now())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	err = tx.Model(&user).Updates(map[string]interface{}{
+		"two_factor_enabled": true,
+		"totp_last_counter":  counter,
+	}).Error
+	if err == nil {
+		err = replaceRecoveryCodes(tx, user.ID, codes)
+	}
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+func (ctrl *Controller) DisableTwoFactor(c *gin.Context) {
+	var req models.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := ctrl.DB.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !user.TwoFactorEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	ok, err := ctrl.verifySecondFactor(tx, &user, req.Code)
+	if err != nil || !ok {
+		tx.Rollback()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+
+	err = tx.Model(&user).Updates(map[string]interface{}{
+		"two_factor_enabled": false,
+		"totp_secret":        "",
+		"totp_last_counter":  0,
+	}).Error
+	if err == nil {
+		err = tx.Where("user_id = ?", user.ID).Delete(models.RecoveryCode{}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// challengeSecondFactor responds to a first login step with the challenge
+// token that VerifyTwoFactorLogin exchanges for a session.
+func (ctrl *Controller) challengeSecondFactor(c *gin.Context, user *models.User) {
+	challenge, err := middleware.GenerateChallengeToken(user.ID, ctrl.Cfg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":             "Two-factor authentication required",
+		"two_factor_required": true,
+		"challenge_token":     challenge,
+	})
+}
+
+// VerifyTwoFactorLogin is the second step of Login, and of OpenID Connect
+// logins, for users with 2FA: it
+// exchanges the challenge token and a code for a real session.
+func (ctrl *Controller) VerifyTwoFactorLogin(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := middleware.ParseChallengeToken(req.ChallengeToken, ctrl.Cfg)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+
+	var user models.User
+	if err := ctrl.DB.First(&user, claims.UserID).Error; err != nil || !user.TwoFactorEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+
+	// Codes count towards the same lockout as passwords
+	if !ctrl.checkLoginLock(c, &user) {
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	ok, err := ctrl.verifySecondFactor(tx, &user, req.Code)
+	if err != nil || !ok {
+		tx.Rollback()
+		ctrl.recordLoginFailure(c, &user)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+
+	session, err := ctrl.issueSession(tx, &user)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	ctrl.resetLoginFailures(&user)
+
+	session["message"] = "Login successful"
+	session["user"] = user
+	c.JSON(http.StatusOK, session)
+}
+
+// verifySecondFactor accepts either a TOTP code, which cannot be replayed
+// within its time window, or an unused recovery code, which is consumed.
+func (ctrl *Controller) verifySecondFactor(tx *gorm.DB, user *models.User, code string) (bool, error) {
+	if counter, ok := totp.Validate(user.TOTPSecret, code, time.Now()); ok {
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND totp_last_counter < ?", user.ID, counter).
+			Update("totp_last_counter", counter)
+		return result.RowsAffected == 1, result.Error
+	}
+
+	result := tx.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, middleware.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func generateRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 10)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.EncodeToString(buf))
+		codes[i] = raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+	}
+	return codes, nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	for _, code := range codes {
+		recoveryCode := models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: middleware.HashToken(normalizeRecoveryCode(code)),
+		}
+		if err := tx.Create(&recoveryCode).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,399 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dod-backend/authz"
+	"dod-backend/mailer"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// Waiver Controllers
+//
+// A waiver lets a required item go unmet: once its designated approver
+// approves it, the item counts as satisfied until the waiver expires.
+func (ctrl *Controller) RequestWaiver(c *gin.Context) {
+	workItem, item, _, ok := ctrl.loadSignOff(c, authz.WorkItemUpdate, "No permission to edit this work item")
+	if !ok {
+		return
+	}
+
+	var req models.CreateWaiverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if !item.IsRequired {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only required items can be waived"})
+		return
+	}
+	if !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expiry must be in the future"})
+		return
+	}
+	if req.ApproverID == ctrl.personBehind(userID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Waivers cannot be approved by whoever requests them"})
+		return
+	}
+	if ctrl.isServiceAccount(req.ApproverID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Waivers cannot be approved by an API key"})
+		return
+	}
+
+	approver, err := authz.Resolve(ctrl.DB, workItem.ProjectID, req.ApproverID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return
+	}
+	if !approver.Can(authz.ItemCheck) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The approver must be able to check items of this project"})
+		return
+	}
+
+	var open int
+	err = ctrl.DB.Model(&models.Waiver{}).
+		Where("work_item_id = ? AND do_d_item_id = ? AND (status = ? OR (status = ? AND expires_at > ?))",
+			workItem.ID, item.ID, models.WaiverPending, models.WaiverApproved, time.Now()).
+		Count(&open).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waivers"})
+		return
+	}
+	if open > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This item already has a waiver"})
+		return
+	}
+
+	waiver := models.Waiver{
+		ProjectID:     workItem.ProjectID,
+		WorkItemID:    workItem.ID,
+		DoDItemID:     item.ID,
+		Justification: req.Justification,
+		FollowUpRef:   req.FollowUpRef,
+		ExpiresAt:     req.ExpiresAt,
+		Status:        models.WaiverPending,
+		RequestedBy:   userID,
+		ApproverID:    req.ApproverID,
+	}
+	if err := ctrl.DB.Create(&waiver).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request waiver"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Waiver requested successfully",
+		"waiver":  waiver,
+	})
+}
+
+func (ctrl *Controller) GetProjectWaivers(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, uint(projectID), authz.ProjectView, "No access to this project") {
+		return
+	}
+
+	query := ctrl.DB.Where("project_id = ?", projectID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if workItemID := c.Query("work_item_id"); workItemID != "" {
+		query = query.Where("work_item_id = ?", workItemID)
+	}
+
+	var waivers []models.Waiver
+	err = query.Preload("Requester").
+		Preload("Approver").
+		Preload("Item").
+		Order("id").
+		Find(&waivers).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waivers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"waivers": waivers})
+}
+
+func (ctrl *Controller) ApproveWaiver(c *gin.Context) {
+	ctrl.decideWaiver(c, models.WaiverApproved, "Waiver approved")
+}
+
+func (ctrl *Controller) RejectWaiver(c *gin.Context) {
+	ctrl.decideWaiver(c, models.WaiverRejected, "Waiver rejected")
+}
+
+// RevokeWaiver withdraws a pending or approved waiver, making the item count
+// as unmet again.
+func (ctrl *Controller) RevokeWaiver(c *gin.Context) {
+	waiver, ok := ctrl.loadWaiver(c)
+	if !ok {
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, waiver.ProjectID, authz.WorkItemUpdate, "No permission to edit this work item") {
+		return
+	}
+
+	if waiver.Status != models.WaiverPending && waiver.Status != models.WaiverApproved {
+		c.JSON(http.StatusConflict, gin.H{"error": "Waiver is no longer in force"})
+		return
+	}
+
+	if err := ctrl.DB.Model(waiver).Update("status", models.WaiverRevoked).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke waiver"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Waiver revoked",
+		"waiver":  waiver,
+	})
+}
+
+func (ctrl *Controller) GetProjectAlerts(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, uint(projectID), authz.ProjectView, "No access to this project") {
+		return
+	}
+
+	var alerts []models.Alert
+	if err := ctrl.DB.Where("project_id = ?", projectID).Order("id desc").Find(&alerts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alerts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts})
+}
+
+// decideWaiver lets the designated approver take a decision on a pending
+// waiver.
+func (ctrl *Controller) decideWaiver(c *gin.Context, status, message string) {
+	waiver, ok := ctrl.loadWaiver(c)
+	if !ok {
+		return
+	}
+
+	var req models.DecideWaiverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if waiver.ApproverID != c.GetUint("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the designated approver can decide on this waiver"})
+		return
+	}
+	// The approver may have lost the permission since the waiver was requested
+	if !authz.Require(c, ctrl.DB, waiver.ProjectID, authz.ItemCheck, "The approver can no longer check items of this project") {
+		return
+	}
+	if waiver.Status != models.WaiverPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Waiver is not pending"})
+		return
+	}
+	now := time.Now()
+	if status == models.WaiverApproved && !waiver.ExpiresAt.After(now) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Waiver has already expired"})
+		return
+	}
+
+	var workItem models.WorkItem
+	if err := ctrl.DB.First(&workItem, waiver.WorkItemID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Work item not found"})
+		return
+	}
+	wasMet := ctrl.dodMet(&workItem)
+
+	waiver.Status = status
+	waiver.DecidedAt = &now
+	waiver.DecisionComment = req.Comment
+	err := ctrl.DB.Model(waiver).Updates(map[string]interface{}{
+		"status":           status,
+		"decided_at":       now,
+		"decision_comment": req.Comment,
+	}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decide on waiver"})
+		return
+	}
+
+	// An approved waiver can be what the work item was missing
+	if workItem.Status, err = ctrl.workItemStatus(&workItem); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute work item status"})
+		return
+	}
+	ctrl.emitCompleted(&workItem, wasMet, c.GetUint("user_id"))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"waiver":  waiver,
+	})
+}
+
+// loadWaiver resolves the :id/:waiverId route parameters, writing the error
+// response itself when the waiver cannot be found.
+func (ctrl *Controller) loadWaiver(c *gin.Context) (*models.Waiver, bool) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return nil, false
+	}
+
+	waiverID, err := strconv.Atoi(c.Param("waiverId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid waiver ID"})
+		return nil, false
+	}
+
+	var waiver models.Waiver
+	if err := ctrl.DB.Where("id = ? AND project_id = ?", waiverID, projectID).First(&waiver).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Waiver not found"})
+		return nil, false
+	}
+
+	return &waiver, true
+}
+
+// Waiver expiry
+
+// WatchWaivers announces waivers about to run out and expires them as they
+// do, every interval, until the process exits.
+func (ctrl *Controller) WatchWaivers(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		if _, err := ctrl.RemindExpiringWaivers(now); err != nil {
+			log.Printf("Failed to remind expiring waivers: %v", err)
+		}
+		if _, err := ctrl.ExpireWaivers(now); err != nil {
+			log.Printf("Failed to expire waivers: %v", err)
+		}
+	}
+}
+
+// ExpireWaivers marks the approved waivers that ran out by now as expired.
+// Their work items count the item as unmet again, leave the done state if
+// they no longer meet their DoD, and an alert is raised on the project,
+// emailed to the requester and the approver and posted to chat channels.
+// A waiver that fails to expire is logged and tried again next time, without
+// holding back the others.
+func (ctrl *Controller) ExpireWaivers(now time.Time) (int, error) {
+	var waivers []models.Waiver
+	err := ctrl.DB.Where("status = ? AND expires_at <= ?", models.WaiverApproved, now).
+		Preload("Requester").
+		Preload("Approver").
+		Preload("Item").
+		Find(&waivers).Error
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, waiver := range waivers {
+		if err := ctrl.expireWaiver(waiver); err != nil {
+			log.Printf("Failed to expire waiver %d: %v", waiver.ID, err)
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+func (ctrl *Controller) expireWaiver(waiver models.Waiver) error {
+	var workItem models.WorkItem
+	if err := ctrl.DB.First(&workItem, waiver.WorkItemID).Error; err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("The waiver on %q for work item %q expired", waiver.Item.Title, workItem.Title)
+	if waiver.FollowUpRef != "" {
+		message += fmt.Sprintf(" (follow-up: %s)", waiver.FollowUpRef)
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := tx.Model(&waiver).Update("status", models.WaiverExpired).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	alert := models.Alert{
+		ProjectID:  waiver.ProjectID,
+		WorkItemID: &workItem.ID,
+		Kind:       models.AlertWaiverExpired,
+		Message:    message,
+	}
+	if err := tx.Create(&alert).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	var reopened *models.WorkItemStatus
+	if workItem.State == models.WorkItemDone && workItem.DoDID != nil {
+		status, err := ctrl.workItemStatus(&workItem)
+		if err != nil {
+			return err
+		}
+		if !status.Done {
+			if err := ctrl.DB.Model(&workItem).Update("state", models.WorkItemInProgress).Error; err != nil {
+				return err
+			}
+			reopened = status
+		}
+	}
+
+	ctrl.notifyWaiver(models.NotifyWaiverExpired, &waiver, &workItem)
+	if reopened != nil {
+		ctrl.notifyWorkItemBlocked(&workItem, "The work item left the done state: its waiver expired.", itemTitles(reopened.UnmetRequired))
+	}
+
+	// The alert is stored either way, so a failed email is only logged
+	for _, user := range []models.User{waiver.Requester, waiver.Approver} {
+		msg := mailer.Message{
+			To:      user.Email,
+			Subject: "Waiver expired: " + waiver.Item.Title,
+			Body:    message + ".\n\nJustification: " + waiver.Justification + "\n",
+		}
+		if err := ctrl.Mailer.Send(msg); err != nil {
+			log.Printf("Failed to send waiver alert: %v", err)
+		}
+	}
+	return nil
+}
+
+// activeWaivers returns the approved waivers of a work item still in force,
+// by DoD item.
+func activeWaivers(db *gorm.DB, workItemID uint) (map[uint]models.Waiver, error) {
+	var waivers []models.Waiver
+	err := db.Where("work_item_id = ? AND status = ? AND expires_at > ?", workItemID, models.WaiverApproved, time.Now()).
+		Find(&waivers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byItem := make(map[uint]models.Waiver, len(waivers))
+	for _, waiver := range waivers {
+		byItem[waiver.DoDItemID] = waiver
+	}
+	return byItem, nil
+}
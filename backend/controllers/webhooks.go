@@ -0,0 +1,547 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"dod-backend/models"
+	"dod-backend/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// webhookDisableAfter is the number of failed attempts in a row after which
+// a webhook is disabled.
+const webhookDisableAfter = 20
+
+// webhookLease keeps a delivery being attempted from being picked up again.
+const webhookLease = time.Minute
+
+// webhookWorkers bounds the number of endpoints delivered to at once.
+const webhookWorkers = 8
+
+// Webhook Controllers
+//
+// Project owners subscribe URLs to the events of their project. Events are
+// queued as deliveries, sent in the background and retried with an
+// exponential backoff; the delivery log can be replayed.
+func (ctrl *Controller) GetProjectWebhooks(c *gin.Context) {
+	project, ok := ctrl.loadIntegrationProject(c)
+	if !ok {
+		return
+	}
+
+	var hooks []models.Webhook
+	if err := ctrl.DB.Where("project_id = ?", project.ID).Order("id").Find(&hooks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": hooks, "events": models.WebhookEvents})
+}
+
+// CreateProjectWebhook returns the signing secret, generated unless given,
+// which is never shown again.
+func (ctrl *Controller) CreateProjectWebhook(c *gin.Context) {
+	project, ok := ctrl.loadIntegrationProject(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !checkEvents(c, req.Events, models.WebhookEvents) {
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = webhooks.NewSecret(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+			return
+		}
+	}
+
+	hook := models.Webhook{
+		ProjectID: project.ID,
+		URL:       req.URL,
+		Secret:    secret,
+		Active:    true,
+		CreatedBy: c.GetUint("user_id"),
+	}
+	if err := hook.SetEvents(req.Events); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := ctrl.DB.Create(&hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Webhook created successfully",
+		"webhook": hook,
+		"secret":  secret,
+	})
+}
+
+// UpdateProjectWebhook re-enabling a webhook clears its failures.
+func (ctrl *Controller) UpdateProjectWebhook(c *gin.Context) {
+	hook, ok := ctrl.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.URL != nil {
+		hook.URL = *req.URL
+	}
+	if req.Events != nil {
+		if !checkEvents(c, req.Events, models.WebhookEvents) {
+			return
+		}
+		if err := hook.SetEvents(req.Events); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Active != nil && *req.Active != hook.Active {
+		hook.Active = *req.Active
+		hook.Failures = 0
+		hook.DisabledAt = nil
+		hook.DisabledReason = ""
+		if !hook.Active {
+			now := time.Now()
+			hook.DisabledAt = &now
+		}
+	}
+
+	if err := ctrl.DB.Save(hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook updated successfully",
+		"webhook": hook,
+	})
+}
+
+func (ctrl *Controller) DeleteProjectWebhook(c *gin.Context) {
+	hook, ok := ctrl.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := tx.Where("webhook_id = ?", hook.ID).Delete(models.WebhookDelivery{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+	if err := tx.Delete(hook).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// PingProjectWebhook queues a ping event, to test the receiver.
+func (ctrl *Controller) PingProjectWebhook(c *gin.Context) {
+	hook, ok := ctrl.loadWebhook(c)
+	if !ok {
+		return
+	}
+	if !hook.Active {
+		c.JSON(http.StatusConflict, gin.H{"error": "Webhook is disabled"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	payload, err := webhookPayload(hook.ProjectID, &userID, models.EventPing, gin.H{"webhook_id": hook.ID, "events": hook.EventList})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue ping"})
+		return
+	}
+	delivery, err := ctrl.queueDelivery(hook, models.EventPing, payload, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue ping"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Ping queued",
+		"delivery": delivery,
+	})
+}
+
+func (ctrl *Controller) GetWebhookDeliveries(c *gin.Context) {
+	hook, ok := ctrl.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	query := ctrl.DB.Where("webhook_id = ?", hook.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id desc").Limit(100).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// ReplayWebhookDelivery queues the payload of a past delivery again, as a new
+// delivery.
+func (ctrl *Controller) ReplayWebhookDelivery(c *gin.Context) {
+	hook, ok := ctrl.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := strconv.Atoi(c.Param("deliveryId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+
+	var original models.WebhookDelivery
+	if err := ctrl.DB.Where("id = ? AND webhook_id = ?", deliveryID, hook.ID).First(&original).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+	if !hook.Active {
+		c.JSON(http.StatusConflict, gin.H{"error": "Webhook is disabled"})
+		return
+	}
+
+	delivery, err := ctrl.queueDelivery(hook, original.Event, original.Payload, &original.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay delivery"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Delivery replayed",
+		"delivery": delivery,
+	})
+}
+
+// Webhook helpers
+
+// loadWebhook resolves the :id/:webhookId route parameters, writing the
+// error response itself.
+func (ctrl *Controller) loadWebhook(c *gin.Context) (*models.Webhook, bool) {
+	project, ok := ctrl.loadIntegrationProject(c)
+	if !ok {
+		return nil, false
+	}
+
+	webhookID, err := strconv.Atoi(c.Param("webhookId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return nil, false
+	}
+
+	var hook models.Webhook
+	if err := ctrl.DB.Where("id = ? AND project_id = ?", webhookID, project.ID).First(&hook).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return nil, false
+	}
+	return &hook, true
+}
+
+// checkEvents rejects events missing from known, "*" standing for all of
+// them, writing the error response itself.
+func checkEvents(c *gin.Context, events, known []string) bool {
+	for _, event := range events {
+		found := event == "*"
+		for _, candidate := range known {
+			found = found || candidate == event
+		}
+		if !found {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown event %q", event)})
+			return false
+		}
+	}
+	return true
+}
+
+func webhookPayload(projectID uint, actorID *uint, event string, data interface{}) (string, error) {
+	payload, err := json.Marshal(models.WebhookEvent{
+		Event:     event,
+		ProjectID: projectID,
+		ActorID:   actorID,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+	return string(payload), err
+}
+
+func (ctrl *Controller) queueDelivery(hook *models.Webhook, event, payload string, replayOf *uint) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	delivery := models.WebhookDelivery{
+		WebhookID:     hook.ID,
+		ProjectID:     hook.ProjectID,
+		Event:         event,
+		Payload:       payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &now,
+		ReplayOf:      replayOf,
+	}
+	if err := ctrl.DB.Create(&delivery).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// emit queues the event for the active webhooks of the project subscribed to
+// it. It must be called once the change is committed, which a failure to
+// queue does not undo: it is only logged.
+func (ctrl *Controller) emit(projectID uint, actorID *uint, event string, data interface{}) {
+	var hooks []models.Webhook
+	if err := ctrl.DB.Where("project_id = ? AND active = ?", projectID, true).Find(&hooks).Error; err != nil {
+		log.Printf("Failed to fetch webhooks of project %d: %v", projectID, err)
+		return
+	}
+
+	var payload string
+	for i := range hooks {
+		if !hooks[i].Subscribes(event) {
+			continue
+		}
+		if payload == "" {
+			var err error
+			if payload, err = webhookPayload(projectID, actorID, event, data); err != nil {
+				log.Printf("Failed to encode %s event: %v", event, err)
+				return
+			}
+		}
+		if _, err := ctrl.queueDelivery(&hooks[i], event, payload, nil); err != nil {
+			log.Printf("Failed to queue %s event for webhook %d: %v", event, hooks[i].ID, err)
+		}
+	}
+}
+
+// emitFrom emits the event on behalf of the user of the request.
+func (ctrl *Controller) emitFrom(c *gin.Context, projectID uint, event string, data interface{}) {
+	userID := c.GetUint("user_id")
+	ctrl.emit(projectID, &userID, event, data)
+}
+
+// emitJoined emits the participations of a user who joined projects through
+// invitations.
+func (ctrl *Controller) emitJoined(user *models.User, projectIDs []uint) {
+	if len(projectIDs) == 0 {
+		return
+	}
+
+	var participants []models.ProjectParticipant
+	if err := ctrl.DB.Where("user_id = ? AND project_id IN (?)", user.ID, projectIDs).Find(&participants).Error; err != nil {
+		log.Printf("Failed to fetch participations of user %d: %v", user.ID, err)
+		return
+	}
+	for _, participant := range participants {
+		ctrl.emit(participant.ProjectID, &user.ID, models.EventParticipantAdded, participant)
+	}
+}
+
+// emitItem emits an item event on the project of the item's DoD.
+func (ctrl *Controller) emitItem(c *gin.Context, event string, item *models.DoDItem) {
+	var dod models.DoD
+	if err := ctrl.DB.First(&dod, item.DoDID).Error; err != nil {
+		log.Printf("Failed to fetch DoD %d: %v", item.DoDID, err)
+		return
+	}
+	ctrl.emitFrom(c, dod.ProjectID, event, item)
+}
+
+// WatchWebhooks sends the due deliveries every interval, forever.
+func (ctrl *Controller) WatchWebhooks(interval time.Duration) {
+	for {
+		if _, err := ctrl.DeliverWebhooks(time.Now()); err != nil {
+			log.Printf("Failed to deliver webhooks: %v", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// DeliverWebhooks attempts the deliveries due at now, and returns how many
+// were delivered. Endpoints are delivered to concurrently, each one its
+// deliveries in order, so that a slow endpoint only holds back its own.
+func (ctrl *Controller) DeliverWebhooks(now time.Time) (int, error) {
+	var due []models.WebhookDelivery
+	err := ctrl.DB.Where("status IN (?) AND next_attempt_at <= ?", []string{models.DeliveryPending, models.DeliveryFailed}, now).
+		Order("id").
+		Limit(100).
+		Find(&due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var hookIDs []uint
+	byHook := map[uint][]*models.WebhookDelivery{}
+	for i := range due {
+		if _, ok := byHook[due[i].WebhookID]; !ok {
+			hookIDs = append(hookIDs, due[i].WebhookID)
+		}
+		byHook[due[i].WebhookID] = append(byHook[due[i].WebhookID], &due[i])
+	}
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		delivered int
+		errs      []error
+	)
+	workers := make(chan struct{}, webhookWorkers)
+	for _, hookID := range hookIDs {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(deliveries []*models.WebhookDelivery) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			for _, delivery := range deliveries {
+				ok, err := ctrl.attemptDelivery(delivery, now)
+				mu.Lock()
+				if ok {
+					delivered++
+				}
+				if err != nil {
+					errs = append(errs, err)
+				}
+				mu.Unlock()
+				if err != nil {
+					return
+				}
+			}
+		}(byHook[hookID])
+	}
+	wg.Wait()
+	return delivered, errors.Join(errs...)
+}
+
+// attemptDelivery sends the delivery once, scheduling the next attempt or
+// giving it up when it fails, and disables the webhook after too many
+// failures in a row.
+func (ctrl *Controller) attemptDelivery(delivery *models.WebhookDelivery, now time.Time) (bool, error) {
+	// Claim the attempt, in case another instance picked the delivery too
+	lease := now.Add(webhookLease)
+	claim := ctrl.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND attempts = ?", delivery.ID, delivery.Attempts).
+		Updates(map[string]interface{}{"attempts": delivery.Attempts + 1, "next_attempt_at": lease})
+	if claim.Error != nil {
+		return false, claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return false, nil
+	}
+	delivery.Attempts++
+
+	var hook models.Webhook
+	if err := ctrl.DB.First(&hook, delivery.WebhookID).Error; err != nil || !hook.Active {
+		delivery.Status = models.DeliveryFailed
+		delivery.Error = "Webhook is disabled"
+		delivery.NextAttemptAt = nil
+		return false, ctrl.DB.Save(delivery).Error
+	}
+
+	sender := ctrl.Webhooks
+	if sender == nil {
+		sender = webhooks.NewSender()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	result, err := sender.Send(ctx, webhooks.Request{
+		URL:        hook.URL,
+		Secret:     hook.Secret,
+		Event:      delivery.Event,
+		DeliveryID: delivery.ID,
+		Payload:    []byte(delivery.Payload),
+	})
+	delivery.ResponseStatus = result.StatusCode
+	delivery.DurationMs = result.Duration.Milliseconds()
+
+	if err == nil {
+		delivery.Status = models.DeliveryDelivered
+		delivery.Error = ""
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+		hook.Failures = 0
+	} else {
+		delivery.Status = models.DeliveryFailed
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = nil
+		if delivery.Attempts < webhooks.MaxAttempts {
+			next := now.Add(webhooks.Backoff(delivery.Attempts))
+			delivery.NextAttemptAt = &next
+		}
+		hook.Failures++
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := tx.Save(delivery).Error; err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := tx.Model(&hook).Update("failures", hook.Failures).Error; err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if hook.Failures >= webhookDisableAfter {
+		if err := disableWebhook(tx, &hook, now); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+	return delivery.Status == models.DeliveryDelivered, tx.Commit().Error
+}
+
+// disableWebhook stops deliveries to the webhook and raises an alert on its
+// project. It runs within the caller's transaction.
+func disableWebhook(tx *gorm.DB, hook *models.Webhook, now time.Time) error {
+	reason := fmt.Sprintf("Disabled after %d failed deliveries in a row", hook.Failures)
+	err := tx.Model(hook).Updates(map[string]interface{}{
+		"active":          false,
+		"disabled_at":     now,
+		"disabled_reason": reason,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&models.Alert{
+		ProjectID: hook.ProjectID,
+		Kind:      models.AlertWebhookDisabled,
+		Message:   fmt.Sprintf("Webhook to %s: %s", hook.URL, reason),
+	}).Error
+}
@@ -0,0 +1,525 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"dod-backend/authz"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Work Item Controllers
+func (ctrl *Controller) CreateWorkItem(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var req models.CreateWorkItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if !authz.Require(c, ctrl.DB, uint(projectID), authz.WorkItemCreate, "No permission to create work items for this project") {
+		return
+	}
+
+	if req.DoDID != nil && !ctrl.dodBelongsToProject(*req.DoDID, uint(projectID)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "DoD does not belong to this project"})
+		return
+	}
+	if req.DoDID != nil && ctrl.isReadyChecklist(*req.DoDID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A ready checklist cannot be attached to a work item"})
+		return
+	}
+
+	if req.Type == "" {
+		req.Type = "story"
+	}
+
+	workItem := models.WorkItem{
+		ProjectID:   uint(projectID),
+		DoDID:       req.DoDID,
+		Title:       req.Title,
+		ExternalRef: req.ExternalRef,
+		Type:        req.Type,
+		Component:   req.Component,
+		Size:        req.Size,
+		CreatedBy:   userID,
+	}
+	if err := workItem.SetLabels(req.Labels); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Without an explicit DoD, the one its rules select, if any
+	if workItem.DoDID == nil {
+		if workItem.DoDID, err = ctrl.selectDoD(uint(projectID), workItem.Attributes()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evaluate applicability"})
+			return
+		}
+	}
+	if err := ctrl.DB.Create(&workItem).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create work item"})
+		return
+	}
+	ctrl.emitFrom(c, workItem.ProjectID, models.EventWorkItemCreated, workItem)
+
+	if workItem.Status, err = ctrl.workItemStatus(&workItem); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute work item status"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Work item created successfully",
+		"work_item": workItem,
+	})
+}
+
+func (ctrl *Controller) GetProjectWorkItems(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, uint(projectID), authz.ProjectView, "No access to this project") {
+		return
+	}
+
+	var workItems []models.WorkItem
+	if err := ctrl.DB.Where("project_id = ?", projectID).Find(&workItems).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch work items"})
+		return
+	}
+
+	for i := range workItems {
+		if workItems[i].Status, err = ctrl.workItemStatus(&workItems[i]); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute work item status"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"work_items": workItems})
+}
+
+func (ctrl *Controller) GetWorkItem(c *gin.Context) {
+	workItem, ok := ctrl.loadWorkItem(c)
+	if !ok {
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, workItem.ProjectID, authz.ProjectView, "No access to this project") {
+		return
+	}
+
+	var err error
+	if workItem.Status, err = ctrl.workItemStatus(workItem); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute work item status"})
+		return
+	}
+	if workItem.Readiness, err = ctrl.workItemReadiness(workItem); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute work item status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"work_item": workItem})
+}
+
+func (ctrl *Controller) AttachWorkItemDoD(c *gin.Context) {
+	workItem, ok := ctrl.loadWorkItem(c)
+	if !ok {
+		return
+	}
+
+	var req models.AttachDoDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !authz.Require(c, ctrl.DB, workItem.ProjectID, authz.WorkItemUpdate, "No permission to edit this work item") {
+		return
+	}
+
+	if !ctrl.dodBelongsToProject(req.DoDID, workItem.ProjectID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "DoD does not belong to this project"})
+		return
+	}
+	if ctrl.isReadyChecklist(req.DoDID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A ready checklist cannot be attached to a work item"})
+		return
+	}
+
+	// Only work items already started are pinned straight away
+	var revisionID *uint
+	var err error
+	if workItem.State != models.WorkItemTodo {
+		if revisionID, err = ctrl.revisionInForce(req.DoDID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+			return
+		}
+	}
+
+	// Completions are keyed by DoDItem, so ticks recorded against a previous DoD
+	// are simply ignored by the status computation.
+	workItem.DoDID = &req.DoDID
+	workItem.DoDRevisionID = revisionID
+	err = ctrl.DB.Model(workItem).Updates(map[string]interface{}{
+		"do_d_id":          req.DoDID,
+		"do_d_revision_id": revisionID,
+	}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to attach DoD"})
+		return
+	}
+
+	if workItem.Status, err = ctrl.workItemStatus(workItem); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute work item status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "DoD attached successfully",
+		"work_item": workItem,
+	})
+}
+
+func (ctrl *Controller) CheckWorkItemDoDItem(c *gin.Context) {
+	workItem, ok := ctrl.loadWorkItem(c)
+	if !ok {
+		return
+	}
+
+	itemID, err := strconv.Atoi(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid DoD item ID"})
+		return
+	}
+
+	var req models.CheckDoDItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if !authz.Require(c, ctrl.DB, workItem.ProjectID, authz.ItemCheck, "No permission to check items of this work item") {
+		return
+	}
+
+	item, err := ctrl.checklistItem(workItem, uint(itemID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch DoD items"})
+		return
+	}
+	if item == nil && workItem.DoDID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No DoD attached to this work item"})
+		return
+	}
+	if item == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DoD item not found"})
+		return
+	}
+	policy := ctrl.itemPolicy(item.DoDItem)
+
+	var completion models.DoDItemCompletion
+	ctrl.DB.Where("work_item_id = ? AND do_d_item_id = ?", workItem.ID, item.ID).
+		FirstOrInit(&completion, models.DoDItemCompletion{WorkItemID: workItem.ID, DoDItemID: item.ID})
+
+	// Items needing approval are only ticked once approved
+	var decision string
+	needsApproval := policy.ApprovalsRequired() > 0
+	completion.Note = req.Note
+	if *req.Checked {
+		now := time.Now()
+		completion.Checked = !needsApproval
+		completion.CheckedBy = &userID
+		completion.CheckedAt = &now
+		completion.PendingApproval = needsApproval
+		completion.Approvals = 0
+		if needsApproval {
+			decision = models.DecisionRequested
+		}
+	} else {
+		if needsApproval && (completion.Checked || completion.PendingApproval) {
+			decision = models.DecisionWithdrawn
+		}
+		completion.Checked = false
+		completion.CheckedBy = nil
+		completion.CheckedAt = nil
+		completion.PendingApproval = false
+		completion.Approvals = 0
+	}
+
+	wasMet := ctrl.dodMet(workItem)
+	tx := ctrl.DB.Begin()
+	if err := tx.Save(&completion).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update DoD item completion"})
+		return
+	}
+	if decision != "" {
+		if err := recordDecision(tx, workItem.ID, item.ID, userID, decision, req.Note); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update DoD item completion"})
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update DoD item completion"})
+		return
+	}
+	ctrl.refreshPullRequests(workItem.ID)
+
+	if workItem.Status, err = ctrl.workItemStatus(workItem); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute work item status"})
+		return
+	}
+	if workItem.Readiness, err = ctrl.workItemReadiness(workItem); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute work item status"})
+		return
+	}
+	ctrl.emitCompleted(workItem, wasMet, userID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "DoD item updated successfully",
+		"completion": completion,
+		"work_item":  workItem,
+	})
+}
+
+// loadWorkItem resolves the :id/:workItemId route parameters, writing the
+// error response itself when the work item cannot be found.
+func (ctrl *Controller) loadWorkItem(c *gin.Context) (*models.WorkItem, bool) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return nil, false
+	}
+
+	workItemID, err := strconv.Atoi(c.Param("workItemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid work item ID"})
+		return nil, false
+	}
+
+	var workItem models.WorkItem
+	if err := ctrl.DB.Where("id = ? AND project_id = ?", workItemID, projectID).First(&workItem).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Work item not found"})
+		return nil, false
+	}
+
+	return &workItem, true
+}
+
+func (ctrl *Controller) dodBelongsToProject(dodID, projectID uint) bool {
+	var dod models.DoD
+	return ctrl.DB.Where("id = ? AND project_id = ?", dodID, projectID).First(&dod).Error == nil
+}
+
+// revisionInForce returns the ID of the last published revision of the DoD,
+// which work items starting now are pinned to.
+func (ctrl *Controller) revisionInForce(dodID uint) (*uint, error) {
+	revision, err := latestRevision(ctrl.DB, dodID)
+	if err != nil || revision == nil {
+		return nil, err
+	}
+	return &revision.ID, nil
+}
+
+// workItemItems returns the checklist of a work item: the organization
+// baseline of the same kind and the items of its DoD that apply to it, as
+// published in its pinned revision, rules and baseline included. Work items
+// not pinned yet follow the last published revision, or the live items when
+// the DoD was never published.
+func (ctrl *Controller) workItemItems(workItem *models.WorkItem) ([]models.EffectiveDoDItem, *models.DoDRevision, error) {
+	var dod models.DoD
+	if err := ctrl.DB.Select("id, kind").First(&dod, *workItem.DoDID).Error; err != nil {
+		return nil, nil, err
+	}
+
+	revision, err := ctrl.workItemRevision(workItem)
+	if err != nil {
+		return nil, nil, err
+	}
+	if revision == nil {
+		items, err := ctrl.effectiveItems(workItem.ProjectID, dod.Kind, []uint{dod.ID})
+		if err != nil {
+			return nil, nil, err
+		}
+		items, err = ctrl.applicableItems(workItem, items)
+		return items, nil, err
+	}
+
+	var items []models.EffectiveDoDItem
+	if revision.Inherited == nil {
+		items, err = ctrl.inheritedItems(workItem.ProjectID, dod.Kind)
+	} else {
+		baseline := make([]models.DoDItem, 0, len(revision.Inherited))
+		for _, item := range revision.Inherited {
+			baseline = append(baseline, models.DoDItem{
+				ID:          item.ItemID,
+				DoDID:       item.DoDID,
+				Title:       item.Title,
+				Description: item.Description,
+				IsRequired:  item.IsRequired,
+				Order:       item.Order,
+			})
+		}
+		items, err = ctrl.inherit(workItem.ProjectID, baseline)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var liveRules map[uint][]models.ApplicabilityRule
+	attributes := workItem.Attributes()
+	for _, item := range revision.Items {
+		rules := item.Rules
+		if rules == nil {
+			if liveRules == nil {
+				if _, liveRules, err = ctrl.rulesFor([]uint{revision.DoDID}); err != nil {
+					return nil, nil, err
+				}
+			}
+			rules = liveRules[item.ItemID]
+		}
+		if applies, _, _ := matchRules(rules, attributes); !applies {
+			continue
+		}
+		items = append(items, models.EffectiveDoDItem{DoDItem: models.DoDItem{
+			ID:          item.ItemID,
+			DoDID:       revision.DoDID,
+			Title:       item.Title,
+			Description: item.Description,
+			IsRequired:  item.IsRequired,
+			Order:       item.Order,
+		}})
+	}
+	return items, revision, nil
+}
+
+// workItemRevision returns the revision the work item is judged against: the
+// one it is pinned to, else the last published one, nil when there is none.
+func (ctrl *Controller) workItemRevision(workItem *models.WorkItem) (*models.DoDRevision, error) {
+	if workItem.DoDRevisionID == nil {
+		return latestRevision(ctrl.DB, *workItem.DoDID)
+	}
+
+	var revision models.DoDRevision
+	if err := ctrl.DB.First(&revision, *workItem.DoDRevisionID).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// workItemStatus evaluates a work item against its attached DoD, merged with
+// the organization baseline. The item is done only when every required
+// DoDItem has been checked; items not applicable to the project are left out.
+func (ctrl *Controller) workItemStatus(workItem *models.WorkItem) (*models.WorkItemStatus, error) {
+	status := &models.WorkItemStatus{
+		Criteria:      []models.WorkItemCriterion{},
+		UnmetRequired: []models.DoDItem{},
+		Waived:        []models.DoDItem{},
+	}
+	if workItem.DoDID == nil {
+		return status, nil
+	}
+
+	items, revision, err := ctrl.workItemItems(workItem)
+	if err != nil {
+		return nil, err
+	}
+	if revision != nil {
+		status.Revision = revision.Number
+	}
+	return status, ctrl.evaluateItems(status, workItem.ID, items)
+}
+
+// dodMet tells whether the work item meets its DoD, taken before a change to
+// its checklist for emitCompleted.
+func (ctrl *Controller) dodMet(workItem *models.WorkItem) bool {
+	if workItem.DoDID == nil {
+		return false
+	}
+	status, err := ctrl.workItemStatus(workItem)
+	return err == nil && status.Done
+}
+
+// emitCompleted emits workitem.done when the change to the checklist of the
+// work item, whose status is computed again, met its DoD: it was not met
+// before. Every change that can complete a work item goes through it.
+func (ctrl *Controller) emitCompleted(workItem *models.WorkItem, wasMet bool, actorID uint) {
+	if wasMet || workItem.DoDID == nil || workItem.Status == nil || !workItem.Status.Done {
+		return
+	}
+	ctrl.emit(workItem.ProjectID, &actorID, models.EventWorkItemDone, workItem)
+}
+
+// evaluateItems fills the status with the completions the work item holds
+// for the given checklist.
+func (ctrl *Controller) evaluateItems(status *models.WorkItemStatus, workItemID uint, items []models.EffectiveDoDItem) error {
+	var completions []models.DoDItemCompletion
+	if err := ctrl.DB.Where("work_item_id = ?", workItemID).Find(&completions).Error; err != nil {
+		return err
+	}
+	byItem := make(map[uint]models.DoDItemCompletion, len(completions))
+	for _, completion := range completions {
+		byItem[completion.DoDItemID] = completion
+	}
+
+	waivers, err := activeWaivers(ctrl.DB, workItemID)
+	if err != nil {
+		return err
+	}
+
+	for _, effective := range items {
+		if effective.NotApplicable {
+			continue
+		}
+		item := effective.DoDItem
+		completion := byItem[item.ID]
+		criterion := models.WorkItemCriterion{
+			Item:      item,
+			Inherited: effective.Inherited,
+			Checked:   completion.Checked,
+			CheckedBy: completion.CheckedBy,
+			CheckedAt: completion.CheckedAt,
+			Note:      completion.Note,
+
+			PendingApproval: completion.PendingApproval,
+			Approvals:       completion.Approvals,
+			EvidenceID:      completion.EvidenceID,
+		}
+		waiver, waived := waivers[item.ID]
+		waived = waived && item.IsRequired && !completion.Checked
+		if waived {
+			criterion.WaiverID = &waiver.ID
+			criterion.WaivedUntil = &waiver.ExpiresAt
+		}
+		status.Criteria = append(status.Criteria, criterion)
+
+		if !item.IsRequired {
+			continue
+		}
+		status.RequiredTotal++
+		switch {
+		case completion.Checked:
+			status.RequiredMet++
+		case waived:
+			status.RequiredMet++
+			status.Waived = append(status.Waived, item)
+		default:
+			status.UnmetRequired = append(status.UnmetRequired, item)
+		}
+	}
+
+	status.Done = status.RequiredMet == status.RequiredTotal
+	return nil
+}
@@ -31,19 +31,65 @@ func Initialize(cfg *config.Config) *gorm.DB {
     log.Println("Database connected successfully")
     
     // Auto migrate les modèles
-    err = db.AutoMigrate(
+    if err := Migrate(db); err != nil {
+        log.Fatal("Failed to migrate database:", err)
+    }
+    
+    log.Println("Database migration completed")
+    return db
+}
+
+// Migrate crée ou met à jour le schéma pour tous les modèles
+func Migrate(db *gorm.DB) error {
+    // Completions are unique per work item and item: keep the latest of
+    // those recorded twice before the index existed
+    if db.HasTable(&models.DoDItemCompletion{}) {
+        err := db.Exec("DELETE FROM do_d_item_completions WHERE id NOT IN " +
+            "(SELECT MAX(id) FROM do_d_item_completions GROUP BY work_item_id, do_d_item_id)").Error
+        if err != nil {
+            return err
+        }
+    }
+
+    return db.AutoMigrate(
         &models.User{},
         &models.Project{},
         &models.DoD{},
         &models.DoDItem{},
         &models.ProjectParticipant{},
+        &models.WorkItem{},
+        &models.DoDItemCompletion{},
+        &models.ProjectInvitation{},
+        &models.RefreshToken{},
+        &models.RevokedToken{},
+        &models.PasswordResetToken{},
+        &models.RecoveryCode{},
+        &models.OIDCLoginState{},
+        &models.UserIdentity{},
+        &models.APIToken{},
+        &models.AuditLog{},
+        &models.ProjectRole{},
+        &models.Organization{},
+        &models.OrganizationMember{},
+        &models.Team{},
+        &models.TeamMember{},
+        &models.ProjectTeam{},
+        &models.NotApplicableItem{},
+        &models.DoDTemplate{},
+        &models.DoDTemplateItem{},
+        &models.DoDRevision{},
+        &models.ApplicabilityRule{},
+        &models.ItemDecision{},
+        &models.Waiver{},
+        &models.Alert{},
+        &models.Evidence{},
+        &models.Repository{},
+        &models.PullRequest{},
+        &models.WebhookReceipt{},
+        &models.Webhook{},
+        &models.WebhookDelivery{},
+        &models.NotificationChannel{},
     ).Error
-    if err != nil {
-        log.Fatal("Failed to migrate database:", err)
-    }
-    
-    log.Println("Database migration completed")
-    return db
 }
 
 func Close(db *gorm.DB) {
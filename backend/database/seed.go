@@ -63,10 +63,11 @@ func SeedDatabase(db *gorm.DB) {
 		}
 		db.Create(&participant)
 
-		// Create sample DoD
+		// Create sample DoD from the built-in feature template
+		template, _ := BuiltinTemplate("feature")
 		dod := models.DoD{
-			Title:       "Feature Development DoD",
-			Description: "Definition of Done for feature development tasks",
+			Title:       template.Name,
+			Description: template.Description,
 			ProjectID:   project.ID,
 			CreatedBy:   alice.ID,
 			IsActive:    true,
@@ -74,49 +75,11 @@ func SeedDatabase(db *gorm.DB) {
 		db.Create(&dod)
 		log.Printf("Created DoD: %s", dod.Title)
 
-		// Add DoD items
-		dodItems := []models.DoDItem{
-			{
-				DoDID:       dod.ID,
-				Title:       "Code Review Completed",
-				Description: "All code has been reviewed by at least one other developer",
-				IsRequired:  true,
-				Order:       1,
-			},
-			{
-				DoDID:       dod.ID,
-				Title:       "Unit Tests Written",
-				Description: "Unit tests cover at least 80% of the new code",
-				IsRequired:  true,
-				Order:       2,
-			},
-			{
-				DoDID:       dod.ID,
-				Title:       "Integration Tests Pass",
-				Description: "All existing integration tests pass with new changes",
-				IsRequired:  true,
-				Order:       3,
-			},
-			{
-				DoDID:       dod.ID,
-				Title:       "Documentation Updated",
-				Description: "Technical documentation has been updated to reflect changes",
-				IsRequired:  false,
-				Order:       4,
-			},
-			{
-				DoDID:       dod.ID,
-				Title:       "Performance Testing",
-				Description: "Performance impact has been evaluated",
-				IsRequired:  false,
-				Order:       5,
-			},
+		if err := ApplyTemplate(db, dod.ID, template); err != nil {
+			log.Printf("Failed to create DoD items: %v", err)
+		} else {
+			log.Printf("Created %d DoD items", len(template.Items))
 		}
-
-		for _, item := range dodItems {
-			db.Create(&item)
-		}
-		log.Printf("Created %d DoD items", len(dodItems))
 	}
 
 	log.Println("Database seeding completed!")
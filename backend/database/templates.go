@@ -0,0 +1,102 @@
+package database
+
+import (
+	"dod-backend/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// BuiltinTemplates are the DoD templates available to everyone.
+var BuiltinTemplates = []models.DoDTemplate{
+	{
+		Key:         "feature",
+		Name:        "Feature Development DoD",
+		Description: "Definition of Done for feature development tasks",
+		Items: []models.DoDTemplateItem{
+			{Title: "Code Review Completed", Description: "All code has been reviewed by at least one other developer", IsRequired: true, Order: 1, AutoCheck: models.AutoCheckReview},
+			{Title: "Unit Tests Written", Description: "Unit tests cover at least 80% of the new code", IsRequired: true, Order: 2, AutoCheck: models.AutoCheckCoverage},
+			{Title: "Integration Tests Pass", Description: "All existing integration tests pass with new changes", IsRequired: true, Order: 3, AutoCheck: models.AutoCheckTests},
+			{Title: "Documentation Updated", Description: "Technical documentation has been updated to reflect changes", IsRequired: false, Order: 4},
+			{Title: "Performance Testing", Description: "Performance impact has been evaluated", IsRequired: false, Order: 5},
+		},
+	},
+	{
+		Key:         "bugfix",
+		Name:        "Bug Fix DoD",
+		Description: "Definition of Done for bug fixes",
+		Items: []models.DoDTemplateItem{
+			{Title: "Root Cause Identified", Description: "The cause of the bug is understood and documented in the ticket", IsRequired: true, Order: 1},
+			{Title: "Regression Test Added", Description: "A test reproduces the bug and passes with the fix", IsRequired: true, Order: 2},
+			{Title: "Code Review Completed", Description: "The fix has been reviewed by at least one other developer", IsRequired: true, Order: 3, AutoCheck: models.AutoCheckReview},
+			{Title: "Verified By Reporter", Description: "The reporter confirmed the fix in a test environment", IsRequired: false, Order: 4},
+		},
+	},
+	{
+		Key:         "release",
+		Name:        "Release DoD",
+		Description: "Definition of Done for shipping a release",
+		Items: []models.DoDTemplateItem{
+			{Title: "All Tests Pass", Description: "The full test suite passes on the release branch", IsRequired: true, Order: 1, AutoCheck: models.AutoCheckTests},
+			{Title: "Release Notes Written", Description: "User-facing changes are listed in the release notes", IsRequired: true, Order: 2},
+			{Title: "Version Tagged", Description: "The release commit is tagged with its version", IsRequired: true, Order: 3},
+			{Title: "Rollback Plan Ready", Description: "The steps to roll back the release are documented", IsRequired: true, Order: 4},
+			{Title: "Stakeholders Notified", Description: "Support and product teams know what is shipping", IsRequired: false, Order: 5},
+		},
+	},
+	{
+		Key:         "spike",
+		Name:        "Spike DoD",
+		Description: "Definition of Done for time-boxed investigations",
+		Items: []models.DoDTemplateItem{
+			{Title: "Question Answered", Description: "The question the spike was opened for has an answer", IsRequired: true, Order: 1},
+			{Title: "Findings Shared", Description: "Findings have been written down and shared with the team", IsRequired: true, Order: 2},
+			{Title: "Follow-up Work Created", Description: "Follow-up stories have been created where needed", IsRequired: false, Order: 3},
+		},
+	},
+}
+
+// BuiltinTemplate returns the built-in template with the key.
+func BuiltinTemplate(key string) (*models.DoDTemplate, bool) {
+	for i := range BuiltinTemplates {
+		if BuiltinTemplates[i].Key == key {
+			template := BuiltinTemplates[i]
+			template.BuiltIn = true
+			return &template, true
+		}
+	}
+	return nil, false
+}
+
+// ApplyTemplate adds the items of the template to the DoD.
+func ApplyTemplate(db *gorm.DB, dodID uint, template *models.DoDTemplate) error {
+	for _, templateItem := range template.Items {
+		item := models.DoDItem{
+			DoDID:       dodID,
+			Title:       templateItem.Title,
+			Description: templateItem.Description,
+			IsRequired:  templateItem.IsRequired,
+			Order:       templateItem.Order,
+
+			AutoCheck:          templateItem.AutoCheck,
+			AutoCheckThreshold: templateItem.AutoCheckThreshold,
+		}
+		if err := CreateDoDItem(db, &item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateDoDItem inserts the item, persisting optional items as such: gorm
+// leaves false out of the insert, where is_required defaults to true, and
+// reloads the default into the item.
+func CreateDoDItem(db *gorm.DB, item *models.DoDItem) error {
+	required := item.IsRequired
+	if err := db.Create(item).Error; err != nil {
+		return err
+	}
+	if required {
+		return nil
+	}
+	return db.Model(item).Update("is_required", false).Error
+}
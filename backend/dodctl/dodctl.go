@@ -0,0 +1,96 @@
+// Package dodctl implements the dodctl command, apart from its main so that
+// it can be run from tests.
+package dodctl
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"dod-backend/client"
+)
+
+// Exit codes of the gate command
+const (
+	ExitPass  = 0
+	ExitFail  = 1
+	ExitError = 2
+)
+
+// Run runs the command line args, without the program name, and returns the
+// exit code.
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: dodctl gate [flags] <work item ref>")
+		return ExitError
+	}
+
+	switch args[0] {
+	case "gate":
+		return gate(args[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "dodctl: unknown command %q\n", args[0])
+		return ExitError
+	}
+}
+
+func gate(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("gate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	server := flags.String("server", envOr("DOD_SERVER", "http://localhost:8080"), "DoD API base URL")
+	token := flags.String("token", os.Getenv("DOD_TOKEN"), "API token, preferably a project API key with the gate scope")
+	project := flags.Uint("project", 0, "project ID, when not using a project API key")
+	strict := flags.Bool("strict", false, "fail work items that only pass thanks to waivers")
+	asJSON := flags.Bool("json", false, "print the verdict as JSON")
+	if err := flags.Parse(args); err != nil {
+		return ExitError
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: dodctl gate [flags] <work item ref>")
+		return ExitError
+	}
+	if *token == "" {
+		fmt.Fprintln(stderr, "dodctl: no token, set -token or DOD_TOKEN")
+		return ExitError
+	}
+
+	verdict, err := client.New(*server, *token).Gate(context.Background(), flags.Arg(0), *project)
+	if err != nil {
+		fmt.Fprintf(stderr, "dodctl: %v\n", err)
+		return ExitError
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(verdict)
+	} else {
+		printVerdict(stdout, verdict)
+	}
+
+	if !verdict.Pass || (*strict && verdict.Verdict == client.VerdictWaived) {
+		return ExitFail
+	}
+	return ExitPass
+}
+
+func printVerdict(w io.Writer, verdict *client.Verdict) {
+	name := verdict.ExternalRef
+	if name == "" {
+		name = fmt.Sprintf("#%d", verdict.WorkItemID)
+	}
+	fmt.Fprintf(w, "%s %s: %s (%d/%d required items met)\n", name, verdict.Title, verdict.Verdict, verdict.RequiredMet, verdict.RequiredTotal)
+	for _, reason := range verdict.Reasons {
+		fmt.Fprintf(w, "  - %s\n", reason)
+	}
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
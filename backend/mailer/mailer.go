@@ -0,0 +1,114 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+
+	"dod-backend/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends transactional emails (invitations, password resets, ...).
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New returns an SMTP mailer when SMTP_HOST is configured and falls back to
+// logging emails otherwise, which is enough for local development.
+func New(cfg *config.Config) Mailer {
+	if cfg.SMTPHost == "" {
+		return &LogMailer{}
+	}
+	return &SMTPMailer{
+		Addr:     net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort),
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.MailFrom,
+	}
+}
+
+type SMTPMailer struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, _ := net.SplitHostPort(m.Addr)
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("send mail to %q: %w", msg.To, err)
+	}
+
+	// Subjects carry user input (project names, item titles): line breaks
+	// would start new headers
+	subject := strings.Join(strings.Fields(msg.Subject), " ")
+	headers := []string{
+		"From: " + m.From,
+		"To: " + to.Address,
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	body := strings.Join(headers, "\r\n") + "\r\n\r\n" + msg.Body
+
+	// The envelope sender must be a bare address even when From carries a display name
+	sender := m.From
+	if addr, err := mail.ParseAddress(m.From); err == nil {
+		sender = addr.Address
+	}
+
+	if err := smtp.SendMail(m.Addr, auth, sender, []string{to.Address}, []byte(body)); err != nil {
+		return fmt.Errorf("send mail to %s: %w", msg.To, err)
+	}
+	return nil
+}
+
+type LogMailer struct{}
+
+func (m *LogMailer) Send(msg Message) error {
+	log.Printf("Email to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// MemoryMailer keeps sent messages in memory, for tests.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	Messages []Message
+}
+
+func (m *MemoryMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Messages = append(m.Messages, msg)
+	return nil
+}
+
+// Last returns the most recent message sent to the address.
+func (m *MemoryMailer) Last(to string) (Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.Messages) - 1; i >= 0; i-- {
+		if m.Messages[i].To == to {
+			return m.Messages[i], true
+		}
+	}
+	return Message{}, false
+}
@@ -3,8 +3,10 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"dod-backend/config"
+	"dod-backend/controllers"
 	"dod-backend/database"
 	"dod-backend/routes"
 
@@ -40,7 +42,14 @@ func main() {
 	r := gin.Default()
 
 	// Configurer les routes
-	routes.SetupRoutes(r, db)
+	ctrl := controllers.NewController(db, cfg)
+	routes.SetupRoutesWithController(r, ctrl)
+
+	// Expirer les dérogations arrivées à échéance
+	go ctrl.WatchWaivers(time.Minute)
+
+	// Envoyer les webhooks en attente
+	go ctrl.WatchWebhooks(10 * time.Second)
 
 	// Démarrer le serveur
 	port := os.Getenv("PORT")
@@ -14,19 +14,29 @@ import (
 )
 
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	UserID       uint   `json:"user_id"`
+	Username     string `json:"username"`
+	Email        string `json:"email"`
+	TokenVersion int    `json:"ver"`
 	jwt.StandardClaims
 }
 
 func GenerateJWT(user *models.User, cfg *config.Config) (string, error) {
+	jti, err := newJTI()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := &Claims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Email:    user.Email,
+		UserID:       user.ID,
+		Username:     user.Username,
+		Email:        user.Email,
+		TokenVersion: user.TokenVersion,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(24 * time.Hour).Unix(),
+			Id:        jti,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(cfg.AccessTokenTTL).Unix(),
 			Issuer:    "dod-backend",
 		},
 	}
@@ -35,7 +45,7 @@ func GenerateJWT(user *models.User, cfg *config.Config) (string, error) {
 	return token.SignedString([]byte(cfg.JWTSecret))
 }
 
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+func AuthMiddleware(cfg *config.Config, db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -45,6 +55,12 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
+
+		// Personal access tokens and project API keys share the header
+		if IsAPIToken(tokenString) {
+			authenticateAPIToken(c, db, tokenString)
+			return
+		}
 		
 		claims := &Claims{}
 		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
@@ -57,19 +73,43 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		if isRevoked(db, claims) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("email", claims.Email)
+		c.Set("auth_method", AuthMethodSession)
+		c.Set("jti", claims.Id)
+		c.Set("token_expires_at", time.Unix(claims.ExpiresAt, 0))
 		c.Next()
 	}
 }
 
+// isRevoked rejects tokens logged out individually (by jti) and tokens issued
+// before the user logged out of all sessions (by version).
+func isRevoked(db *gorm.DB, claims *Claims) bool {
+	var user models.User
+	if err := db.Select("id, token_version").First(&user, claims.UserID).Error; err != nil {
+		return true
+	}
+	if user.TokenVersion != claims.TokenVersion {
+		return true
+	}
+
+	var revoked models.RevokedToken
+	return !db.Where("jti = ?", claims.Id).First(&revoked).RecordNotFound()
+}
+
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Credentials", "true")
 		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"dod-backend/config"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// ChallengeTTL is how long a user has to type their second factor after
+// giving a valid password.
+const ChallengeTTL = 5 * time.Minute
+
+type ChallengeClaims struct {
+	UserID uint `json:"user_id"`
+	jwt.StandardClaims
+}
+
+// GenerateChallengeToken proves that the password step of a two-step login
+// succeeded. Like invitation tokens it is signed with a derived key, so it
+// cannot be used as a session token.
+func GenerateChallengeToken(userID uint, cfg *config.Config) (string, error) {
+	claims := &ChallengeClaims{
+		UserID: userID,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(ChallengeTTL).Unix(),
+			Issuer:    "dod-backend",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(challengeKey(cfg))
+}
+
+func ParseChallengeToken(tokenString string, cfg *config.Config) (*ChallengeClaims, error) {
+	claims := &ChallengeClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return challengeKey(cfg), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid challenge token")
+	}
+	return claims, nil
+}
+
+func challengeKey(cfg *config.Config) []byte {
+	return []byte(cfg.JWTSecret + ":2fa")
+}
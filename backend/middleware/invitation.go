@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"dod-backend/config"
+	"dod-backend/models"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// InvitationTTL is how long an emailed invitation stays usable.
+const InvitationTTL = 7 * 24 * time.Hour
+
+type InvitationClaims struct {
+	InvitationID uint   `json:"invitation_id"`
+	Email        string `json:"email"`
+	jwt.StandardClaims
+}
+
+// GenerateInvitationToken signs the link sent by email. It uses a key derived
+// from the JWT secret so that it can never be replayed as a session token.
+func GenerateInvitationToken(invitation *models.ProjectInvitation, cfg *config.Config) (string, error) {
+	claims := &InvitationClaims{
+		InvitationID: invitation.ID,
+		Email:        invitation.Email,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: invitation.ExpiresAt.Unix(),
+			Issuer:    "dod-backend",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(invitationKey(cfg))
+}
+
+func ParseInvitationToken(tokenString string, cfg *config.Config) (*InvitationClaims, error) {
+	claims := &InvitationClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return invitationKey(cfg), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid invitation token")
+	}
+	return claims, nil
+}
+
+func invitationKey(cfg *config.Config) []byte {
+	return []byte(cfg.JWTSecret + ":invitation")
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dod-backend/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit throttles the route per client IP. The scope keeps separate
+// budgets for separate endpoints.
+func RateLimit(limiter *ratelimit.Limiter, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ok, retryAfter := limiter.Allow(scope + ":" + c.ClientIP()); !ok {
+			TooManyRequests(c, retryAfter, "Too many requests, try again later")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// TooManyRequests writes a 429 telling the client when to come back.
+func TooManyRequests(c *gin.Context, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       message,
+		"retry_after": seconds,
+	})
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Scopes granted to API tokens. Interactive sessions implicitly hold them all.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeGate  = "gate"
+)
+
+// APIToken authenticates scripts and CI pipelines. A personal access token
+// acts as the user who created it; a project API key acts as a service
+// account participating in that project only. Only the hash is stored, the
+// prefix is kept so that tokens can be told apart.
+type APIToken struct {
+	ID         uint       `json:"id" gorm:"primary_key"`
+	Name       string     `json:"name" gorm:"not null"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	ProjectID  *uint      `json:"project_id" gorm:"index"`
+	CreatedBy  uint       `json:"created_by" gorm:"not null"`
+	Prefix     string     `json:"prefix" gorm:"not null"`
+	TokenHash  string     `json:"-" gorm:"not null;unique_index"`
+	Scopes     string     `json:"-" gorm:"not null"` // comma separated
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// Decoded from Scopes on read
+	ScopeList []string `json:"scopes" gorm:"-"`
+}
+
+func (t *APIToken) SetScopes(scopes []string) {
+	t.ScopeList = scopes
+	t.Scopes = strings.Join(scopes, ",")
+}
+
+func (t *APIToken) AfterFind() error {
+	t.ScopeList = []string{}
+	if t.Scopes != "" {
+		t.ScopeList = strings.Split(t.Scopes, ",")
+	}
+	return nil
+}
+
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.ScopeList {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *APIToken) Expired() bool {
+	return t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt)
+}
+
+// DTOs pour les requêtes
+type CreateAPITokenRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=read write gate"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
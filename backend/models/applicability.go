@@ -0,0 +1,121 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrCommaInList is returned for list values containing a comma, the
+// separator of the columns they are stored in.
+var ErrCommaInList = errors.New("values cannot contain commas")
+
+// ApplicabilityRule narrows which work items a DoD, or one of its items,
+// applies to. A rule matches a work item when every condition it sets holds;
+// a DoD or item with rules applies when one of them matches, and to every
+// work item when it has none.
+type ApplicabilityRule struct {
+	ID         uint      `json:"id" gorm:"primary_key"`
+	DoDID      uint      `json:"dod_id" gorm:"not null"`
+	DoDItemID  *uint     `json:"item_id"` // nil for a rule on the whole DoD
+	Name       string    `json:"name"`
+	Types      string    `json:"-"` // comma separated
+	Labels     string    `json:"-"` // comma separated, any of them
+	Components string    `json:"-"` // comma separated
+	MinSize    *int      `json:"min_size"`
+	MaxSize    *int      `json:"max_size"`
+	CreatedBy  uint      `json:"created_by" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// Decoded from the comma separated columns on read
+	TypeList      []string `json:"types" gorm:"-"`
+	LabelList     []string `json:"labels" gorm:"-"`
+	ComponentList []string `json:"components" gorm:"-"`
+}
+
+func (r *ApplicabilityRule) SetConditions(types, labels, components []string) error {
+	var err error
+	if r.TypeList, r.Types, err = joinList(types); err != nil {
+		return err
+	}
+	if r.LabelList, r.Labels, err = joinList(labels); err != nil {
+		return err
+	}
+	r.ComponentList, r.Components, err = joinList(components)
+	return err
+}
+
+func (r *ApplicabilityRule) AfterFind() error {
+	r.TypeList = splitList(r.Types)
+	r.LabelList = splitList(r.Labels)
+	r.ComponentList = splitList(r.Components)
+	return nil
+}
+
+// WorkItemAttributes are what applicability rules look at.
+type WorkItemAttributes struct {
+	Type      string   `json:"type"`
+	Labels    []string `json:"labels"`
+	Component string   `json:"component"`
+	Size      *int     `json:"size"`
+}
+
+// ResolvedItem is one item of a resolved checklist, with the reason it was
+// selected or left out.
+type ResolvedItem struct {
+	Item      DoDItem `json:"item"`
+	Inherited bool    `json:"inherited"`
+	RuleID    *uint   `json:"rule_id"`
+	Reason    string  `json:"reason"`
+}
+
+// ResolvedDoD tells whether a DoD applies to a work item, and why.
+type ResolvedDoD struct {
+	DoDID   uint   `json:"dod_id"`
+	Title   string `json:"title"`
+	Applies bool   `json:"applies"`
+	RuleID  *uint  `json:"rule_id"`
+	Reason  string `json:"reason"`
+}
+
+func joinList(values []string) ([]string, string, error) {
+	list := []string{}
+	for _, value := range values {
+		if strings.Contains(value, ",") {
+			return nil, "", fmt.Errorf("%q: %w", value, ErrCommaInList)
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			list = append(list, value)
+		}
+	}
+	return list, strings.Join(list, ","), nil
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
+
+// DTOs pour les requêtes
+type ApplicabilityRuleRequest struct {
+	Name       string   `json:"name"`
+	ItemID     *uint    `json:"item_id"`
+	Types      []string `json:"types" binding:"omitempty,dive,oneof=story task bug"`
+	Labels     []string `json:"labels"`
+	Components []string `json:"components"`
+	MinSize    *int     `json:"min_size" binding:"omitempty,min=0"`
+	MaxSize    *int     `json:"max_size" binding:"omitempty,min=0"`
+}
+
+type EvaluateApplicabilityRequest struct {
+	WorkItemID *uint    `json:"work_item_id"`
+	Kind       string   `json:"kind" binding:"omitempty,oneof=ready done release custom"`
+	Type       string   `json:"type" binding:"omitempty,oneof=story task bug"`
+	Labels     []string `json:"labels"`
+	Component  string   `json:"component"`
+	Size       *int     `json:"size" binding:"omitempty,min=0"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// Audited actions
+const (
+	AuditAccountLocked = "account_locked"
+)
+
+// AuditLog records security relevant events for later review.
+type AuditLog struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	Action    string    `json:"action" gorm:"not null;index"`
+	UserID    *uint     `json:"user_id" gorm:"index"`
+	IP        string    `json:"ip" gorm:"column:ip"`
+	Details   string    `json:"details"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+)
+
+// Automated checks a DoDItem can be met by, from the reports CI uploads and
+// the pull requests linked to the work item.
+const (
+	AutoCheckCoverage = "coverage" // line coverage >= threshold (%, 80 by default)
+	AutoCheckTests    = "tests"    // failed tests <= threshold (0 by default)
+	AutoCheckFindings = "findings" // high-severity findings <= threshold (0 by default)
+	AutoCheckReview   = "review"   // pull request approvals >= threshold (1 by default)
+)
+
+// AutoCheckDefaults are the thresholds of automated checks that set none.
+var AutoCheckDefaults = map[string]float64{
+	AutoCheckCoverage: 80,
+	AutoCheckTests:    0,
+	AutoCheckFindings: 0,
+	AutoCheckReview:   1,
+}
+
+// Evidence is a CI report uploaded for a work item, kept with what it says
+// so that the items it ticked can be traced back to it.
+type Evidence struct {
+	ID           uint      `json:"id" gorm:"primary_key"`
+	ProjectID    uint      `json:"project_id" gorm:"not null;index"`
+	WorkItemID   uint      `json:"work_item_id" gorm:"not null;index"`
+	Format       string    `json:"format" gorm:"not null"`
+	Name         string    `json:"name"`
+	Content      string    `json:"-" gorm:"type:text"`
+	Tests        int       `json:"tests"`
+	Failures     int       `json:"failures"`
+	Skipped      int       `json:"skipped"`
+	LinesValid   int       `json:"lines_valid"`
+	LinesCovered int       `json:"lines_covered"`
+	Coverage     *float64  `json:"coverage"`
+	Findings     int       `json:"findings"`
+	HighFindings int       `json:"high_findings"`
+	UploadedBy   uint      `json:"uploaded_by" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// CheckResult is the outcome of an automated check against a report.
+type CheckResult struct {
+	Item      DoDItem `json:"item"`
+	Passed    bool    `json:"passed"`
+	Threshold float64 `json:"threshold"`
+	Actual    float64 `json:"actual"`
+	Detail    string  `json:"detail"`
+}
+
+// AutoCheckLimit is the threshold the item's automated check applies.
+func (i *DoDItem) AutoCheckLimit() float64 {
+	if i.AutoCheckThreshold != nil {
+		return *i.AutoCheckThreshold
+	}
+	return AutoCheckDefaults[i.AutoCheck]
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// Gate verdicts. Waived passes the gate, but only thanks to waivers that
+// will run out; anything else than pass or waived fails it.
+const (
+	GatePass   = "pass"
+	GateFail   = "fail"
+	GateWaived = "waived"
+)
+
+// GateVerdict tells a CI pipeline whether a work item meets its DoD.
+type GateVerdict struct {
+	Verdict       string     `json:"verdict"`
+	Pass          bool       `json:"pass"`
+	ProjectID     uint       `json:"project_id"`
+	WorkItemID    uint       `json:"work_item_id"`
+	ExternalRef   string     `json:"external_ref"`
+	Title         string     `json:"title"`
+	DoDID         *uint      `json:"dod_id"`
+	Revision      int        `json:"revision,omitempty"`
+	RequiredTotal int        `json:"required_total"`
+	RequiredMet   int        `json:"required_met"`
+	Unmet         []GateItem `json:"unmet"`
+	Waived        []GateItem `json:"waived"`
+	Reasons       []string   `json:"reasons"`
+	EvaluatedAt   time.Time  `json:"evaluated_at"`
+}
+
+// GateItem is a required item the verdict depends on.
+type GateItem struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Inherited   bool       `json:"inherited"`
+	Reason      string     `json:"reason"`
+	WaiverID    *uint      `json:"waiver_id,omitempty"`
+	WaivedUntil *time.Time `json:"waived_until,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// ProjectInvitation is a pending participation for an email that has no
+// account yet. It turns into a ProjectParticipant when that email registers,
+// or accepts it once signed in.
+type ProjectInvitation struct {
+	ID         uint       `json:"id" gorm:"primary_key"`
+	ProjectID  uint       `json:"project_id" gorm:"not null"`
+	Email      string     `json:"email" gorm:"not null"`
+	Role       string     `json:"role" gorm:"not null"`
+	InvitedBy  uint       `json:"invited_by" gorm:"not null"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	SendError  string     `json:"send_error" gorm:"not null;default:''"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// Relations
+	Project Project `json:"-" gorm:"foreignkey:ProjectID"`
+	Inviter User    `json:"-" gorm:"foreignkey:InvitedBy"`
+}
@@ -12,6 +12,23 @@ type User struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
+	// Bumped to invalidate every access token issued so far
+	TokenVersion int `json:"-" gorm:"not null;default:0"`
+
+	// Two-factor authentication (TOTP). The secret is set at enrolment and
+	// only enforced once the first code has been verified.
+	TwoFactorEnabled bool   `json:"two_factor_enabled" gorm:"default:false"`
+	TOTPSecret       string `json:"-" gorm:"column:totp_secret"`
+	TOTPLastCounter  int64  `json:"-" gorm:"column:totp_last_counter"`
+
+	// Brute-force protection: consecutive failed logins, and the time before
+	// which no login attempt is evaluated
+	FailedLogins int        `json:"-" gorm:"not null;default:0"`
+	LockedUntil  *time.Time `json:"-"`
+
+	// Set on the accounts backing project API keys, which cannot sign in
+	ServiceAccount bool `json:"service_account" gorm:"default:false"`
+
 	// Relations
 	Projects     []Project           `json:"projects" gorm:"foreignkey:OwnerID"`
 	Participants []ProjectParticipant `json:"participants" gorm:"foreignkey:UserID"`
@@ -25,17 +42,22 @@ type Project struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
+	// Personal projects have no organization
+	OrganizationID *uint `json:"organization_id" gorm:"index"`
+
 	// Relations
 	Owner        User                 `json:"owner" gorm:"foreignkey:OwnerID"`
+	Organization *Organization        `json:"organization,omitempty" gorm:"foreignkey:OrganizationID"`
 	DoDs         []DoD               `json:"dods" gorm:"foreignkey:ProjectID"`
 	Participants []ProjectParticipant `json:"participants" gorm:"foreignkey:ProjectID"`
+	Teams        []ProjectTeam        `json:"teams,omitempty" gorm:"foreignkey:ProjectID"`
 }
 
 type ProjectParticipant struct {
 	ID        uint      `json:"id" gorm:"primary_key"`
 	ProjectID uint      `json:"project_id" gorm:"not null"`
 	UserID    uint      `json:"user_id" gorm:"not null"`
-	Role      string    `json:"role" gorm:"default:'member'"` // owner, editor, viewer
+	Role      string    `json:"role" gorm:"default:'member'"` // owner, editor, viewer or a custom project role
 	CreatedAt time.Time `json:"created_at"`
 
 	// Relations
@@ -50,15 +72,28 @@ type DoD struct {
 	ProjectID   uint      `json:"project_id" gorm:"not null"`
 	CreatedBy   uint      `json:"created_by" gorm:"not null"`
 	IsActive    bool      `json:"is_active" gorm:"default:true"`
+	Kind        string    `json:"kind" gorm:"default:'done'"` // ready, done, release, custom
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
+	// Set on organization baselines, which have no project
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"index"`
+
 	// Relations
 	Project Project   `json:"project" gorm:"foreignkey:ProjectID"`
 	Creator User      `json:"creator" gorm:"foreignkey:CreatedBy"`
 	Items   []DoDItem `json:"items" gorm:"foreignkey:DoDID"`
 }
 
+// Checklist kinds. Active ready checklists gate work items entering progress;
+// work items are judged against a done, release or custom one.
+const (
+	DoDKindReady   = "ready"
+	DoDKindDone    = "done"
+	DoDKindRelease = "release"
+	DoDKindCustom  = "custom"
+)
+
 type DoDItem struct {
 	ID          uint      `json:"id" gorm:"primary_key"`
 	DoDID       uint      `json:"dod_id" gorm:"not null"`
@@ -69,6 +104,15 @@ type DoDItem struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
+	// Sign-off policy, see VerificationSelf and friends
+	Verification      string `json:"verification" gorm:"default:'self'"`
+	ApproverRole      string `json:"approver_role"`
+	RequiredApprovals int    `json:"required_approvals" gorm:"default:1"`
+
+	// Automated check, see AutoCheckCoverage and friends; none when empty
+	AutoCheck          string   `json:"auto_check"`
+	AutoCheckThreshold *float64 `json:"auto_check_threshold"`
+
 	// Relations
 	DoD DoD `json:"dod" gorm:"foreignkey:DoDID"`
 }
@@ -83,27 +127,75 @@ type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
+
+	// Token of the emailed invitation link, proving the email is the user's
+	InvitationToken string `json:"invitation_token"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
 }
 
 type CreateProjectRequest struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
+	Name           string `json:"name" binding:"required"`
+	Description    string `json:"description"`
+	OrganizationID *uint  `json:"organization_id"`
+}
+
+type UpdateProjectRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=1"`
+	Description *string `json:"description"`
 }
 
 type CreateDoDRequest struct {
 	Title       string `json:"title" binding:"required"`
 	Description string `json:"description"`
 	ProjectID   uint   `json:"project_id" binding:"required"`
+	Kind        string `json:"kind" binding:"omitempty,oneof=ready done release custom"`
+}
+
+type UpdateDoDRequest struct {
+	Title       *string `json:"title" binding:"omitempty,min=1"`
+	Description *string `json:"description"`
+	IsActive    *bool   `json:"is_active"`
+	Kind        *string `json:"kind" binding:"omitempty,oneof=ready done release custom"`
 }
 
 type CreateDoDItemRequest struct {
-	Title       string `json:"title" binding:"required"`
-	Description string `json:"description"`
-	IsRequired  bool   `json:"is_required"`
-	Order       int    `json:"order"`
+	Title             string `json:"title" binding:"required"`
+	Description       string `json:"description"`
+	IsRequired        bool   `json:"is_required"`
+	Order             int    `json:"order"`
+	Verification      string `json:"verification" binding:"omitempty,oneof=self peer role quorum"`
+	ApproverRole      string `json:"approver_role"`
+	RequiredApprovals int    `json:"required_approvals" binding:"omitempty,min=1,max=10"`
+
+	AutoCheck          string   `json:"auto_check" binding:"omitempty,oneof=coverage tests findings review"`
+	AutoCheckThreshold *float64 `json:"auto_check_threshold" binding:"omitempty,min=0"`
+}
+
+type UpdateDoDItemRequest struct {
+	Title             *string `json:"title" binding:"omitempty,min=1"`
+	Description       *string `json:"description"`
+	IsRequired        *bool   `json:"is_required"`
+	Order             *int    `json:"order"`
+	Verification      *string `json:"verification" binding:"omitempty,oneof=self peer role quorum"`
+	ApproverRole      *string `json:"approver_role"`
+	RequiredApprovals *int    `json:"required_approvals" binding:"omitempty,min=1,max=10"`
+
+	AutoCheck          *string  `json:"auto_check" binding:"omitempty,oneof=coverage tests findings review"`
+	AutoCheckThreshold *float64 `json:"auto_check_threshold" binding:"omitempty,min=0"`
 }
 
 type AddParticipantRequest struct {
 	Email string `json:"email" binding:"required,email"`
-	Role  string `json:"role" binding:"required,oneof=editor viewer"`
-}
\ No newline at end of file
+	Role  string `json:"role" binding:"required"` // editor, viewer or a custom project role
+}
+
+type UpdateParticipantRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type TransferOwnershipRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
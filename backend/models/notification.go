@@ -0,0 +1,97 @@
+package models
+
+import (
+	"net/url"
+	"time"
+)
+
+// Events chat channels can be notified of.
+const (
+	NotifyWorkItemBlocked = "workitem.blocked" // held back by an unmet required item
+	NotifyWaiverExpiring  = "waiver.expiring"  // an approved waiver runs out within a day
+	NotifyWaiverExpired   = "waiver.expired"
+	NotifyDoDPublished    = "dod.published"
+)
+
+// NotificationEvents lists the events chat channels can subscribe to.
+var NotificationEvents = []string{
+	NotifyWorkItemBlocked, NotifyWaiverExpiring, NotifyWaiverExpired, NotifyDoDPublished,
+}
+
+// NotificationChannel posts the events of a project to a Slack or Microsoft
+// Teams incoming webhook. The webhook URL is a secret and only shown as a
+// hint.
+type NotificationChannel struct {
+	ID         uint       `json:"id" gorm:"primary_key"`
+	ProjectID  uint       `json:"project_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	Kind       string     `json:"kind" gorm:"not null"` // slack, teams
+	WebhookURL string     `json:"-" gorm:"not null"`
+	Events     string     `json:"-" gorm:"not null"` // comma separated, "*" for all
+	Active     bool       `json:"active"`
+	LastSentAt *time.Time `json:"last_sent_at"`
+	LastError  string     `json:"last_error"`
+	CreatedBy  uint       `json:"created_by" gorm:"not null"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// Decoded from Events and WebhookURL on read
+	EventList      []string `json:"events" gorm:"-"`
+	WebhookURLHint string   `json:"webhook_url_hint" gorm:"-"`
+}
+
+func (n *NotificationChannel) SetEvents(events []string) error {
+	var err error
+	n.EventList, n.Events, err = joinList(events)
+	return err
+}
+
+func (n *NotificationChannel) SetWebhookURL(webhookURL string) {
+	n.WebhookURL = webhookURL
+	n.WebhookURLHint = urlHint(webhookURL)
+}
+
+func (n *NotificationChannel) AfterFind() error {
+	n.EventList = splitList(n.Events)
+	n.WebhookURLHint = urlHint(n.WebhookURL)
+	return nil
+}
+
+// Subscribes tells whether the channel is notified of the event.
+func (n *NotificationChannel) Subscribes(event string) bool {
+	for _, subscribed := range n.EventList {
+		if subscribed == event || subscribed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// urlHint keeps the host of the URL and the end of its path, the rest being
+// the secret.
+func urlHint(webhookURL string) string {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	hint := parsed.Scheme + "://" + parsed.Host + "/…"
+	if path := parsed.Path; len(path) > 4 {
+		hint += path[len(path)-4:]
+	}
+	return hint
+}
+
+// DTOs pour les requêtes
+type CreateNotificationChannelRequest struct {
+	Name       string   `json:"name" binding:"required"`
+	Kind       string   `json:"kind" binding:"required,oneof=slack teams"`
+	WebhookURL string   `json:"webhook_url" binding:"required,url"`
+	Events     []string `json:"events" binding:"required,min=1"`
+}
+
+type UpdateNotificationChannelRequest struct {
+	Name       *string  `json:"name"`
+	WebhookURL *string  `json:"webhook_url" binding:"omitempty,url"`
+	Events     []string `json:"events" binding:"omitempty,min=1"`
+	Active     *bool    `json:"active"`
+}
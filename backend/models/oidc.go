@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// OIDCLoginState remembers an authorization request between the redirect to
+// the identity provider and the callback. It is single-use, and bound to the
+// browser that started the login by a secret kept in its session storage.
+type OIDCLoginState struct {
+	ID           uint      `json:"id" gorm:"primary_key"`
+	State        string    `json:"-" gorm:"not null;unique_index"`
+	Nonce        string    `json:"-" gorm:"not null"`
+	CodeVerifier string    `json:"-" gorm:"not null"`
+	BindingHash  string    `json:"-" gorm:"not null"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// UserIdentity links a user to an account at an external identity provider.
+type UserIdentity struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Issuer    string    `json:"issuer" gorm:"not null;unique_index:idx_user_identity_subject"`
+	Subject   string    `json:"subject" gorm:"not null;unique_index:idx_user_identity_subject"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DTOs pour les requêtes
+type OIDCCallbackRequest struct {
+	Code         string `json:"code" binding:"required"`
+	State        string `json:"state" binding:"required"`
+	LoginBinding string `json:"login_binding" binding:"required"`
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+)
+
+// Organization groups projects, typically one per company or department.
+type Organization struct {
+	ID          uint      `json:"id" gorm:"primary_key"`
+	Name        string    `json:"name" gorm:"not null"`
+	Description string    `json:"description"`
+	CreatedBy   uint      `json:"created_by" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relations
+	Members []OrganizationMember `json:"members,omitempty" gorm:"foreignkey:OrganizationID"`
+}
+
+type OrganizationMember struct {
+	ID             uint      `json:"id" gorm:"primary_key"`
+	OrganizationID uint      `json:"organization_id" gorm:"not null;unique_index:idx_organization_member"`
+	UserID         uint      `json:"user_id" gorm:"not null;unique_index:idx_organization_member"`
+	Role           string    `json:"role" gorm:"not null"` // admin, member
+	CreatedAt      time.Time `json:"created_at"`
+
+	// Relations
+	User User `json:"user" gorm:"foreignkey:UserID"`
+}
+
+// NotApplicableItem marks an optional item inherited from the organization
+// baseline as not applicable to a project.
+type NotApplicableItem struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	ProjectID uint      `json:"project_id" gorm:"not null;unique_index:idx_not_applicable_item"`
+	DoDItemID uint      `json:"dod_item_id" gorm:"not null;unique_index:idx_not_applicable_item"`
+	Reason    string    `json:"reason"`
+	CreatedBy uint      `json:"created_by" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EffectiveDoDItem is an item of the merged checklist of a project: its own
+// items plus the ones inherited from its organization.
+type EffectiveDoDItem struct {
+	DoDItem
+	Inherited           bool   `json:"inherited"`
+	Locked              bool   `json:"locked"`
+	NotApplicable       bool   `json:"not_applicable"`
+	NotApplicableReason string `json:"not_applicable_reason,omitempty"`
+}
+
+// DTOs pour les requêtes
+type CreateOrganizationRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+type UpdateOrganizationRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=1"`
+	Description *string `json:"description"`
+}
+
+type AddOrganizationMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=admin member"`
+}
+
+type UpdateOrganizationMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin member"`
+}
+
+type MoveProjectRequest struct {
+	OrganizationID uint `json:"organization_id" binding:"required"`
+}
+
+type CreateOrganizationDoDRequest struct {
+	Title       string `json:"title" binding:"required"`
+	Description string `json:"description"`
+	Kind        string `json:"kind" binding:"omitempty,oneof=ready done release custom"`
+}
+
+type MarkNotApplicableRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+)
+
+// Repository is a GitHub or GitLab repository whose webhooks are received by
+// a project. The access token, when set, lets the DoD verdict be posted back
+// as a commit status.
+type Repository struct {
+	ID            uint      `json:"id" gorm:"primary_key"`
+	ProjectID     uint      `json:"project_id" gorm:"not null;index"`
+	Provider      string    `json:"provider" gorm:"not null"`  // github, gitlab
+	FullName      string    `json:"full_name" gorm:"not null"` // owner/name, or group/subgroup/name
+	APIURL        string    `json:"api_url"`                   // the provider's public API when empty
+	WebhookSecret string    `json:"-" gorm:"not null"`
+	AccessToken   string    `json:"-"`
+	CreatedBy     uint      `json:"created_by" gorm:"not null"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	// Computed on read, never persisted
+	HasAccessToken bool `json:"has_access_token" gorm:"-"`
+}
+
+func (r *Repository) AfterFind() error {
+	r.HasAccessToken = r.AccessToken != ""
+	return nil
+}
+
+// PullRequest is a pull request (merge request on GitLab) of a repository,
+// linked to the work item it references, if any.
+type PullRequest struct {
+	ID           uint      `json:"id" gorm:"primary_key"`
+	ProjectID    uint      `json:"project_id" gorm:"not null;index"`
+	RepositoryID uint      `json:"repository_id" gorm:"not null;index"`
+	WorkItemID   *uint     `json:"work_item_id" gorm:"index"`
+	Number       int       `json:"number" gorm:"not null"`
+	Title        string    `json:"title"`
+	Branch       string    `json:"branch"`
+	HeadSHA      string    `json:"head_sha"`
+	URL          string    `json:"url"`
+	State        string    `json:"state"`    // open, closed, merged
+	CIState      string    `json:"ci_state"` // pending, success, failure, of the head commit
+	Approvers    string    `json:"-"`        // comma separated
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// Decoded from Approvers on read
+	ApproverList []string `json:"approvers" gorm:"-"`
+}
+
+// SetApproval adds or removes the reviewer's approval.
+func (p *PullRequest) SetApproval(reviewer string, approved bool) error {
+	approvers := []string{}
+	for _, approver := range p.ApproverList {
+		if approver != reviewer {
+			approvers = append(approvers, approver)
+		}
+	}
+	if approved {
+		approvers = append(approvers, reviewer)
+	}
+	var err error
+	p.ApproverList, p.Approvers, err = joinList(approvers)
+	return err
+}
+
+func (p *PullRequest) AfterFind() error {
+	p.ApproverList = splitList(p.Approvers)
+	return nil
+}
+
+// WebhookReceipt records a webhook delivery received for a repository, so
+// that the same delivery is not handled twice.
+type WebhookReceipt struct {
+	ID           uint      `json:"id" gorm:"primary_key"`
+	ProjectID    uint      `json:"project_id" gorm:"not null;index"`
+	RepositoryID uint      `json:"repository_id" gorm:"not null;unique_index:idx_webhook_receipt"`
+	DeliveryID   string    `json:"delivery_id" gorm:"not null;unique_index:idx_webhook_receipt"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// DTOs pour les requêtes
+type CreateRepositoryRequest struct {
+	Provider      string `json:"provider" binding:"required,oneof=github gitlab"`
+	FullName      string `json:"full_name" binding:"required"`
+	APIURL        string `json:"api_url" binding:"omitempty,url"`
+	WebhookSecret string `json:"webhook_secret" binding:"required,min=16"`
+	AccessToken   string `json:"access_token"`
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DoDRevision is an immutable published version of a DoD. The live items of
+// the DoD are its draft; publishing snapshots them into a new revision, along
+// with the organization baseline they are inherited with.
+type DoDRevision struct {
+	ID          uint      `json:"id" gorm:"primary_key"`
+	DoDID       uint      `json:"dod_id" gorm:"not null;unique_index:idx_dod_revision"`
+	Number      int       `json:"number" gorm:"not null;unique_index:idx_dod_revision"`
+	Snapshot    string    `json:"-" gorm:"type:text;not null"` // JSON encoded items
+	Baseline    string    `json:"-" gorm:"type:text"`          // JSON encoded inherited items
+	Changelog   string    `json:"changelog"`
+	PublishedBy uint      `json:"published_by" gorm:"not null"`
+	PublishedAt time.Time `json:"published_at"`
+
+	// Decoded from Snapshot and Baseline on read. Inherited is nil in
+	// revisions published before the baseline was kept, which inherit the
+	// live baseline.
+	Items     []RevisionItem `json:"items" gorm:"-"`
+	Inherited []RevisionItem `json:"inherited" gorm:"-"`
+
+	// Relations
+	Publisher User `json:"publisher" gorm:"foreignkey:PublishedBy"`
+}
+
+// RevisionItem is a DoDItem as it was when the revision was published, with
+// its applicability rules. It keeps the item ID so that completions still
+// apply.
+type RevisionItem struct {
+	ItemID      uint   `json:"item_id"`
+	DoDID       uint   `json:"dod_id,omitempty"` // organization DoD of inherited items
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	IsRequired  bool   `json:"is_required"`
+	Order       int    `json:"order"`
+
+	// Nil in revisions published before rules were kept, which follow the
+	// live rules
+	Rules []ApplicabilityRule `json:"rules"`
+}
+
+func (r *DoDRevision) SetItems(items []RevisionItem) error {
+	snapshot, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	r.Items = items
+	r.Snapshot = string(snapshot)
+	return nil
+}
+
+func (r *DoDRevision) SetInherited(items []RevisionItem) error {
+	baseline, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	r.Inherited = items
+	r.Baseline = string(baseline)
+	return nil
+}
+
+func (r *DoDRevision) AfterFind() error {
+	r.Items = []RevisionItem{}
+	r.Inherited = nil
+	if r.Baseline != "" {
+		r.Inherited = []RevisionItem{}
+		if err := json.Unmarshal([]byte(r.Baseline), &r.Inherited); err != nil {
+			return err
+		}
+	}
+	if r.Snapshot == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(r.Snapshot), &r.Items)
+}
+
+// RevisionItemChange is an item present on both sides of a diff with
+// different content.
+type RevisionItemChange struct {
+	ItemID uint         `json:"item_id"`
+	Fields []string     `json:"fields"`
+	Before RevisionItem `json:"before"`
+	After  RevisionItem `json:"after"`
+}
+
+// RevisionDiff compares two revisions of a DoD item by item.
+type RevisionDiff struct {
+	Added     []RevisionItem       `json:"added"`
+	Removed   []RevisionItem       `json:"removed"`
+	Changed   []RevisionItemChange `json:"changed"`
+	Unchanged int                  `json:"unchanged"`
+}
+
+// DTOs pour les requêtes
+type PublishDoDRequest struct {
+	Changelog string `json:"changelog" binding:"required"`
+}
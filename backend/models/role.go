@@ -0,0 +1,46 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// ProjectRole is a role defined by a project owner on top of the built-in
+// owner, editor and viewer. Participants refer to it by name.
+type ProjectRole struct {
+	ID          uint      `json:"id" gorm:"primary_key"`
+	ProjectID   uint      `json:"project_id" gorm:"not null;unique_index:idx_project_role_name"`
+	Name        string    `json:"name" gorm:"not null;unique_index:idx_project_role_name"`
+	Description string    `json:"description"`
+	Permissions string    `json:"-" gorm:"not null"` // comma separated
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Decoded from Permissions on read
+	PermissionList []string `json:"permissions" gorm:"-"`
+}
+
+func (r *ProjectRole) SetPermissions(permissions []string) {
+	r.PermissionList = permissions
+	r.Permissions = strings.Join(permissions, ",")
+}
+
+func (r *ProjectRole) AfterFind() error {
+	r.PermissionList = []string{}
+	if r.Permissions != "" {
+		r.PermissionList = strings.Split(r.Permissions, ",")
+	}
+	return nil
+}
+
+// DTOs pour les requêtes
+type CreateProjectRoleRequest struct {
+	Name        string   `json:"name" binding:"required,max=50"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required,min=1"`
+}
+
+type UpdateProjectRoleRequest struct {
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions" binding:"omitempty,min=1"`
+}
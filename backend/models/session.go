@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken is stored hashed; the plain value is only ever returned to the client.
+type RefreshToken struct {
+	ID            uint       `json:"id" gorm:"primary_key"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash     string     `json:"-" gorm:"not null;unique_index"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	RevokedReason string     `json:"revoked_reason" gorm:"not null;default:''"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Why a refresh token was revoked. Only rotated tokens coming back betray a
+// leak; empty reasons predate them and are taken as rotations.
+const (
+	RevokedRotated   = "rotated"
+	RevokedLoggedOut = "logged_out"
+)
+
+// RevokedToken blacklists an access token by its jti until it expires anyway.
+type RevokedToken struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	JTI       string    `json:"jti" gorm:"column:jti;not null;unique_index"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PasswordResetToken is a single-use, time-limited token emailed on request.
+type PasswordResetToken struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"not null;unique_index"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// DTOs pour les requêtes
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
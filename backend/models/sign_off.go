@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+)
+
+// Verification policies of a DoDItem: who confirms that a work item meets it.
+const (
+	VerificationSelf   = "self"   // ticking the item is enough
+	VerificationPeer   = "peer"   // approved by another participant
+	VerificationRole   = "role"   // approved by a participant holding ApproverRole
+	VerificationQuorum = "quorum" // approved by RequiredApprovals other participants
+)
+
+// Sign-off decisions recorded on a work item's DoD item.
+const (
+	DecisionRequested = "requested"
+	DecisionApproved  = "approved"
+	DecisionRejected  = "rejected"
+	DecisionCommented = "commented"
+	DecisionWithdrawn = "withdrawn"
+)
+
+// ItemDecision is one entry of the sign-off history of a DoD item on a work
+// item. Approvals only count towards the latest request.
+type ItemDecision struct {
+	ID         uint      `json:"id" gorm:"primary_key"`
+	WorkItemID uint      `json:"work_item_id" gorm:"not null;index:idx_item_decision"`
+	DoDItemID  uint      `json:"dod_item_id" gorm:"not null;index:idx_item_decision"`
+	UserID     uint      `json:"user_id" gorm:"not null"`
+	Decision   string    `json:"decision" gorm:"not null"`
+	Comment    string    `json:"comment"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// Relations
+	User User `json:"user" gorm:"foreignkey:UserID"`
+}
+
+// ApprovalsRequired is the number of approvals the item needs on top of
+// being ticked, 0 for self-checked items.
+func (i *DoDItem) ApprovalsRequired() int {
+	switch i.Verification {
+	case VerificationPeer, VerificationRole:
+		return 1
+	case VerificationQuorum:
+		if i.RequiredApprovals < 1 {
+			return 1
+		}
+		return i.RequiredApprovals
+	}
+	return 0
+}
+
+// DTOs pour les requêtes
+type SignOffRequest struct {
+	Comment string `json:"comment"`
+}
+
+type CommentRequest struct {
+	Comment string `json:"comment" binding:"required"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+)
+
+// Team is a named group of users that can be granted a role on projects as a
+// whole.
+type Team struct {
+	ID          uint      `json:"id" gorm:"primary_key"`
+	Name        string    `json:"name" gorm:"not null"`
+	Description string    `json:"description"`
+	CreatedBy   uint      `json:"created_by" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relations
+	Members []TeamMember `json:"members,omitempty" gorm:"foreignkey:TeamID"`
+}
+
+type TeamMember struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	TeamID    uint      `json:"team_id" gorm:"not null;unique_index:idx_team_member"`
+	UserID    uint      `json:"user_id" gorm:"not null;unique_index:idx_team_member"`
+	Role      string    `json:"role" gorm:"not null"` // maintainer, member
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relations
+	User User `json:"user" gorm:"foreignkey:UserID"`
+}
+
+// ProjectTeam grants a role on a project to every member of a team.
+type ProjectTeam struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	ProjectID uint      `json:"project_id" gorm:"not null;unique_index:idx_project_team"`
+	TeamID    uint      `json:"team_id" gorm:"not null;unique_index:idx_project_team"`
+	Role      string    `json:"role" gorm:"not null"` // editor, viewer or a custom project role
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relations
+	Team Team `json:"team" gorm:"foreignkey:TeamID"`
+}
+
+// DTOs pour les requêtes
+type CreateTeamRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+type UpdateTeamRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=1"`
+	Description *string `json:"description"`
+}
+
+type AddTeamMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=maintainer member"`
+}
+
+type UpdateTeamMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=maintainer member"`
+}
+
+type AddProjectTeamRequest struct {
+	TeamID uint   `json:"team_id" binding:"required"`
+	Role   string `json:"role" binding:"required"`
+}
+
+type UpdateProjectTeamRequest struct {
+	Role string `json:"role" binding:"required"`
+}
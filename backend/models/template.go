@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+)
+
+// DoDTemplate is a reusable list of items to start DoDs from. Built-in
+// templates live in code and are identified by their key; user-defined ones
+// are private to their creator unless shared with an organization.
+type DoDTemplate struct {
+	ID             uint      `json:"id,omitempty" gorm:"primary_key"`
+	Key            string    `json:"key,omitempty" gorm:"-"`
+	BuiltIn        bool      `json:"built_in" gorm:"-"`
+	Name           string    `json:"name" gorm:"not null"`
+	Description    string    `json:"description"`
+	CreatedBy      uint      `json:"created_by,omitempty"`
+	OrganizationID *uint     `json:"organization_id,omitempty" gorm:"index"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	// Relations
+	Items []DoDTemplateItem `json:"items" gorm:"foreignkey:TemplateID"`
+}
+
+type DoDTemplateItem struct {
+	ID          uint   `json:"id,omitempty" gorm:"primary_key"`
+	TemplateID  uint   `json:"template_id,omitempty" gorm:"not null;index"`
+	Title       string `json:"title" gorm:"not null"`
+	Description string `json:"description"`
+	IsRequired  bool   `json:"is_required"`
+	Order       int    `json:"order"`
+
+	AutoCheck          string   `json:"auto_check,omitempty"`
+	AutoCheckThreshold *float64 `json:"auto_check_threshold,omitempty"`
+}
+
+// DTOs pour les requêtes
+type DoDTemplateItemRequest struct {
+	Title       string `json:"title" binding:"required"`
+	Description string `json:"description"`
+	IsRequired  bool   `json:"is_required"`
+	Order       int    `json:"order"`
+}
+
+// CreateDoDTemplateRequest takes its items either from the list or from an
+// existing DoD.
+type CreateDoDTemplateRequest struct {
+	Name           string                   `json:"name" binding:"required"`
+	Description    string                   `json:"description"`
+	OrganizationID *uint                    `json:"organization_id"`
+	DoDID          *uint                    `json:"dod_id"`
+	Items          []DoDTemplateItemRequest `json:"items" binding:"dive"`
+}
+
+type UpdateDoDTemplateRequest struct {
+	Name        *string                  `json:"name" binding:"omitempty,min=1"`
+	Description *string                  `json:"description"`
+	Items       []DoDTemplateItemRequest `json:"items" binding:"omitempty,dive"`
+}
+
+// CreateDoDFromTemplateRequest names either a built-in template by key or a
+// user-defined one by ID.
+type CreateDoDFromTemplateRequest struct {
+	ProjectID   uint   `json:"project_id" binding:"required"`
+	Template    string `json:"template"`
+	TemplateID  *uint  `json:"template_id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Kind        string `json:"kind" binding:"omitempty,oneof=ready done release custom"`
+}
+
+type CloneProjectRequest struct {
+	Name                string  `json:"name" binding:"required"`
+	Description         *string `json:"description"`
+	OrganizationID      *uint   `json:"organization_id"`
+	IncludeParticipants bool    `json:"include_participants"`
+}
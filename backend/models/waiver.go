@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+)
+
+// Waiver states. Only approved waivers count, and only until they expire.
+const (
+	WaiverPending  = "pending"
+	WaiverApproved = "approved"
+	WaiverRejected = "rejected"
+	WaiverRevoked  = "revoked"
+	WaiverExpired  = "expired"
+)
+
+// Waiver lets a work item meet its DoD with a required item unmet, for a
+// limited time and on the word of a designated approver.
+type Waiver struct {
+	ID              uint       `json:"id" gorm:"primary_key"`
+	ProjectID       uint       `json:"project_id" gorm:"not null;index"`
+	WorkItemID      uint       `json:"work_item_id" gorm:"not null;index"`
+	DoDItemID       uint       `json:"dod_item_id" gorm:"not null"`
+	Justification   string     `json:"justification" gorm:"not null"`
+	FollowUpRef     string     `json:"follow_up_ref"`
+	ExpiresAt       time.Time  `json:"expires_at" gorm:"not null"`
+	Status          string     `json:"status" gorm:"not null"`
+	RequestedBy     uint       `json:"requested_by" gorm:"not null"`
+	ApproverID      uint       `json:"approver_id" gorm:"not null"`
+	DecidedAt       *time.Time `json:"decided_at"`
+	DecisionComment string     `json:"decision_comment"`
+	RemindedAt      *time.Time `json:"reminded_at"` // when the expiry was announced
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// Relations
+	Requester User    `json:"requester" gorm:"foreignkey:RequestedBy"`
+	Approver  User    `json:"approver" gorm:"foreignkey:ApproverID"`
+	Item      DoDItem `json:"item" gorm:"foreignkey:DoDItemID"`
+}
+
+// Alert kinds
+const (
+	AlertWaiverExpired   = "waiver_expired"
+	AlertWebhookDisabled = "webhook_disabled"
+)
+
+// Alert is raised on a project when something needs attention, such as a
+// waiver running out on a work item.
+type Alert struct {
+	ID         uint      `json:"id" gorm:"primary_key"`
+	ProjectID  uint      `json:"project_id" gorm:"not null;index"`
+	WorkItemID *uint     `json:"work_item_id"`
+	Kind       string    `json:"kind" gorm:"not null"`
+	Message    string    `json:"message"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DTOs pour les requêtes
+type CreateWaiverRequest struct {
+	Justification string    `json:"justification" binding:"required"`
+	ApproverID    uint      `json:"approver_id" binding:"required"`
+	ExpiresAt     time.Time `json:"expires_at" binding:"required"`
+	FollowUpRef   string    `json:"follow_up_ref"`
+}
+
+type DecideWaiverRequest struct {
+	Comment string `json:"comment"`
+}
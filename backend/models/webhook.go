@@ -0,0 +1,120 @@
+package models
+
+import (
+	"time"
+)
+
+// Events project webhooks can subscribe to. Ping is only sent on request, to
+// test a subscription.
+const (
+	EventDoDCreated       = "dod.created"
+	EventDoDUpdated       = "dod.updated"
+	EventDoDDeleted       = "dod.deleted"
+	EventDoDPublished     = "dod.published"
+	EventItemAdded        = "item.added"
+	EventItemUpdated      = "item.updated"
+	EventItemDeleted      = "item.deleted"
+	EventWorkItemCreated  = "workitem.created"
+	EventWorkItemDone     = "workitem.done"
+	EventParticipantAdded = "participant.added"
+	EventPing             = "ping"
+)
+
+// WebhookEvents lists the events webhooks can subscribe to.
+var WebhookEvents = []string{
+	EventDoDCreated, EventDoDUpdated, EventDoDDeleted, EventDoDPublished,
+	EventItemAdded, EventItemUpdated, EventItemDeleted,
+	EventWorkItemCreated, EventWorkItemDone,
+	EventParticipantAdded,
+}
+
+// Delivery states. Failed deliveries are being retried until their next
+// attempt is nil, at which point they have been given up.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook subscribes a URL to events of a project. It is disabled after too
+// many failed attempts in a row.
+type Webhook struct {
+	ID             uint       `json:"id" gorm:"primary_key"`
+	ProjectID      uint       `json:"project_id" gorm:"not null;index"`
+	URL            string     `json:"url" gorm:"not null"`
+	Secret         string     `json:"-" gorm:"not null"`
+	Events         string     `json:"-" gorm:"not null"` // comma separated, "*" for all
+	Active         bool       `json:"active"`
+	Failures       int        `json:"failures"` // failed attempts in a row
+	DisabledAt     *time.Time `json:"disabled_at"`
+	DisabledReason string     `json:"disabled_reason"`
+	CreatedBy      uint       `json:"created_by" gorm:"not null"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Decoded from Events on read
+	EventList []string `json:"events" gorm:"-"`
+}
+
+func (w *Webhook) SetEvents(events []string) error {
+	var err error
+	w.EventList, w.Events, err = joinList(events)
+	return err
+}
+
+func (w *Webhook) AfterFind() error {
+	w.EventList = splitList(w.Events)
+	return nil
+}
+
+// Subscribes tells whether the webhook is sent the event.
+func (w *Webhook) Subscribes(event string) bool {
+	for _, subscribed := range w.EventList {
+		if subscribed == event || subscribed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is an event queued for a webhook, kept as the delivery log
+// once sent or given up.
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primary_key"`
+	WebhookID      uint       `json:"webhook_id" gorm:"not null;index"`
+	ProjectID      uint       `json:"project_id" gorm:"not null;index"`
+	Event          string     `json:"event" gorm:"not null"`
+	Payload        string     `json:"payload" gorm:"type:text"`
+	Status         string     `json:"status" gorm:"not null;index"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at" gorm:"index"`
+	ResponseStatus int        `json:"response_status"`
+	Error          string     `json:"error"`
+	DurationMs     int64      `json:"duration_ms"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	ReplayOf       *uint      `json:"replay_of"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// WebhookEvent is the JSON body of deliveries.
+type WebhookEvent struct {
+	Event     string      `json:"event"`
+	ProjectID uint        `json:"project_id"`
+	ActorID   *uint       `json:"actor_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// DTOs pour les requêtes
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Secret string   `json:"secret" binding:"omitempty,min=16"`
+	Events []string `json:"events" binding:"required,min=1"`
+}
+
+type UpdateWebhookRequest struct {
+	URL    *string  `json:"url" binding:"omitempty,url"`
+	Events []string `json:"events" binding:"omitempty,min=1"`
+	Active *bool    `json:"active"`
+}
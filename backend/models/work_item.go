@@ -0,0 +1,141 @@
+package models
+
+import (
+	"time"
+)
+
+type WorkItem struct {
+	ID          uint      `json:"id" gorm:"primary_key"`
+	ProjectID   uint      `json:"project_id" gorm:"not null"`
+	DoDID       *uint     `json:"dod_id"`
+	Title       string    `json:"title" gorm:"not null"`
+	ExternalRef string    `json:"external_ref"`
+	Type        string    `json:"type" gorm:"default:'story'"` // story, task, bug
+	State       string    `json:"state" gorm:"default:'todo'"` // todo, in_progress, done
+	Labels      string    `json:"-"`                           // comma separated
+	Component   string    `json:"component"`
+	Size        *int      `json:"size"` // estimate, in points
+	CreatedBy   uint      `json:"created_by" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Published revision of the DoD the work item is judged against, set
+	// once it leaves todo. Until then it follows the last published revision,
+	// or the live items of the DoD when there is none.
+	DoDRevisionID *uint `json:"dod_revision_id"`
+
+	// Relations
+	DoD *DoD `json:"dod,omitempty" gorm:"foreignkey:DoDID"`
+
+	// Decoded from Labels on read
+	LabelList []string `json:"labels" gorm:"-"`
+
+	// Computed on read, never persisted
+	Status    *WorkItemStatus `json:"status,omitempty" gorm:"-"`
+	Readiness *WorkItemStatus `json:"readiness,omitempty" gorm:"-"`
+}
+
+func (w *WorkItem) SetLabels(labels []string) error {
+	var err error
+	w.LabelList, w.Labels, err = joinList(labels)
+	return err
+}
+
+func (w *WorkItem) AfterFind() error {
+	w.LabelList = splitList(w.Labels)
+	return nil
+}
+
+// Attributes returns what applicability rules look at.
+func (w *WorkItem) Attributes() WorkItemAttributes {
+	return WorkItemAttributes{
+		Type:      w.Type,
+		Labels:    w.LabelList,
+		Component: w.Component,
+		Size:      w.Size,
+	}
+}
+
+// Work item states. Leaving todo requires the active ready checklists of the
+// project to be satisfied.
+const (
+	WorkItemTodo       = "todo"
+	WorkItemInProgress = "in_progress"
+	WorkItemDone       = "done"
+)
+
+// DoDItemCompletion records whether a work item satisfies one DoDItem of its
+// DoD. A work item has at most one per item.
+type DoDItemCompletion struct {
+	ID         uint       `json:"id" gorm:"primary_key"`
+	WorkItemID uint       `json:"work_item_id" gorm:"not null;unique_index:idx_completion_item"`
+	DoDItemID  uint       `json:"dod_item_id" gorm:"not null;unique_index:idx_completion_item"`
+	Checked    bool       `json:"checked"`
+	CheckedBy  *uint      `json:"checked_by"`
+	CheckedAt  *time.Time `json:"checked_at"`
+	Note       string     `json:"note"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// Ticked by CheckedBy on an item that needs approval, and not yet
+	// approved: Checked stays false until it is
+	PendingApproval bool `json:"pending_approval"`
+	Approvals       int  `json:"approvals"`
+
+	// Set when an automated check ticked the item from an uploaded report
+	EvidenceID *uint `json:"evidence_id"`
+}
+
+// WorkItemStatus is the computed completion state of a work item against its DoD.
+type WorkItemStatus struct {
+	Done          bool                `json:"done"`
+	Revision      int                 `json:"revision,omitempty"`
+	RequiredTotal int                 `json:"required_total"`
+	RequiredMet   int                 `json:"required_met"`
+	Criteria      []WorkItemCriterion `json:"criteria"`
+	UnmetRequired []DoDItem           `json:"unmet_required"`
+
+	// Required items counted as met on the strength of a waiver
+	Waived []DoDItem `json:"waived"`
+}
+
+type WorkItemCriterion struct {
+	Item      DoDItem    `json:"item"`
+	Inherited bool       `json:"inherited"`
+	Checked   bool       `json:"checked"`
+	CheckedBy *uint      `json:"checked_by"`
+	CheckedAt *time.Time `json:"checked_at"`
+	Note      string     `json:"note"`
+
+	PendingApproval bool  `json:"pending_approval"`
+	Approvals       int   `json:"approvals"`
+	EvidenceID      *uint `json:"evidence_id,omitempty"`
+
+	// Set while an approved waiver stands in for the check
+	WaiverID    *uint      `json:"waiver_id,omitempty"`
+	WaivedUntil *time.Time `json:"waived_until,omitempty"`
+}
+
+// DTOs pour les requêtes
+type CreateWorkItemRequest struct {
+	Title       string   `json:"title" binding:"required"`
+	ExternalRef string   `json:"external_ref"`
+	Type        string   `json:"type" binding:"omitempty,oneof=story task bug"`
+	Labels      []string `json:"labels"`
+	Component   string   `json:"component"`
+	Size        *int     `json:"size" binding:"omitempty,min=0"`
+	DoDID       *uint    `json:"dod_id"`
+}
+
+type UpdateWorkItemStateRequest struct {
+	State string `json:"state" binding:"required,oneof=todo in_progress done"`
+}
+
+type AttachDoDRequest struct {
+	DoDID uint `json:"dod_id" binding:"required"`
+}
+
+type CheckDoDItemRequest struct {
+	Checked *bool  `json:"checked" binding:"required"`
+	Note    string `json:"note"`
+}
@@ -0,0 +1,118 @@
+// Package ratelimit implements token bucket rate limiting over a pluggable
+// bucket store.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit describes a bucket holding at most Burst tokens, refilled at Rate
+// tokens per second. Rate must be positive.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute allows n requests per minute, all of which may come at once.
+func PerMinute(n int) Limit {
+	return Limit{Rate: float64(n) / 60, Burst: n}
+}
+
+// Store holds the buckets. MemoryStore is enough for a single instance; a
+// shared store is needed once the API runs replicated.
+type Store interface {
+	// Take removes one token from the bucket at key. When it is empty, it
+	// reports how long until a token becomes available.
+	Take(key string, limit Limit, now time.Time) (ok bool, retryAfter time.Duration)
+}
+
+type Limiter struct {
+	Limit Limit
+	Store Store
+
+	// Now is replaceable for tests
+	Now func() time.Time
+}
+
+func New(limit Limit, store Store) *Limiter {
+	return &Limiter{Limit: limit, Store: store, Now: time.Now}
+}
+
+// Allow consumes a token for key. A nil limiter lets everything through.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	return l.Store.Take(key, l.Limit, l.Now())
+}
+
+// MemoryStore keeps the buckets in process memory. Buckets that have refilled
+// completely are forgotten, since they are identical to new ones.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+// sweepInterval bounds how often the store looks for forgettable buckets.
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+		b.updated = now
+	}
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	b.full = now.Add(seconds((float64(limit.Burst) - b.tokens) / limit.Rate))
+
+	if allowed {
+		return true, 0
+	}
+	return false, seconds((1 - b.tokens) / limit.Rate)
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,282 @@
+// Package reports reads the test, coverage and static analysis reports CI
+// pipelines produce: JUnit XML, Cobertura XML, LCOV and SARIF.
+package reports
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Report formats
+const (
+	JUnit     = "junit"
+	Cobertura = "cobertura"
+	LCOV      = "lcov"
+	SARIF     = "sarif"
+)
+
+// HighSeverity is the SARIF security-severity from which a finding counts as
+// high, as on GitHub code scanning.
+const HighSeverity = 7.0
+
+var ErrUnknownFormat = errors.New("unknown report format")
+
+// Summary is what a report says, depending on its format: test counts for
+// JUnit, line coverage for Cobertura and LCOV, findings for SARIF.
+type Summary struct {
+	Format string `json:"format"`
+
+	Tests    int `json:"tests"`
+	Failures int `json:"failures"`
+	Skipped  int `json:"skipped"`
+
+	LinesValid   int      `json:"lines_valid"`
+	LinesCovered int      `json:"lines_covered"`
+	Coverage     *float64 `json:"coverage,omitempty"`
+
+	Findings     int `json:"findings"`
+	HighFindings int `json:"high_findings"`
+}
+
+// Detect guesses the format of the report from its content.
+func Detect(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		if bytes.Contains(trimmed, []byte(`"runs"`)) {
+			return SARIF
+		}
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		if bytes.Contains(trimmed, []byte("<testsuite")) {
+			return JUnit
+		}
+		if bytes.Contains(trimmed, []byte("<coverage")) {
+			return Cobertura
+		}
+	case bytes.HasPrefix(trimmed, []byte("TN:")), bytes.HasPrefix(trimmed, []byte("SF:")):
+		return LCOV
+	}
+	return ""
+}
+
+// Parse summarizes the report, detecting its format when none is given.
+func Parse(format string, data []byte) (*Summary, error) {
+	if format == "" {
+		format = Detect(data)
+	}
+
+	var summary *Summary
+	var err error
+	switch format {
+	case JUnit:
+		summary, err = parseJUnit(data)
+	case Cobertura:
+		summary, err = parseCobertura(data)
+	case LCOV:
+		summary, err = parseLCOV(data)
+	case SARIF:
+		summary, err = parseSARIF(data)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	summary.Format = format
+	return summary, nil
+}
+
+// JUnit
+
+type junitSuite struct {
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+	Cases    []junitCase  `xml:"testcase"`
+}
+
+type junitCase struct {
+	Failures []struct{} `xml:"failure"`
+	Errors   []struct{} `xml:"error"`
+	Skipped  *struct{}  `xml:"skipped"`
+}
+
+// parseJUnit counts the test cases of the suites, which may be nested, and
+// falls back on the suite counters when cases are not listed.
+func parseJUnit(data []byte) (*Summary, error) {
+	var root junitSuite
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{}
+	countJUnit(summary, root)
+	return summary, nil
+}
+
+func countJUnit(summary *Summary, suite junitSuite) {
+	if len(suite.Cases) == 0 && len(suite.Suites) == 0 {
+		summary.Tests += suite.Tests
+		summary.Failures += suite.Failures + suite.Errors
+		summary.Skipped += suite.Skipped
+		return
+	}
+
+	for _, testCase := range suite.Cases {
+		summary.Tests++
+		switch {
+		case len(testCase.Failures) > 0 || len(testCase.Errors) > 0:
+			summary.Failures++
+		case testCase.Skipped != nil:
+			summary.Skipped++
+		}
+	}
+	for _, nested := range suite.Suites {
+		countJUnit(summary, nested)
+	}
+}
+
+// Coverage
+
+type coberturaReport struct {
+	LineRate     float64 `xml:"line-rate,attr"`
+	LinesValid   int     `xml:"lines-valid,attr"`
+	LinesCovered int     `xml:"lines-covered,attr"`
+}
+
+func parseCobertura(data []byte) (*Summary, error) {
+	var report coberturaReport
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{LinesValid: report.LinesValid, LinesCovered: report.LinesCovered}
+	coverage := report.LineRate * 100
+	if report.LinesValid > 0 {
+		coverage = float64(report.LinesCovered) * 100 / float64(report.LinesValid)
+	}
+	summary.Coverage = &coverage
+	return summary, nil
+}
+
+// parseLCOV adds up the lines found (LF) and hit (LH) of every source file.
+// Coverage is left out when no line was found: an empty report proves nothing.
+func parseLCOV(data []byte) (*Summary, error) {
+	summary := &Summary{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		var target *int
+		switch {
+		case strings.HasPrefix(line, "LF:"):
+			target = &summary.LinesValid
+		case strings.HasPrefix(line, "LH:"):
+			target = &summary.LinesCovered
+		default:
+			continue
+		}
+		count, err := strconv.Atoi(line[3:])
+		if err != nil {
+			return nil, errors.New("invalid LCOV line: " + line)
+		}
+		*target += count
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if summary.LinesValid > 0 {
+		coverage := float64(summary.LinesCovered) * 100 / float64(summary.LinesValid)
+		summary.Coverage = &coverage
+	}
+	return summary, nil
+}
+
+// SARIF
+
+type sarifLog struct {
+	Runs []struct {
+		Tool struct {
+			Driver struct {
+				Rules []sarifRule `json:"rules"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results []struct {
+			RuleID     string          `json:"ruleId"`
+			Level      string          `json:"level"`
+			Properties sarifProperties `json:"properties"`
+		} `json:"results"`
+	} `json:"runs"`
+}
+
+type sarifRule struct {
+	ID                   string `json:"id"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+	Properties sarifProperties `json:"properties"`
+}
+
+type sarifProperties struct {
+	SecuritySeverity json.RawMessage `json:"security-severity"`
+}
+
+// severity reads security-severity, which tools write as a string or a
+// number; -1 when it is missing.
+func (p sarifProperties) severity() float64 {
+	raw := strings.Trim(string(p.SecuritySeverity), `"`)
+	if raw == "" {
+		return -1
+	}
+	severity, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return -1
+	}
+	return severity
+}
+
+// parseSARIF counts the results, and as high those at the error level or
+// with a high security-severity, taking both from the rule when the result
+// does not say.
+func parseSARIF(data []byte) (*Summary, error) {
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		return nil, err
+	}
+	if log.Runs == nil {
+		return nil, errors.New("SARIF report has no runs")
+	}
+
+	summary := &Summary{}
+	for _, run := range log.Runs {
+		rules := make(map[string]sarifRule, len(run.Tool.Driver.Rules))
+		for _, rule := range run.Tool.Driver.Rules {
+			rules[rule.ID] = rule
+		}
+
+		for _, result := range run.Results {
+			rule := rules[result.RuleID]
+			level := result.Level
+			if level == "" {
+				level = rule.DefaultConfiguration.Level
+			}
+			severity := result.Properties.severity()
+			if severity < 0 {
+				severity = rule.Properties.severity()
+			}
+
+			summary.Findings++
+			if level == "error" || severity >= HighSeverity {
+				summary.HighFindings++
+			}
+		}
+	}
+	return summary, nil
+}
@@ -1,9 +1,11 @@
 package routes
 
 import (
+	"dod-backend/authz"
 	"dod-backend/config"
 	"dod-backend/controllers"
 	"dod-backend/middleware"
+	"dod-backend/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
@@ -11,7 +13,13 @@ import (
 
 func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	cfg := config.Load()
-	ctrl := controllers.NewController(db, cfg)
+	SetupRoutesWithController(r, controllers.NewController(db, cfg))
+}
+
+// SetupRoutesWithController registers the routes on an already built
+// controller, so that tests can swap its dependencies (e.g. the mailer).
+func SetupRoutesWithController(r *gin.Engine, ctrl *controllers.Controller) {
+	cfg := ctrl.Cfg
 
 	// Middleware
 	r.Use(middleware.CORSMiddleware())
@@ -27,28 +35,203 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		// Auth routes (public)
 		auth := api.Group("/auth")
 		{
-			auth.POST("/register", ctrl.Register)
-			auth.POST("/login", ctrl.Login)
+			auth.POST("/register", middleware.RateLimit(ctrl.IPLimiter, "register"), ctrl.Register)
+			auth.POST("/login", middleware.RateLimit(ctrl.IPLimiter, "login"), ctrl.Login)
+			auth.POST("/refresh", middleware.RateLimit(ctrl.IPLimiter, "refresh"), ctrl.RefreshSession)
+			auth.POST("/password/forgot", middleware.RateLimit(ctrl.IPLimiter, "password"), ctrl.ForgotPassword)
+			auth.POST("/password/reset", middleware.RateLimit(ctrl.IPLimiter, "password"), ctrl.ResetPassword)
+			auth.POST("/2fa/verify", middleware.RateLimit(ctrl.IPLimiter, "2fa"), ctrl.VerifyTwoFactorLogin)
+			auth.GET("/oidc/login", ctrl.OIDCLogin)
+			auth.POST("/oidc/callback", middleware.RateLimit(ctrl.IPLimiter, "oidc"), ctrl.OIDCCallback)
+		}
+
+		// Invitation lookup for the sign-up page (public)
+		api.GET("/invitations/:token", ctrl.GetInvitation)
+
+		// Code host webhooks, authenticated by the repository's webhook secret
+		api.POST("/repositories/:repositoryId/webhook", ctrl.ReceiveRepositoryWebhook)
+
+		// Release gates for CI pipelines, with keys granted the "gate" scope
+		gates := api.Group("/gates")
+		gates.Use(middleware.AuthMiddleware(cfg, ctrl.DB), middleware.RequireScope(models.ScopeGate))
+		{
+			gates.GET("/:workItemRef", ctrl.GetGate)
 		}
 
 		// Protected routes
 		protected := api.Group("/")
-		protected.Use(middleware.AuthMiddleware(cfg))
+		protected.Use(middleware.AuthMiddleware(cfg, ctrl.DB), middleware.ScopeMiddleware())
 		{
+			// Account management, not available to API tokens
+			account := protected.Group("/")
+			account.Use(middleware.RequireSession())
+			{
+				account.POST("/auth/logout", ctrl.Logout)
+				account.POST("/auth/logout-all", ctrl.LogoutAllSessions)
+				account.PUT("/auth/password", ctrl.ChangePassword)
+				account.POST("/auth/2fa/setup", ctrl.SetupTwoFactor)
+				account.POST("/auth/2fa/enable", ctrl.EnableTwoFactor)
+				account.POST("/auth/2fa/disable", ctrl.DisableTwoFactor)
+
+				// Personal access tokens
+				account.GET("/tokens", ctrl.GetAPITokens)
+				account.POST("/tokens", ctrl.CreateAPIToken)
+				account.DELETE("/tokens/:tokenId", ctrl.RevokeAPIToken)
+
+				// Invitations sent to the user's email
+				account.POST("/invitations/accept", ctrl.AcceptInvitation)
+
+				// Project API keys
+				account.GET("/projects/:id/api-keys", ctrl.GetProjectAPIKeys)
+				account.POST("/projects/:id/api-keys", ctrl.CreateProjectAPIKey)
+				account.DELETE("/projects/:id/api-keys/:keyId", ctrl.RevokeProjectAPIKey)
+			}
+
+			// Organizations
+			organizations := protected.Group("/organizations")
+			{
+				organizations.POST("/", ctrl.CreateOrganization)
+				organizations.GET("/", ctrl.GetUserOrganizations)
+				organizations.GET("/:id", ctrl.GetOrganization)
+				organizations.PATCH("/:id", ctrl.UpdateOrganization)
+				organizations.DELETE("/:id", ctrl.DeleteOrganization)
+				organizations.POST("/:id/members", ctrl.AddOrganizationMember)
+				organizations.PATCH("/:id/members/:userId", ctrl.UpdateOrganizationMember)
+				organizations.DELETE("/:id/members/:userId", ctrl.RemoveOrganizationMember)
+
+				// Baseline DoDs inherited by the organization's projects
+				organizations.GET("/:id/dods", ctrl.GetOrganizationDoDs)
+				organizations.POST("/:id/dods", ctrl.CreateOrganizationDoD)
+				organizations.PATCH("/:id/dods/:dodId", ctrl.UpdateOrganizationDoD)
+				organizations.DELETE("/:id/dods/:dodId", ctrl.DeleteOrganizationDoD)
+				organizations.POST("/:id/dods/:dodId/items", ctrl.AddOrganizationDoDItem)
+				organizations.PATCH("/:id/dods/:dodId/items/:itemId", ctrl.UpdateOrganizationDoDItem)
+				organizations.DELETE("/:id/dods/:dodId/items/:itemId", ctrl.DeleteOrganizationDoDItem)
+			}
+
+			// Teams
+			teams := protected.Group("/teams")
+			{
+				teams.POST("/", ctrl.CreateTeam)
+				teams.GET("/", ctrl.GetUserTeams)
+				teams.GET("/:id", ctrl.GetTeam)
+				teams.PATCH("/:id", ctrl.UpdateTeam)
+				teams.DELETE("/:id", ctrl.DeleteTeam)
+				teams.POST("/:id/members", ctrl.AddTeamMember)
+				teams.PATCH("/:id/members/:userId", ctrl.UpdateTeamMember)
+				teams.DELETE("/:id/members/:userId", ctrl.RemoveTeamMember)
+			}
+
 			// Projects
 			projects := protected.Group("/projects")
 			{
 				projects.POST("/", ctrl.CreateProject)
 				projects.GET("/", ctrl.GetUserProjects)
+				projects.GET("/:id", ctrl.GetProject)
+				projects.PATCH("/:id", ctrl.UpdateProject)
+				projects.DELETE("/:id", ctrl.DeleteProject)
+				projects.POST("/:id/clone", ctrl.CloneProject)
+				projects.GET("/:id/participants", ctrl.GetProjectParticipants)
 				projects.POST("/:id/participants", ctrl.AddProjectParticipant)
+				projects.PATCH("/:id/participants/:userId", ctrl.UpdateParticipantRole)
+				projects.DELETE("/:id/participants/:userId", ctrl.RemoveProjectParticipant)
+				projects.POST("/:id/leave", ctrl.LeaveProject)
+				projects.POST("/:id/transfer-ownership", ctrl.TransferProjectOwnership)
+				projects.PUT("/:id/organization", ctrl.MoveProjectToOrganization)
+				projects.GET("/:id/teams", ctrl.GetProjectTeams)
+				projects.POST("/:id/teams", ctrl.AddProjectTeam)
+				projects.PATCH("/:id/teams/:teamId", ctrl.UpdateProjectTeam)
+				projects.DELETE("/:id/teams/:teamId", ctrl.RemoveProjectTeam)
+				projects.GET("/:id/invitations", ctrl.GetProjectInvitations)
+				projects.DELETE("/:id/invitations/:invitationId", ctrl.RevokeProjectInvitation)
 				projects.GET("/:id/dods", ctrl.GetProjectDoDs)
+				projects.GET("/:id/effective-dod", ctrl.GetEffectiveDoD)
+				projects.POST("/:id/applicability", ctrl.EvaluateApplicability)
+				projects.PUT("/:id/inherited-items/:itemId/not-applicable", ctrl.MarkItemNotApplicable)
+				projects.DELETE("/:id/inherited-items/:itemId/not-applicable", ctrl.ClearItemNotApplicable)
+
+				// Roles and permissions
+				projects.GET("/:id/permissions", ctrl.GetProjectPermissions)
+				projects.GET("/:id/roles", ctrl.GetProjectRoles)
+				projects.POST("/:id/roles", authz.RequirePermission(ctrl.DB, authz.RoleManage), ctrl.CreateProjectRole)
+				projects.PATCH("/:id/roles/:roleId", authz.RequirePermission(ctrl.DB, authz.RoleManage), ctrl.UpdateProjectRole)
+				projects.DELETE("/:id/roles/:roleId", authz.RequirePermission(ctrl.DB, authz.RoleManage), ctrl.DeleteProjectRole)
+
+				// Work items
+				projects.POST("/:id/work-items", ctrl.CreateWorkItem)
+				projects.GET("/:id/work-items", ctrl.GetProjectWorkItems)
+				projects.GET("/:id/work-items/:workItemId", ctrl.GetWorkItem)
+				projects.PUT("/:id/work-items/:workItemId/dod", ctrl.AttachWorkItemDoD)
+				projects.PUT("/:id/work-items/:workItemId/state", ctrl.UpdateWorkItemState)
+				projects.PUT("/:id/work-items/:workItemId/checks/:itemId", ctrl.CheckWorkItemDoDItem)
+				projects.POST("/:id/work-items/:workItemId/checks/:itemId/approve", ctrl.ApproveWorkItemItem)
+				projects.POST("/:id/work-items/:workItemId/checks/:itemId/reject", ctrl.RejectWorkItemItem)
+				projects.POST("/:id/work-items/:workItemId/checks/:itemId/comments", ctrl.CommentWorkItemItem)
+				projects.GET("/:id/work-items/:workItemId/checks/:itemId/decisions", ctrl.GetWorkItemItemDecisions)
+				projects.POST("/:id/work-items/:workItemId/checks/:itemId/waivers", ctrl.RequestWaiver)
+				projects.POST("/:id/work-items/:workItemId/evidence", ctrl.UploadEvidence)
+				projects.GET("/:id/work-items/:workItemId/evidence", ctrl.GetWorkItemEvidence)
+				projects.GET("/:id/work-items/:workItemId/evidence/:evidenceId", ctrl.DownloadEvidence)
+
+				// Waivers
+				projects.GET("/:id/waivers", ctrl.GetProjectWaivers)
+				projects.POST("/:id/waivers/:waiverId/approve", ctrl.ApproveWaiver)
+				projects.POST("/:id/waivers/:waiverId/reject", ctrl.RejectWaiver)
+				projects.DELETE("/:id/waivers/:waiverId", ctrl.RevokeWaiver)
+				projects.GET("/:id/alerts", ctrl.GetProjectAlerts)
+
+				// Code hosts
+				projects.GET("/:id/repositories", ctrl.GetProjectRepositories)
+				projects.POST("/:id/repositories", ctrl.CreateProjectRepository)
+				projects.DELETE("/:id/repositories/:repositoryId", ctrl.DeleteProjectRepository)
+				projects.GET("/:id/pull-requests", ctrl.GetProjectPullRequests)
+
+				// Outgoing webhooks
+				projects.GET("/:id/webhooks", ctrl.GetProjectWebhooks)
+				projects.POST("/:id/webhooks", ctrl.CreateProjectWebhook)
+				projects.PATCH("/:id/webhooks/:webhookId", ctrl.UpdateProjectWebhook)
+				projects.DELETE("/:id/webhooks/:webhookId", ctrl.DeleteProjectWebhook)
+				projects.POST("/:id/webhooks/:webhookId/ping", ctrl.PingProjectWebhook)
+				projects.GET("/:id/webhooks/:webhookId/deliveries", ctrl.GetWebhookDeliveries)
+				projects.POST("/:id/webhooks/:webhookId/deliveries/:deliveryId/replay", ctrl.ReplayWebhookDelivery)
+
+				// Chat notifications
+				projects.GET("/:id/notification-channels", ctrl.GetProjectNotificationChannels)
+				projects.POST("/:id/notification-channels", ctrl.CreateProjectNotificationChannel)
+				projects.PATCH("/:id/notification-channels/:channelId", ctrl.UpdateProjectNotificationChannel)
+				projects.DELETE("/:id/notification-channels/:channelId", ctrl.DeleteProjectNotificationChannel)
+				projects.POST("/:id/notification-channels/:channelId/test", ctrl.TestProjectNotificationChannel)
 			}
 
 			// DoDs
 			dods := protected.Group("/dods")
 			{
 				dods.POST("/", ctrl.CreateDoD)
+				dods.POST("/from-template", ctrl.CreateDoDFromTemplate)
+				dods.GET("/:id", ctrl.GetDoD)
+				dods.PATCH("/:id", ctrl.UpdateDoD)
+				dods.DELETE("/:id", ctrl.DeleteDoD)
 				dods.POST("/:id/items", ctrl.AddDoDItem)
+				dods.PATCH("/:id/items/:itemId", ctrl.UpdateDoDItem)
+				dods.DELETE("/:id/items/:itemId", ctrl.DeleteDoDItem)
+				dods.POST("/:id/publish", ctrl.PublishDoD)
+				dods.GET("/:id/revisions", ctrl.GetDoDRevisions)
+				dods.GET("/:id/revisions/:number", ctrl.GetDoDRevision)
+				dods.GET("/:id/diff", ctrl.DiffDoDRevisions)
+				dods.GET("/:id/rules", ctrl.GetDoDRules)
+				dods.POST("/:id/rules", ctrl.CreateDoDRule)
+				dods.PUT("/:id/rules/:ruleId", ctrl.UpdateDoDRule)
+				dods.DELETE("/:id/rules/:ruleId", ctrl.DeleteDoDRule)
+			}
+
+			// DoD templates
+			templates := protected.Group("/dod-templates")
+			{
+				templates.GET("/", ctrl.GetDoDTemplates)
+				templates.POST("/", ctrl.CreateDoDTemplate)
+				templates.GET("/:id", ctrl.GetDoDTemplate)
+				templates.PATCH("/:id", ctrl.UpdateDoDTemplate)
+				templates.DELETE("/:id", ctrl.DeleteDoDTemplate)
 			}
 		}
 	}
@@ -0,0 +1,105 @@
+// Package safehttp builds the HTTP clients that call URLs chosen by users,
+// such as webhook receivers. They only connect to public addresses, checked
+// once the host name is resolved, and never follow redirects, so that these
+// URLs cannot reach the network the server runs in.
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+var ErrNonPublicAddress = errors.New("destination is not a public address")
+
+// Ranges that are neither private nor loopback by the net package's
+// definitions, yet not reachable on the internet either.
+var reserved = []*net.IPNet{
+	mustCIDR("0.0.0.0/8"),       // "this" network
+	mustCIDR("100.64.0.0/10"),   // carrier-grade NAT
+	mustCIDR("192.0.0.0/24"),    // protocol assignments
+	mustCIDR("198.18.0.0/15"),   // benchmarking
+	mustCIDR("240.0.0.0/4"),     // reserved, and broadcast
+	mustCIDR("64:ff9b::/96"),    // NAT64, which maps any IPv4 address
+	mustCIDR("64:ff9b:1::/48"),  // local-use NAT64
+	mustCIDR("2001:db8::/32"),   // documentation
+	mustCIDR("2002::/16"),       // 6to4, which maps any IPv4 address
+	mustCIDR("fec0::/10"),       // deprecated site-local
+	mustCIDR("100::/64"),        // discard-only
+	mustCIDR("::ffff:0:0:0/96"), // IPv4-translated
+}
+
+func mustCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// Public tells whether the address is routable on the internet: neither
+// loopback, private, link-local (e.g. cloud metadata at 169.254.169.254),
+// multicast nor reserved.
+func Public(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range reserved {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewClient returns a client that refuses to connect to addresses that are
+// not public.
+func NewClient(timeout time.Duration) *http.Client {
+	return newClient(timeout, func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || !Public(ip) {
+			return ErrNonPublicAddress
+		}
+		return nil
+	})
+}
+
+// NewTrustedClient returns a client that connects to any address, for hosts
+// the operator vouches for: the configured code hosts, or stand-ins in tests.
+// It does not follow redirects either.
+func NewTrustedClient(timeout time.Duration) *http.Client {
+	return newClient(timeout, nil)
+}
+
+func newClient(timeout time.Duration, control func(network, address string, c syscall.RawConn) error) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second, Control: control}
+	transport := &http.Transport{
+		// No proxy: it would be the address checked, not the destination
+		Proxy: nil,
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, address)
+		},
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// Redirects could lead anywhere: the response is taken as is
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,158 @@
+package scm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultGitHubAPI is the API of github.com; GitHub Enterprise serves it
+// under /api/v3.
+const DefaultGitHubAPI = "https://api.github.com"
+
+type githubPullRequest struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	State   string `json:"state"`
+	Merged  bool   `json:"merged"`
+	HTMLURL string `json:"html_url"`
+	Head    struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+}
+
+type githubPayload struct {
+	Action      string             `json:"action"`
+	PullRequest *githubPullRequest `json:"pull_request"`
+	Review      struct {
+		State string `json:"state"`
+		User  struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"review"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+
+	// Status events
+	SHA     string `json:"sha"`
+	State   string `json:"state"`
+	Context string `json:"context"`
+}
+
+func parseGitHub(kind string, body []byte) (*Event, error) {
+	var payload githubPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, ErrMalformedPayload
+	}
+	event := &Event{Repository: payload.Repository.FullName}
+
+	switch kind {
+	case "pull_request":
+		if payload.PullRequest == nil {
+			return nil, ErrMalformedPayload
+		}
+		event.Kind = EventPullRequest
+		event.setGitHubPullRequest(payload.PullRequest)
+	case "pull_request_review":
+		if payload.PullRequest == nil {
+			return nil, ErrMalformedPayload
+		}
+		event.setGitHubPullRequest(payload.PullRequest)
+		// Comments neither give nor take an approval
+		state := strings.ToLower(payload.Review.State)
+		if payload.Action == "dismissed" {
+			state = "dismissed"
+		}
+		if state != "approved" && state != "changes_requested" && state != "dismissed" {
+			return &Event{Ignored: true}, nil
+		}
+		event.Kind = EventReview
+		event.Reviewer = payload.Review.User.Login
+		event.Approved = state == "approved"
+	case "status":
+		event.Kind = EventStatus
+		event.SHA = payload.SHA
+		event.CIName = payload.Context
+		event.CIState = ciState(payload.State)
+	default:
+		return &Event{Ignored: true}, nil
+	}
+	return event, nil
+}
+
+func (e *Event) setGitHubPullRequest(pr *githubPullRequest) {
+	e.Number = pr.Number
+	e.Title = pr.Title
+	e.Branch = pr.Head.Ref
+	e.HeadSHA = pr.Head.SHA
+	e.URL = pr.HTMLURL
+	e.State = PullRequestOpen
+	switch {
+	case pr.Merged:
+		e.State = PullRequestMerged
+	case pr.State == "closed":
+		e.State = PullRequestClosed
+	}
+}
+
+// ciState maps the CI states of both providers onto pending, success and
+// failure.
+func ciState(state string) string {
+	switch strings.ToLower(state) {
+	case "success":
+		return StateSuccess
+	case "failure", "failed", "error", "canceled":
+		return StateFailure
+	}
+	return StatePending
+}
+
+// GitHubClient posts commit statuses through the GitHub REST API.
+type GitHubClient struct {
+	APIURL     string
+	Token      string
+	HTTPClient *http.Client
+}
+
+func (g *GitHubClient) SetCommitStatus(ctx context.Context, repository, sha string, status CommitStatus) error {
+	body, err := json.Marshal(map[string]string{
+		"state":       status.State,
+		"context":     status.Context,
+		"description": status.description(),
+		"target_url":  status.TargetURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/statuses/%s", g.APIURL, repository, sha)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.Token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	return send(ctx, g.HTTPClient, req)
+}
+
+func send(ctx context.Context, client *http.Client, req *http.Request) error {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s", req.Method, req.URL.Path, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package scm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultGitLabAPI is the API of gitlab.com; self-managed instances serve it
+// under /api/v4.
+const DefaultGitLabAPI = "https://gitlab.com/api/v4"
+
+type gitlabPayload struct {
+	ObjectKind string `json:"object_kind"`
+	User       struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		IID          int    `json:"iid"`
+		Title        string `json:"title"`
+		State        string `json:"state"`
+		Action       string `json:"action"`
+		URL          string `json:"url"`
+		SourceBranch string `json:"source_branch"`
+		LastCommit   struct {
+			ID string `json:"id"`
+		} `json:"last_commit"`
+
+		// Pipeline events
+		SHA    string `json:"sha"`
+		Status string `json:"status"`
+		Name   string `json:"name"`
+	} `json:"object_attributes"`
+}
+
+// parseGitLab reads merge request events, approvals included, and pipeline
+// events, GitLab's commit statuses.
+func parseGitLab(kind string, body []byte) (*Event, error) {
+	var payload gitlabPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, ErrMalformedPayload
+	}
+	attributes := payload.ObjectAttributes
+	event := &Event{Repository: payload.Project.PathWithNamespace}
+
+	switch kind {
+	case "Merge Request Hook":
+		event.Kind = EventPullRequest
+		event.Number = attributes.IID
+		event.Title = attributes.Title
+		event.Branch = attributes.SourceBranch
+		event.HeadSHA = attributes.LastCommit.ID
+		event.URL = attributes.URL
+		event.State = PullRequestOpen
+		switch attributes.State {
+		case "merged":
+			event.State = PullRequestMerged
+		case "closed":
+			event.State = PullRequestClosed
+		}
+		switch attributes.Action {
+		case "approved", "unapproved":
+			event.Kind = EventReview
+			event.Reviewer = payload.User.Username
+			event.Approved = attributes.Action == "approved"
+		}
+	case "Pipeline Hook":
+		event.Kind = EventStatus
+		event.SHA = attributes.SHA
+		event.CIName = attributes.Name
+		event.CIState = ciState(attributes.Status)
+	default:
+		return &Event{Ignored: true}, nil
+	}
+	return event, nil
+}
+
+// GitLabClient posts commit statuses through the GitLab REST API.
+type GitLabClient struct {
+	APIURL     string
+	Token      string
+	HTTPClient *http.Client
+}
+
+func (g *GitLabClient) SetCommitStatus(ctx context.Context, repository, sha string, status CommitStatus) error {
+	// GitLab calls failures "failed"
+	state := status.State
+	if state == StateFailure {
+		state = "failed"
+	}
+
+	form := url.Values{}
+	form.Set("state", state)
+	form.Set("name", status.Context)
+	form.Set("description", status.description())
+	form.Set("target_url", status.TargetURL)
+
+	endpoint := fmt.Sprintf("%s/projects/%s/statuses/%s", g.APIURL, url.PathEscape(repository), sha)
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.Token)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return send(ctx, g.HTTPClient, req)
+}
@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"dod-backend/authz"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestAPIToken(t *testing.T, router *gin.Engine, path, token string, req models.CreateAPITokenRequest) (string, map[string]interface{}) {
+	w := performRequest(router, "POST", path, token, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	response := decodeResponse(w)
+
+	record, ok := response["api_token"].(map[string]interface{})
+	if !ok {
+		record = response["api_key"].(map[string]interface{})
+	}
+	return response["token"].(string), record
+}
+
+func TestPersonalAccessToken(t *testing.T) {
+	router := setupTestRouter()
+	user := registerTestUser(t, router, "patuser")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, user)
+
+	token, record := createTestAPIToken(t, router, "/api/v1/tokens", user.Token, models.CreateAPITokenRequest{
+		Name:   "CI",
+		Scopes: []string{models.ScopeRead},
+	})
+	assert.True(t, strings.HasPrefix(token, "dod_pat_"))
+	assert.True(t, strings.HasPrefix(token, record["prefix"].(string)))
+	assert.Nil(t, record["last_used_at"])
+
+	// Acts as the user, within its scopes
+	w := performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d", projectID), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "PATCH", fmt.Sprintf("/api/v1/projects/%d", projectID), token, models.UpdateProjectRequest{})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Tokens cannot manage the account
+	w = performRequest(router, "GET", "/api/v1/tokens", token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "GET", "/api/v1/tokens", user.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	tokens := decodeResponse(w)["tokens"].([]interface{})
+	assert.Len(t, tokens, 1)
+	listed := tokens[0].(map[string]interface{})
+	assert.NotNil(t, listed["last_used_at"])
+	assert.Equal(t, []interface{}{"read"}, listed["scopes"])
+	assert.Nil(t, listed["token_hash"])
+
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/tokens/%d", uint(record["id"].(float64))), user.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d", projectID), token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestExpiredAPIToken(t *testing.T) {
+	router := setupTestRouter()
+	user := registerTestUser(t, router, "patexpiry")
+
+	past := time.Now().Add(-time.Hour)
+	w := performRequest(router, "POST", "/api/v1/tokens", user.Token, models.CreateAPITokenRequest{
+		Name:      "Stale",
+		Scopes:    []string{models.ScopeRead},
+		ExpiresAt: &past,
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/tokens", user.Token, models.CreateAPITokenRequest{
+		Name:   "Unknown scope",
+		Scopes: []string{"admin"},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, "GET", "/api/v1/projects/", "dod_pat_doesnotexist", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestProjectAPIKey(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "keyowner")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+	otherProjectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	keysPath := fmt.Sprintf("/api/v1/projects/%d/api-keys", projectID)
+	key, record := createTestAPIToken(t, router, keysPath, owner.Token, models.CreateAPITokenRequest{
+		Name:   "Pipeline",
+		Scopes: []string{models.ScopeRead, models.ScopeWrite},
+	})
+	assert.True(t, strings.HasPrefix(key, "dod_key_"))
+
+	// The key can update work items of its project
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), key, models.CreateWorkItemRequest{
+		Title: "Built by CI",
+		DoDID: &dodID,
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	workItemID := uint(decodeResponse(w)["work_item"].(map[string]interface{})["id"].(float64))
+
+	checked := true
+	w = performRequest(router, "PUT", fmt.Sprintf("/api/v1/projects/%d/work-items/%d/checks/%d", projectID, workItemID, requiredItemID), key, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusOK, w.Code)
+	completion := decodeResponse(w)["completion"].(map[string]interface{})
+	assert.True(t, completion["checked"].(bool))
+
+	// ...but nothing outside of it, nor owner actions
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d", otherProjectID), key, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/projects/%d", projectID), key, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/projects/", key, models.CreateProjectRequest{Name: "Sneaky"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "GET", "/api/v1/projects/", key, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, decodeResponse(w)["projects"].([]interface{}), 1)
+
+	// Only the owner manages keys
+	w = performRequest(router, "GET", keysPath, owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, decodeResponse(w)["api_keys"].([]interface{}), 1)
+
+	w = performRequest(router, "DELETE", fmt.Sprintf("%s/%d", keysPath, uint(record["id"].(float64))), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d", projectID), key, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/participants", projectID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, decodeResponse(w)["participants"].([]interface{}), 1)
+}
+
+func TestReadOnlyProjectAPIKey(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "keyreader")
+	viewer := registerTestUser(t, router, "keyviewer")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, viewer, "editor")
+
+	keysPath := fmt.Sprintf("/api/v1/projects/%d/api-keys", projectID)
+	w := performRequest(router, "POST", keysPath, viewer.Token, models.CreateAPITokenRequest{Name: "Nope", Scopes: []string{models.ScopeRead}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	key, _ := createTestAPIToken(t, router, keysPath, owner.Token, models.CreateAPITokenRequest{
+		Name:   "Dashboard",
+		Scopes: []string{models.ScopeRead},
+	})
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), key, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), key, models.CreateWorkItemRequest{Title: "Denied"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAPIKeyManagersCannotEscalate(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "keyescowner")
+	keeper := registerTestUser(t, router, "keyesckeeper")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/roles", projectID), owner.Token, models.CreateProjectRoleRequest{
+		Name:        "keykeeper",
+		Permissions: []string{string(authz.APIKeyManage)},
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	addTestParticipant(t, router, owner, projectID, keeper, "keykeeper")
+
+	keysPath := fmt.Sprintf("/api/v1/projects/%d/api-keys", projectID)
+	w = performRequest(router, "POST", keysPath, keeper.Token, models.CreateAPITokenRequest{Name: "Editor", Scopes: []string{models.ScopeRead, models.ScopeWrite}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "POST", keysPath, keeper.Token, models.CreateAPITokenRequest{Name: "Dashboard", Scopes: []string{models.ScopeRead}})
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"dod-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplicabilityRulesSelectDoDsAndItems(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "rulesowner")
+	projectID, storyDoDID, _, optionalItemID := createTestProjectWithDoD(t, router, owner)
+
+	w := performRequest(router, "POST", "/api/v1/dods/", owner.Token, models.CreateDoDRequest{Title: "Bug DoD", ProjectID: projectID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	bugDoDID := uint(decodeResponse(w)["dod"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/dods/%d/items", bugDoDID), owner.Token, models.CreateDoDItemRequest{Title: "Regression Test Added", IsRequired: true, Order: 1})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// The bug DoD governs bugs only, documentation only matters for UI work
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/dods/%d/rules", bugDoDID), owner.Token, models.ApplicabilityRuleRequest{Name: "Bugs", Types: []string{"bug"}})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	bugRuleID := decodeResponse(w)["rule"].(map[string]interface{})["id"]
+
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/dods/%d/rules", storyDoDID), owner.Token, models.ApplicabilityRuleRequest{ItemID: &optionalItemID, Labels: []string{"ui"}})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	uiRuleID := decodeResponse(w)["rule"].(map[string]interface{})["id"]
+
+	evaluatePath := fmt.Sprintf("/api/v1/projects/%d/applicability", projectID)
+	w = performRequest(router, "POST", evaluatePath, owner.Token, models.EvaluateApplicabilityRequest{Type: "bug"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	response := decodeResponse(w)
+	dods := response["dods"].([]interface{})
+	assert.Len(t, dods, 2)
+	assert.Equal(t, true, dods[1].(map[string]interface{})["applies"])
+	assert.Equal(t, bugRuleID, dods[1].(map[string]interface{})["rule_id"])
+	assert.Contains(t, dods[1].(map[string]interface{})["reason"], `rule "Bugs"`)
+	assert.Len(t, response["items"], 2)
+	excluded := response["excluded"].([]interface{})
+	assert.Len(t, excluded, 1)
+	assert.Equal(t, "no rule matches", excluded[0].(map[string]interface{})["reason"])
+
+	w = performRequest(router, "POST", evaluatePath, owner.Token, models.EvaluateApplicabilityRequest{Type: "story", Labels: []string{"UI"}})
+	response = decodeResponse(w)
+	assert.Equal(t, false, response["dods"].([]interface{})[1].(map[string]interface{})["applies"])
+	items := response["items"].([]interface{})
+	assert.Len(t, items, 2)
+	assert.Equal(t, uiRuleID, items[1].(map[string]interface{})["rule_id"])
+
+	// New work items fall under the DoD their rules select...
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), owner.Token, models.CreateWorkItemRequest{Title: "Crash on login", Type: "bug"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	bug := decodeResponse(w)["work_item"].(map[string]interface{})
+	assert.Equal(t, float64(bugDoDID), bug["dod_id"])
+
+	// ...and are judged against the items that apply to them
+	story := createTestWorkItem(t, router, owner, projectID, storyDoDID)
+	assert.Len(t, workItemStatus(story)["criteria"], 1)
+
+	// Evaluating a work item explains its own DoD only
+	storyID := uint(story["id"].(float64))
+	w = performRequest(router, "POST", evaluatePath, owner.Token, models.EvaluateApplicabilityRequest{WorkItemID: &storyID})
+	response = decodeResponse(w)
+	assert.Equal(t, "story", response["attributes"].(map[string]interface{})["type"])
+	dods = response["dods"].([]interface{})
+	assert.Len(t, dods, 1)
+	assert.Equal(t, float64(storyDoDID), dods[0].(map[string]interface{})["dod_id"])
+	assert.Len(t, response["items"], 1)
+	excluded = response["excluded"].([]interface{})
+	assert.Len(t, excluded, 1)
+	assert.Equal(t, float64(optionalItemID), excluded[0].(map[string]interface{})["item"].(map[string]interface{})["id"])
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/dods/%d/rules", storyDoDID), owner.Token, nil)
+	assert.Len(t, decodeResponse(w)["rules"], 1)
+
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/dods/%d/rules/%v", storyDoDID, uiRuleID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, storyID), owner.Token, nil)
+	assert.Len(t, workItemStatus(decodeResponse(w)["work_item"].(map[string]interface{}))["criteria"], 2)
+}
+
+func TestPinnedWorkItemsKeepPublishedRules(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "pinnedrulesowner")
+	projectID, dodID, _, optionalItemID := createTestProjectWithDoD(t, router, owner)
+
+	publishPath := fmt.Sprintf("/api/v1/dods/%d/publish", dodID)
+	w := performRequest(router, "POST", publishPath, owner.Token, models.PublishDoDRequest{Changelog: "First version"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	pinned := startTestWorkItem(t, router, owner, projectID, createTestWorkItem(t, router, owner, projectID, dodID))
+	pinnedPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, uint(pinned["id"].(float64)))
+	assert.Len(t, workItemStatus(pinned)["criteria"], 2)
+
+	// A new rule is a change of the draft, like any other
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/dods/%d/rules", dodID), owner.Token, models.ApplicabilityRuleRequest{ItemID: &optionalItemID, Labels: []string{"ui"}})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/dods/%d", dodID), owner.Token, nil)
+	assert.Equal(t, true, decodeResponse(w)["unpublished_changes"])
+
+	w = performRequest(router, "GET", pinnedPath, owner.Token, nil)
+	assert.Len(t, workItemStatus(decodeResponse(w)["work_item"].(map[string]interface{}))["criteria"], 2)
+
+	w = performRequest(router, "POST", publishPath, owner.Token, models.PublishDoDRequest{Changelog: "Documentation for UI work only"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	current := startTestWorkItem(t, router, owner, projectID, createTestWorkItem(t, router, owner, projectID, dodID))
+	assert.Len(t, workItemStatus(current)["criteria"], 1)
+
+	w = performRequest(router, "GET", pinnedPath, owner.Token, nil)
+	assert.Len(t, workItemStatus(decodeResponse(w)["work_item"].(map[string]interface{}))["criteria"], 2)
+
+	// Evaluating the pinned work item explains the revision it is pinned to
+	pinnedID := uint(pinned["id"].(float64))
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/applicability", projectID), owner.Token, models.EvaluateApplicabilityRequest{WorkItemID: &pinnedID})
+	response := decodeResponse(w)
+	assert.Len(t, response["items"], 2)
+	assert.Empty(t, response["excluded"])
+	assert.Contains(t, response["dods"].([]interface{})[0].(map[string]interface{})["reason"], "revision 1")
+
+	// Deleting the rule does not touch published revisions either
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/dods/%d/rules", dodID), owner.Token, nil)
+	ruleID := decodeResponse(w)["rules"].([]interface{})[0].(map[string]interface{})["id"]
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/dods/%d/rules/%v", dodID, ruleID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, uint(current["id"].(float64))), owner.Token, nil)
+	assert.Len(t, workItemStatus(decodeResponse(w)["work_item"].(map[string]interface{}))["criteria"], 1)
+}
+
+func TestApplicabilityRuleValidation(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "rulesvalidator")
+	viewer := registerTestUser(t, router, "rulesviewer")
+	projectID, dodID, _, _ := createTestProjectWithDoD(t, router, owner)
+	_, otherDoDID, otherItemID, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, viewer, "viewer")
+
+	rulesPath := fmt.Sprintf("/api/v1/dods/%d/rules", dodID)
+	w := performRequest(router, "POST", rulesPath, owner.Token, models.ApplicabilityRuleRequest{Name: "Anything"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, "POST", rulesPath, owner.Token, models.ApplicabilityRuleRequest{ItemID: &otherItemID, Types: []string{"bug"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	min, max := 8, 3
+	w = performRequest(router, "POST", rulesPath, owner.Token, models.ApplicabilityRuleRequest{MinSize: &min, MaxSize: &max})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, "POST", rulesPath, owner.Token, models.ApplicabilityRuleRequest{Types: []string{"epic"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Commas separate the stored values
+	w = performRequest(router, "POST", rulesPath, owner.Token, models.ApplicabilityRuleRequest{Labels: []string{"ui,backend"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), owner.Token, models.CreateWorkItemRequest{Title: "Story", Labels: []string{"a,b"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, "POST", rulesPath, viewer.Token, models.ApplicabilityRuleRequest{Types: []string{"bug"}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Large items only, replaced later by small ones
+	w = performRequest(router, "POST", rulesPath, owner.Token, models.ApplicabilityRuleRequest{MinSize: &min})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	ruleID := uint(decodeResponse(w)["rule"].(map[string]interface{})["id"].(float64))
+
+	size := 5
+	evaluatePath := fmt.Sprintf("/api/v1/projects/%d/applicability", projectID)
+	w = performRequest(router, "POST", evaluatePath, viewer.Token, models.EvaluateApplicabilityRequest{Size: &size})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, false, decodeResponse(w)["dods"].([]interface{})[0].(map[string]interface{})["applies"])
+
+	w = performRequest(router, "PUT", fmt.Sprintf("%s/%d", rulesPath, ruleID), owner.Token, models.ApplicabilityRuleRequest{MaxSize: &size})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "POST", evaluatePath, viewer.Token, models.EvaluateApplicabilityRequest{Size: &size})
+	assert.Equal(t, true, decodeResponse(w)["dods"].([]interface{})[0].(map[string]interface{})["applies"])
+
+	w = performRequest(router, "PUT", fmt.Sprintf("/api/v1/dods/%d/rules/%d", otherDoDID, ruleID), owner.Token, models.ApplicabilityRuleRequest{MaxSize: &size})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"dod-backend/authz"
+	"dod-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissionMatrix(t *testing.T) {
+	owner := authz.NewGrant(1, 1, authz.RoleOwner, authz.Matrix[authz.RoleOwner])
+	editor := authz.NewGrant(1, 2, authz.RoleEditor, authz.Matrix[authz.RoleEditor])
+	viewer := authz.NewGrant(1, 3, authz.RoleViewer, authz.Matrix[authz.RoleViewer])
+	stranger := authz.NewGrant(1, 4, "", nil)
+
+	for _, p := range authz.Permissions {
+		assert.True(t, owner.Can(p), p)
+	}
+
+	assert.True(t, editor.Can(authz.DoDCreate))
+	assert.True(t, editor.Can(authz.ItemCheck))
+	assert.False(t, editor.Can(authz.ParticipantManage))
+	assert.False(t, editor.Can(authz.ProjectDelete))
+
+	assert.True(t, viewer.Can(authz.ProjectView))
+	assert.False(t, viewer.Can(authz.ItemCheck))
+
+	assert.False(t, stranger.Participant())
+	assert.False(t, stranger.Can(authz.ProjectView))
+
+	// Custom roles always view, and never hold owner-only permissions
+	custom := authz.NewGrant(1, 5, "qa", []authz.Permission{authz.ItemCheck})
+	assert.True(t, custom.Can(authz.ProjectView))
+	assert.True(t, custom.Can(authz.ItemCheck))
+	assert.False(t, authz.Grantable(authz.ProjectDelete))
+	assert.False(t, authz.Grantable("dod:launch"))
+	assert.True(t, authz.Grantable(authz.ParticipantManage))
+
+	assert.True(t, editor.Covers(custom.Permissions()))
+	assert.False(t, viewer.Covers(custom.Permissions()))
+}
+
+func TestCustomProjectRole(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "roleowner")
+	qa := registerTestUser(t, router, "roleqa")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+
+	rolesPath := fmt.Sprintf("/api/v1/projects/%d/roles", projectID)
+	w := performRequest(router, "POST", rolesPath, owner.Token, models.CreateProjectRoleRequest{
+		Name:        "qa",
+		Permissions: []string{string(authz.ItemCheck)},
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	roleID := uint(decodeResponse(w)["role"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "POST", rolesPath, owner.Token, models.CreateProjectRoleRequest{Name: "editor", Permissions: []string{string(authz.ItemCheck)}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, "POST", rolesPath, owner.Token, models.CreateProjectRoleRequest{Name: "admin", Permissions: []string{string(authz.ProjectDelete)}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	addTestParticipant(t, router, owner, projectID, qa, "qa")
+
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), owner.Token, models.CreateWorkItemRequest{Title: "Release notes", DoDID: &dodID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	workItemID := uint(decodeResponse(w)["work_item"].(map[string]interface{})["id"].(float64))
+
+	// The QA role ticks items, and nothing more
+	checked := true
+	w = performRequest(router, "PUT", fmt.Sprintf("/api/v1/projects/%d/work-items/%d/checks/%d", projectID, workItemID, requiredItemID), qa.Token, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/dods/", qa.Token, models.CreateDoDRequest{Title: "Not allowed", ProjectID: projectID})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/permissions", projectID), qa.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response := decodeResponse(w)
+	assert.Equal(t, "qa", response["role"])
+	assert.ElementsMatch(t, []interface{}{"item:check", "project:view"}, response["permissions"])
+
+	// Roles in use cannot be deleted, and only role managers touch them
+	rolePath := fmt.Sprintf("%s/%d", rolesPath, roleID)
+	w = performRequest(router, "DELETE", rolePath, qa.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "DELETE", rolePath, owner.Token, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Widening the role applies straight away
+	w = performRequest(router, "PATCH", rolePath, owner.Token, models.UpdateProjectRoleRequest{
+		Permissions: []string{string(authz.ItemCheck), string(authz.DoDCreate)},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/dods/", qa.Token, models.CreateDoDRequest{Title: "Now allowed", ProjectID: projectID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestDelegatedParticipantManagement(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "delegowner")
+	lead := registerTestUser(t, router, "delegLead")
+	newcomer := registerTestUser(t, router, "delegnew")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/roles", projectID), owner.Token, models.CreateProjectRoleRequest{
+		Name:        "lead",
+		Permissions: []string{string(authz.ParticipantManage), string(authz.ItemCheck)},
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	addTestParticipant(t, router, owner, projectID, lead, "lead")
+
+	participantsPath := fmt.Sprintf("/api/v1/projects/%d/participants", projectID)
+
+	// A lead may add people with a subset of their permissions...
+	w = performRequest(router, "POST", participantsPath, lead.Token, models.AddParticipantRequest{Email: newcomer.Email, Role: "viewer"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// ...but not hand out more than they hold
+	w = performRequest(router, "PATCH", fmt.Sprintf("%s/%d", participantsPath, newcomer.ID), lead.Token, models.UpdateParticipantRoleRequest{Role: "editor"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "PATCH", fmt.Sprintf("%s/%d", participantsPath, newcomer.ID), lead.Token, models.UpdateParticipantRoleRequest{Role: "unknown"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, "PATCH", fmt.Sprintf("%s/%d", participantsPath, newcomer.ID), owner.Token, models.UpdateParticipantRoleRequest{Role: "owner"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// ...nor demote or remove those granted more than them
+	editor := registerTestUser(t, router, "delegeditor")
+	addTestParticipant(t, router, owner, projectID, editor, "editor")
+	w = performRequest(router, "PATCH", fmt.Sprintf("%s/%d", participantsPath, editor.ID), lead.Token, models.UpdateParticipantRoleRequest{Role: "viewer"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequest(router, "DELETE", fmt.Sprintf("%s/%d", participantsPath, editor.ID), lead.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "DELETE", fmt.Sprintf("%s/%d", participantsPath, newcomer.ID), lead.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Deleting the project stays with the owner
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/projects/%d", projectID), lead.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
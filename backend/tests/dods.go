@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"dod-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDoD(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "getdodowner")
+	outsider := registerTestUser(t, router, "getdodoutsider")
+	_, dodID, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	w := performRequest(router, "GET", fmt.Sprintf("/api/v1/dods/%d", dodID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	dod := decodeResponse(w)["dod"].(map[string]interface{})
+	assert.Len(t, dod["items"], 2)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/dods/%d", dodID), outsider.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestUpdateDoD(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "upddodowner")
+	editor := registerTestUser(t, router, "upddodeditor")
+	viewer := registerTestUser(t, router, "upddodviewer")
+	projectID, dodID, _, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, editor, "editor")
+	addTestParticipant(t, router, owner, projectID, viewer, "viewer")
+
+	inactive := false
+	w := performRequest(router, "PATCH", fmt.Sprintf("/api/v1/dods/%d", dodID), viewer.Token, models.UpdateDoDRequest{IsActive: &inactive})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "PATCH", fmt.Sprintf("/api/v1/dods/%d", dodID), editor.Token, models.UpdateDoDRequest{IsActive: &inactive})
+	assert.Equal(t, http.StatusOK, w.Code)
+	dod := decodeResponse(w)["dod"].(map[string]interface{})
+	assert.Equal(t, false, dod["is_active"])
+}
+
+func TestDeleteDoD(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "deldodowner")
+	projectID, dodID, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), owner.Token, models.CreateWorkItemRequest{Title: "Story", DoDID: &dodID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	workItemID := uint(decodeResponse(w)["work_item"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/dods/%d", dodID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/dods/%d", dodID), owner.Token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Work items judged against the deleted DoD are detached, not removed
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, workItemID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, decodeResponse(w)["work_item"].(map[string]interface{})["dod_id"])
+}
+
+func TestUpdateDoDItem(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "upditemowner")
+	viewer := registerTestUser(t, router, "upditemviewer")
+	projectID, dodID, _, optionalItemID := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, viewer, "viewer")
+
+	required := true
+	order := 10
+	path := fmt.Sprintf("/api/v1/dods/%d/items/%d", dodID, optionalItemID)
+
+	w := performRequest(router, "PATCH", path, viewer.Token, models.UpdateDoDItemRequest{IsRequired: &required})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "PATCH", path, owner.Token, models.UpdateDoDItemRequest{IsRequired: &required, Order: &order})
+	assert.Equal(t, http.StatusOK, w.Code)
+	item := decodeResponse(w)["item"].(map[string]interface{})
+	assert.Equal(t, true, item["is_required"])
+	assert.Equal(t, float64(10), item["order"])
+}
+
+func TestDeleteDoDItem(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "delitemowner")
+	_, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+	_, otherDoDID, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	// Items are only reachable through their own DoD
+	w := performRequest(router, "DELETE", fmt.Sprintf("/api/v1/dods/%d/items/%d", otherDoDID, requiredItemID), owner.Token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/dods/%d/items/%d", dodID, requiredItemID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/dods/%d", dodID), owner.Token, nil)
+	assert.Len(t, decodeResponse(w)["dod"].(map[string]interface{})["items"], 1)
+}
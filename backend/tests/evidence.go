@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dod-backend/models"
+	"dod-backend/reports"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const testJUnitReport = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="checkout" tests="3">
+    <testcase name="adds to cart"/>
+    <testcase name="applies coupon"><failure message="expected 10, got 12"/></testcase>
+    <testcase name="pays"><skipped/></testcase>
+  </testsuite>
+  <testsuite name="outer">
+    <testsuite name="inner">
+      <testcase name="nested"/>
+    </testsuite>
+  </testsuite>
+</testsuites>`
+
+const testCoberturaReport = `<?xml version="1.0"?>
+<coverage line-rate="0.7" lines-covered="70" lines-valid="100" version="1.9"></coverage>`
+
+const testLCOVReport = `TN:
+SF:src/cart.js
+LF:40
+LH:38
+end_of_record
+SF:src/pay.js
+LF:10
+LH:8
+end_of_record
+`
+
+const testSARIFReport = `{
+  "version": "2.1.0",
+  "runs": [{
+    "tool": {"driver": {"name": "scanner", "rules": [
+      {"id": "sql-injection", "properties": {"security-severity": "9.1"}},
+      {"id": "unused-var", "defaultConfiguration": {"level": "note"}}
+    ]}},
+    "results": [
+      {"ruleId": "sql-injection", "level": "warning"},
+      {"ruleId": "unused-var"},
+      {"ruleId": "hardcoded-secret", "level": "error"}
+    ]
+  }]
+}`
+
+func uploadTestReport(router *gin.Engine, path, token, report string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", path, strings.NewReader(report))
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestParseReports(t *testing.T) {
+	assert.Equal(t, reports.JUnit, reports.Detect([]byte(testJUnitReport)))
+	assert.Equal(t, reports.Cobertura, reports.Detect([]byte(testCoberturaReport)))
+	assert.Equal(t, reports.LCOV, reports.Detect([]byte(testLCOVReport)))
+	assert.Equal(t, reports.SARIF, reports.Detect([]byte(testSARIFReport)))
+
+	summary, err := reports.Parse("", []byte(testJUnitReport))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, summary.Tests)
+	assert.Equal(t, 1, summary.Failures)
+	assert.Equal(t, 1, summary.Skipped)
+
+	summary, err = reports.Parse(reports.LCOV, []byte(testLCOVReport))
+	assert.NoError(t, err)
+	assert.Equal(t, 50, summary.LinesValid)
+	assert.InDelta(t, 92.0, *summary.Coverage, 0.001)
+
+	// An empty report does not meet a coverage check
+	summary, err = reports.Parse(reports.LCOV, []byte("TN:\nend_of_record\n"))
+	assert.NoError(t, err)
+	assert.Nil(t, summary.Coverage)
+
+	// High: error level, or a high security-severity taken from the rule
+	summary, err = reports.Parse("", []byte(testSARIFReport))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, summary.Findings)
+	assert.Equal(t, 2, summary.HighFindings)
+
+	_, err = reports.Parse("", []byte("hello"))
+	assert.Equal(t, reports.ErrUnknownFormat, err)
+}
+
+func TestCoverageReportTicksItem(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "evidenceowner")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+
+	coverage, tooHigh := models.AutoCheckCoverage, 120.0
+	w := performRequest(router, "PATCH", fmt.Sprintf("/api/v1/dods/%d/items/%d", dodID, requiredItemID), owner.Token, models.UpdateDoDItemRequest{AutoCheck: &coverage, AutoCheckThreshold: &tooHigh})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	setTestVerification(t, router, owner, dodID, requiredItemID, models.UpdateDoDItemRequest{AutoCheck: &coverage})
+
+	workItem := createTestWorkItem(t, router, owner, projectID, dodID)
+	evidencePath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d/evidence", projectID, uint(workItem["id"].(float64)))
+
+	w = uploadTestReport(router, evidencePath, owner.Token, "not a report")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = uploadTestReport(router, evidencePath+"?format=clover", owner.Token, testCoberturaReport)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 70% is under the default threshold
+	w = uploadTestReport(router, evidencePath, owner.Token, testCoberturaReport)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	response := decodeResponse(w)
+	results := response["results"].([]interface{})
+	assert.Len(t, results, 1)
+	assert.Equal(t, false, results[0].(map[string]interface{})["passed"])
+	assert.Equal(t, float64(80), results[0].(map[string]interface{})["threshold"])
+	assert.False(t, workItemDone(t, response))
+
+	w = uploadTestReport(router, evidencePath+"?name=unit", owner.Token, testLCOVReport)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	response = decodeResponse(w)
+	assert.True(t, workItemDone(t, response))
+	evidenceID := response["evidence"].(map[string]interface{})["id"]
+	criterion := workItemStatus(response["work_item"].(map[string]interface{}))["criteria"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, evidenceID, criterion["evidence_id"])
+	assert.Contains(t, criterion["note"], "92.0%")
+
+	// Reports that no check uses are kept all the same
+	w = uploadTestReport(router, evidencePath, owner.Token, testSARIFReport)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, decodeResponse(w)["results"])
+
+	w = performRequest(router, "GET", evidencePath, owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	evidence := decodeResponse(w)["evidence"].([]interface{})
+	assert.Len(t, evidence, 3)
+	assert.Equal(t, "unit", evidence[1].(map[string]interface{})["name"])
+
+	w = performRequest(router, "GET", fmt.Sprintf("%s/%d", evidencePath, uint(evidenceID.(float64))), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, testLCOVReport, w.Body.String())
+
+	// A report without lines unticks the item rather than meeting it
+	w = uploadTestReport(router, evidencePath+"?format=lcov", owner.Token, "TN:\nend_of_record\n")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	response = decodeResponse(w)
+	assert.Equal(t, "No lines measured", response["results"].([]interface{})[0].(map[string]interface{})["detail"])
+	assert.False(t, workItemDone(t, response))
+}
+
+func TestFailingTestsUntickItem(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "junitowner")
+	reviewer := registerTestUser(t, router, "junitreviewer")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, reviewer, "editor")
+
+	tests, allowed, peer := models.AutoCheckTests, 1.0, models.VerificationPeer
+	setTestVerification(t, router, owner, dodID, requiredItemID, models.UpdateDoDItemRequest{AutoCheck: &tests, AutoCheckThreshold: &allowed, Verification: &peer})
+
+	workItem := createTestWorkItem(t, router, owner, projectID, dodID)
+	workItemPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, uint(workItem["id"].(float64)))
+
+	// One failure is allowed, and the peer policy still applies
+	w := uploadTestReport(router, workItemPath+"/evidence", owner.Token, testJUnitReport)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	response := decodeResponse(w)
+	assert.Equal(t, true, response["results"].([]interface{})[0].(map[string]interface{})["passed"])
+	criterion := workItemStatus(response["work_item"].(map[string]interface{}))["criteria"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, true, criterion["pending_approval"])
+
+	w = performRequest(router, "POST", fmt.Sprintf("%s/checks/%d/approve", workItemPath, requiredItemID), reviewer.Token, models.SignOffRequest{})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, workItemDone(t, decodeResponse(w)))
+
+	failing := strings.Replace(testJUnitReport, `<testcase name="nested"/>`, `<testcase name="nested"><error/></testcase>`, 1)
+	w = uploadTestReport(router, workItemPath+"/evidence?format=junit", owner.Token, failing)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	response = decodeResponse(w)
+	assert.Equal(t, false, response["results"].([]interface{})[0].(map[string]interface{})["passed"])
+	assert.False(t, workItemDone(t, response))
+
+	w = performRequest(router, "GET", fmt.Sprintf("%s/checks/%d/decisions", workItemPath, requiredItemID), owner.Token, nil)
+	decisions := decodeResponse(w)["decisions"].([]interface{})
+	assert.Equal(t, models.DecisionWithdrawn, decisions[len(decisions)-1].(map[string]interface{})["decision"])
+}
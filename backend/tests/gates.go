@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"dod-backend/client"
+	"dod-backend/dodctl"
+	"dod-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGateVerdicts(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "gateowner")
+	lead := registerTestUser(t, router, "gatelead")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, lead, "editor")
+
+	keysPath := fmt.Sprintf("/api/v1/projects/%d/api-keys", projectID)
+	gateKey, _ := createTestAPIToken(t, router, keysPath, owner.Token, models.CreateAPITokenRequest{Name: "CI", Scopes: []string{models.ScopeGate}})
+	readKey, _ := createTestAPIToken(t, router, keysPath, owner.Token, models.CreateAPITokenRequest{Name: "Dashboard", Scopes: []string{models.ScopeRead}})
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), owner.Token, models.CreateWorkItemRequest{Title: "Checkout", ExternalRef: "SHOP-42", DoDID: &dodID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	workItemID := uint(decodeResponse(w)["work_item"].(map[string]interface{})["id"].(float64))
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+	ci := client.New(server.URL, gateKey)
+	gate := func(args ...string) (int, string) {
+		var stdout, stderr bytes.Buffer
+		code := dodctl.Run(append([]string{"gate", "-server", server.URL, "-token", gateKey}, args...), &stdout, &stderr)
+		return code, stdout.String()
+	}
+
+	// Keys need the gate scope
+	_, err := client.New(server.URL, readKey).Gate(context.Background(), "SHOP-42", 0)
+	assert.Equal(t, http.StatusForbidden, err.(*client.Error).StatusCode)
+
+	_, err = ci.Gate(context.Background(), "SHOP-404", 0)
+	assert.Equal(t, http.StatusNotFound, err.(*client.Error).StatusCode)
+
+	verdict, err := ci.Gate(context.Background(), "SHOP-42", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, client.VerdictFail, verdict.Verdict)
+	assert.False(t, verdict.Pass)
+	assert.Equal(t, workItemID, verdict.WorkItemID)
+	assert.Len(t, verdict.Unmet, 1)
+	assert.Equal(t, requiredItemID, verdict.Unmet[0].ID)
+	assert.Equal(t, "not checked", verdict.Unmet[0].Reason)
+	assert.Len(t, verdict.Reasons, 1)
+
+	// dodctl exits with 1 on fail, and 2 when it gets no verdict
+	code, _ := gate("SHOP-42")
+	assert.Equal(t, dodctl.ExitFail, code)
+	code, _ = gate("SHOP-404")
+	assert.Equal(t, dodctl.ExitError, code)
+	code, _ = gate()
+	assert.Equal(t, dodctl.ExitError, code)
+	code = dodctl.Run([]string{"gate", "-server", server.URL, "-token", readKey, "SHOP-42"}, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.Equal(t, dodctl.ExitError, code)
+	code = dodctl.Run([]string{"gate", "-server", server.URL, "-token", "", "SHOP-42"}, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.Equal(t, dodctl.ExitError, code)
+	code = dodctl.Run([]string{"status", "SHOP-42"}, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.Equal(t, dodctl.ExitError, code)
+
+	// A waiver lets the gate through, flagged as such
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items/%d/checks/%d/waivers", projectID, workItemID, requiredItemID), owner.Token, models.CreateWaiverRequest{
+		Justification: "Hotfix",
+		ApproverID:    lead.ID,
+		ExpiresAt:     time.Now().Add(24 * time.Hour),
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	waiverID := uint(decodeResponse(w)["waiver"].(map[string]interface{})["id"].(float64))
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/waivers/%d/approve", projectID, waiverID), lead.Token, models.DecideWaiverRequest{})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	verdict, err = ci.Gate(context.Background(), "SHOP-42", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, client.VerdictWaived, verdict.Verdict)
+	assert.True(t, verdict.Pass)
+	assert.Len(t, verdict.Waived, 1)
+	assert.Equal(t, &waiverID, verdict.Waived[0].WaiverID)
+
+	// Waived work items pass, unless -strict
+	code, _ = gate("SHOP-42")
+	assert.Equal(t, dodctl.ExitPass, code)
+	code, _ = gate("-strict", "SHOP-42")
+	assert.Equal(t, dodctl.ExitFail, code)
+
+	checked := true
+	w = performRequest(router, "PUT", fmt.Sprintf("/api/v1/projects/%d/work-items/%d/checks/%d", projectID, workItemID, requiredItemID), owner.Token, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	verdict, err = ci.Gate(context.Background(), fmt.Sprint(workItemID), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, client.VerdictPass, verdict.Verdict)
+	assert.Empty(t, verdict.Unmet)
+	assert.Empty(t, verdict.Waived)
+
+	code, output := gate("-strict", "-json", "SHOP-42")
+	assert.Equal(t, dodctl.ExitPass, code)
+	var printed client.Verdict
+	assert.NoError(t, json.Unmarshal([]byte(output), &printed))
+	assert.Equal(t, client.VerdictPass, printed.Verdict)
+}
+
+func TestGateProjectScoping(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "gatescope")
+	outsider := registerTestUser(t, router, "gateoutsider")
+	projectID, dodID, _, _ := createTestProjectWithDoD(t, router, owner)
+	otherProjectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	key, _ := createTestAPIToken(t, router, fmt.Sprintf("/api/v1/projects/%d/api-keys", projectID), owner.Token, models.CreateAPITokenRequest{Name: "CI", Scopes: []string{models.ScopeGate}})
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), owner.Token, models.CreateWorkItemRequest{Title: "Login", ExternalRef: "AUTH-1", DoDID: &dodID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/gates/AUTH-1?project_id=%d", otherProjectID), key, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Other credentials name the project
+	w = performRequest(router, "GET", "/api/v1/gates/AUTH-1", owner.Token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/gates/AUTH-1?project_id=%d", projectID), outsider.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/gates/AUTH-1?project_id=%d", projectID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.GateFail, decodeResponse(w)["verdict"])
+
+	// Work items without a DoD never pass
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), owner.Token, models.CreateWorkItemRequest{Title: "Spike", ExternalRef: "AUTH-2"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = performRequest(router, "GET", "/api/v1/gates/AUTH-2", key, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response := decodeResponse(w)
+	assert.Equal(t, models.GateFail, response["verdict"])
+	assert.Contains(t, response["reasons"], "No DoD is attached to the work item")
+}
@@ -0,0 +1,241 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"dod-backend/mailer"
+	"dod-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tokenFromEmail extracts the value of a query parameter from the link in an email body.
+func tokenFromEmail(t *testing.T, body, param string) string {
+	start := strings.Index(body, param+"=")
+	if !assert.NotEqual(t, -1, start, "no %s link in email", param) {
+		return ""
+	}
+	raw := body[start+len(param)+1:]
+	if end := strings.IndexAny(raw, " \n"); end != -1 {
+		raw = raw[:end]
+	}
+	token, _ := url.QueryUnescape(raw)
+	return token
+}
+
+func TestInviteUnregisteredEmail(t *testing.T) {
+	router, mailer := setupTestRouterWithMailer()
+	owner := registerTestUser(t, router, "inviteowner")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	email := fmt.Sprintf("newcomer%d@example.com", time.Now().UnixNano())
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/participants", projectID), owner.Token, models.AddParticipantRequest{
+		Email: email,
+		Role:  "editor",
+	})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	// Inviting the same email twice is refused while the first is pending
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/participants", projectID), owner.Token, models.AddParticipantRequest{
+		Email: email,
+		Role:  "viewer",
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	msg, ok := mailer.Last(email)
+	assert.True(t, ok)
+	token := tokenFromEmail(t, msg.Body, "invitation")
+
+	w = performRequest(router, "GET", "/api/v1/invitations/"+url.PathEscape(token), "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	invitation := decodeResponse(w)["invitation"].(map[string]interface{})
+	assert.Equal(t, email, invitation["email"])
+	assert.Equal(t, "editor", invitation["role"])
+
+	// The link only works for the invited email
+	w = performRequest(router, "POST", "/api/v1/auth/register", "", models.RegisterRequest{
+		Username:        fmt.Sprintf("intruder%d", time.Now().UnixNano()),
+		Email:           "intruder-" + email,
+		Password:        "password123",
+		InvitationToken: token,
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Registering through the link joins the project with the invited role
+	w = performRequest(router, "POST", "/api/v1/auth/register", "", models.RegisterRequest{
+		Username:        fmt.Sprintf("newcomer%d", time.Now().UnixNano()),
+		Email:           email,
+		Password:        "password123",
+		InvitationToken: token,
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	response := decodeResponse(w)
+	assert.Equal(t, []interface{}{float64(projectID)}, response["joined_projects"])
+
+	newcomerToken := response["token"].(string)
+	w = performRequest(router, "POST", "/api/v1/dods/", newcomerToken, models.CreateDoDRequest{Title: "Editor DoD", ProjectID: projectID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// The invitation is consumed
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/invitations", projectID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, decodeResponse(w)["invitations"])
+}
+
+func TestInvitationRequiresLink(t *testing.T) {
+	router, mailer := setupTestRouterWithMailer()
+	owner := registerTestUser(t, router, "linkowner")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	email := fmt.Sprintf("squatted%d@example.com", time.Now().UnixNano())
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/participants", projectID), owner.Token, models.AddParticipantRequest{
+		Email: email,
+		Role:  "editor",
+	})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	msg, _ := mailer.Last(email)
+	token := tokenFromEmail(t, msg.Body, "invitation")
+
+	// Knowing the invited address is not enough to join
+	w = performRequest(router, "POST", "/api/v1/auth/register", "", models.RegisterRequest{
+		Username: fmt.Sprintf("squatter%d", time.Now().UnixNano()),
+		Email:    email,
+		Password: "password123",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, decodeResponse(w)["joined_projects"])
+
+	squatterToken := decodeResponse(w)["token"].(string)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/invitations", projectID), owner.Token, nil)
+	assert.Len(t, decodeResponse(w)["invitations"], 1)
+	w = performRequest(router, "GET", "/api/v1/invitations/"+url.PathEscape(token), "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/auth/register", "", models.RegisterRequest{
+		Username:        fmt.Sprintf("forged%d", time.Now().UnixNano()),
+		Email:           "forged-" + email,
+		Password:        "password123",
+		InvitationToken: "not-a-token",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Signed in, the link still only works for the invited email
+	w = performRequest(router, "POST", "/api/v1/invitations/accept", owner.Token, models.AcceptInvitationRequest{Token: token})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequest(router, "POST", "/api/v1/invitations/accept", squatterToken, models.AcceptInvitationRequest{Token: "not-a-token"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAcceptInvitationWhenRegistered(t *testing.T) {
+	router, mailer := setupTestRouterWithMailer()
+	owner := registerTestUser(t, router, "acceptowner")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	// Registered without the link, the invitee accepts it once signed in
+	email := fmt.Sprintf("latecomer%d@example.com", time.Now().UnixNano())
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/participants", projectID), owner.Token, models.AddParticipantRequest{
+		Email: email,
+		Role:  "editor",
+	})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	msg, _ := mailer.Last(email)
+	token := tokenFromEmail(t, msg.Body, "invitation")
+
+	w = performRequest(router, "POST", "/api/v1/auth/register", "", models.RegisterRequest{
+		Username: fmt.Sprintf("latecomer%d", time.Now().UnixNano()),
+		Email:    email,
+		Password: "password123",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	latecomerToken := decodeResponse(w)["token"].(string)
+
+	w = performRequest(router, "POST", "/api/v1/invitations/accept", latecomerToken, models.AcceptInvitationRequest{Token: token})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []interface{}{float64(projectID)}, decodeResponse(w)["joined_projects"])
+
+	w = performRequest(router, "POST", "/api/v1/dods/", latecomerToken, models.CreateDoDRequest{Title: "Editor DoD", ProjectID: projectID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// The invitation is consumed
+	w = performRequest(router, "POST", "/api/v1/invitations/accept", latecomerToken, models.AcceptInvitationRequest{Token: token})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(mailer.Message) error {
+	return errors.New("smtp: connection refused")
+}
+
+func TestInvitationEmailFailure(t *testing.T) {
+	ctrl := setupTestController()
+	router := setupTestRouterWithController(ctrl)
+	owner := registerTestUser(t, router, "failedinviteowner")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	// The invitation is kept, marked as not sent
+	ctrl.Mailer = failingMailer{}
+	email := fmt.Sprintf("unreachable%d@example.com", time.Now().UnixNano())
+	invite := models.AddParticipantRequest{Email: email, Role: "editor"}
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/participants", projectID), owner.Token, invite)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/invitations", projectID), owner.Token, nil)
+	invitations := decodeResponse(w)["invitations"].([]interface{})
+	assert.Len(t, invitations, 1)
+	assert.NotEmpty(t, invitations[0].(map[string]interface{})["send_error"])
+
+	// Inviting again replaces it
+	memory := &mailer.MemoryMailer{}
+	ctrl.Mailer = memory
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/participants", projectID), owner.Token, invite)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	_, sent := memory.Last(email)
+	assert.True(t, sent)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/invitations", projectID), owner.Token, nil)
+	invitations = decodeResponse(w)["invitations"].([]interface{})
+	assert.Len(t, invitations, 1)
+	assert.Empty(t, invitations[0].(map[string]interface{})["send_error"])
+}
+
+func TestRevokeInvitation(t *testing.T) {
+	router, mailer := setupTestRouterWithMailer()
+	owner := registerTestUser(t, router, "revokeowner")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	email := fmt.Sprintf("revoked%d@example.com", time.Now().UnixNano())
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/participants", projectID), owner.Token, models.AddParticipantRequest{
+		Email: email,
+		Role:  "viewer",
+	})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	invitationID := uint(decodeResponse(w)["invitation"].(map[string]interface{})["id"].(float64))
+	msg, _ := mailer.Last(email)
+
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/projects/%d/invitations/%d", projectID, invitationID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/auth/register", "", models.RegisterRequest{
+		Username:        fmt.Sprintf("revoked%d", time.Now().UnixNano()),
+		Email:           email,
+		Password:        "password123",
+		InvitationToken: tokenFromEmail(t, msg.Body, "invitation"),
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, decodeResponse(w)["joined_projects"])
+}
+
+func TestInvalidInvitationToken(t *testing.T) {
+	router := setupTestRouter()
+
+	w := performRequest(router, "GET", "/api/v1/invitations/not-a-token", "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+
+	"dod-backend/mailer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTPServer accepts a single SMTP session on a local port and reports
+// the DATA section it received.
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan string, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost fake SMTP")
+
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					received <- data.String()
+					reply("250 OK")
+					continue
+				}
+				data.WriteString(line)
+				continue
+			}
+
+			switch command := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case command == "DATA":
+				inData = true
+				reply("354 End data with <CR><LF>.<CR><LF>")
+			case command == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), received
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+
+	smtpMailer := &mailer.SMTPMailer{Addr: addr, From: "DoD Manager <no-reply@example.com>"}
+	err := smtpMailer.Send(mailer.Message{
+		To:      "alice@example.com",
+		Subject: "Invitation",
+		Body:    "Welcome aboard",
+	})
+	assert.NoError(t, err)
+
+	data := <-received
+	assert.Contains(t, data, "To: alice@example.com")
+	assert.Contains(t, data, "Subject: Invitation")
+	assert.Contains(t, data, "Welcome aboard")
+}
+
+func TestSMTPMailerEncodesSubject(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+
+	smtpMailer := &mailer.SMTPMailer{Addr: addr, From: "DoD Manager <no-reply@example.com>"}
+	err := smtpMailer.Send(mailer.Message{
+		To:      "alice@example.com",
+		Subject: "Waiver expired: Déploiement\r\nBcc: mallory@example.com",
+		Body:    "Welcome aboard",
+	})
+	assert.NoError(t, err)
+
+	data := <-received
+	assert.NotContains(t, data, "\r\nBcc:")
+	assert.Contains(t, data, "Subject: =?utf-8?q?Waiver_expired:_D=C3=A9ploiement_Bcc:_mallory@example.com?=\r\n")
+
+	err = smtpMailer.Send(mailer.Message{To: "alice@example.com\r\nBcc: mallory@example.com", Subject: "Hi"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,341 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"dod-backend/chat"
+	"dod-backend/models"
+	"dod-backend/safehttp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// chatMessages decodes the payloads a chat stand-in was sent.
+func chatMessages(r *webhookReceiver) []map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	messages := []map[string]interface{}{}
+	for _, received := range r.received {
+		var message map[string]interface{}
+		json.Unmarshal(received.Body, &message)
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func createTestChannel(t *testing.T, router *gin.Engine, owner testUser, projectID uint, req models.CreateNotificationChannelRequest) string {
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/notification-channels", projectID), owner.Token, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	channel := decodeResponse(w)["channel"].(map[string]interface{})
+	return fmt.Sprintf("/api/v1/projects/%d/notification-channels/%d", projectID, uint(channel["id"].(float64)))
+}
+
+func TestChatPayloads(t *testing.T) {
+	n := chat.Notification{
+		Title: "DoD published: Backend (revision 2)",
+		Text:  "Added a changelog item",
+		Facts: []chat.Fact{{Name: "Project", Value: "Payments"}},
+		URL:   "http://localhost:3000/projects/1",
+	}
+
+	payload, err := chat.Payload(chat.Slack, n)
+	assert.NoError(t, err)
+	var slack map[string]interface{}
+	assert.NoError(t, json.Unmarshal(payload, &slack))
+	assert.Equal(t, n.Title, slack["text"])
+	blocks := slack["blocks"].([]interface{})
+	assert.Len(t, blocks, 4)
+	assert.Equal(t, "header", blocks[0].(map[string]interface{})["type"])
+	assert.Equal(t, "*Project*\nPayments", blocks[2].(map[string]interface{})["fields"].([]interface{})[0].(map[string]interface{})["text"])
+	button := blocks[3].(map[string]interface{})["elements"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, n.URL, button["url"])
+
+	payload, err = chat.Payload(chat.Teams, n)
+	assert.NoError(t, err)
+	var teams map[string]interface{}
+	assert.NoError(t, json.Unmarshal(payload, &teams))
+	attachment := teams["attachments"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "application/vnd.microsoft.card.adaptive", attachment["contentType"])
+	card := attachment["content"].(map[string]interface{})
+	assert.Equal(t, "AdaptiveCard", card["type"])
+	body := card["body"].([]interface{})
+	assert.Equal(t, n.Title, body[0].(map[string]interface{})["text"])
+	assert.Equal(t, "FactSet", body[2].(map[string]interface{})["type"])
+	assert.Equal(t, "Action.OpenUrl", card["actions"].([]interface{})[0].(map[string]interface{})["type"])
+
+	// Slack mrkdwn is escaped, plain text is not
+	n = chat.Notification{Title: "Waiver expired: <!channel>", Text: "Ping <!here> & <@U123>", Facts: []chat.Fact{{Name: "Justification", Value: "<https://evil.example|Click>"}}}
+	payload, err = chat.Payload(chat.Slack, n)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(payload, &slack))
+	assert.Equal(t, "Waiver expired: &lt;!channel&gt;", slack["text"])
+	blocks = slack["blocks"].([]interface{})
+	assert.Equal(t, n.Title, blocks[0].(map[string]interface{})["text"].(map[string]interface{})["text"])
+	assert.Equal(t, "Ping &lt;!here&gt; &amp; &lt;@U123&gt;", blocks[1].(map[string]interface{})["text"].(map[string]interface{})["text"])
+	assert.Equal(t, "*Justification*\n&lt;https://evil.example|Click&gt;", blocks[2].(map[string]interface{})["fields"].([]interface{})[0].(map[string]interface{})["text"])
+
+	_, err = chat.Payload("irc", n)
+	assert.Equal(t, chat.ErrUnknownKind, err)
+}
+
+func TestChatClientRefusesInternalAddresses(t *testing.T) {
+	receiver := newWebhookReceiver()
+	defer receiver.Close()
+
+	err := chat.NewClient().Send(context.Background(), chat.Slack, receiver.URL, chat.Notification{Title: "Hello"})
+	assert.ErrorIs(t, err, safehttp.ErrNonPublicAddress)
+	assert.Equal(t, 0, receiver.count())
+}
+
+func TestNotificationChannels(t *testing.T) {
+	ctrl := setupTestController()
+	router := setupTestRouterWithController(ctrl)
+	owner := registerTestUser(t, router, "chatowner")
+	editor := registerTestUser(t, router, "chateditor")
+	projectID, dodID, _, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, editor, "editor")
+
+	slack := newWebhookReceiver()
+	defer slack.Close()
+	teams := newWebhookReceiver()
+	defer teams.Close()
+
+	channelsPath := fmt.Sprintf("/api/v1/projects/%d/notification-channels", projectID)
+	req := models.CreateNotificationChannelRequest{Name: "#releases", Kind: chat.Slack, WebhookURL: slack.URL + "/services/T0/B0/secret", Events: []string{models.NotifyDoDPublished}}
+	w := performRequest(router, "POST", channelsPath, editor.Token, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequest(router, "POST", channelsPath, owner.Token, models.CreateNotificationChannelRequest{Name: "Team", Kind: chat.Teams, WebhookURL: teams.URL, Events: []string{"dod.renamed"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(router, "POST", channelsPath, owner.Token, models.CreateNotificationChannelRequest{Name: "IRC", Kind: "irc", WebhookURL: teams.URL, Events: []string{"*"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	slackPath := createTestChannel(t, router, owner, projectID, req)
+	teamsPath := createTestChannel(t, router, owner, projectID, models.CreateNotificationChannelRequest{Name: "Team", Kind: chat.Teams, WebhookURL: teams.URL + "/webhookb2/secret", Events: []string{"*"}})
+
+	// The webhook URL is a secret
+	w = performRequest(router, "GET", channelsPath, owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response := decodeResponse(w)
+	assert.Len(t, response["events"], len(models.NotificationEvents))
+	channel := response["channels"].([]interface{})[0].(map[string]interface{})
+	assert.NotContains(t, channel, "webhook_url")
+	assert.Equal(t, slack.URL+"/…cret", channel["webhook_url_hint"])
+
+	// The test message goes out whatever the events of the channel
+	w = performRequest(router, "POST", slackPath+"/test", owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotNil(t, decodeResponse(w)["channel"].(map[string]interface{})["last_sent_at"])
+	assert.Equal(t, "Test notification from DoD Manager", chatMessages(slack)[0]["text"])
+
+	// Publishing is posted to both channels, each in its own format, once the
+	// request is answered
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/dods/%d/publish", dodID), owner.Token, models.PublishDoDRequest{Changelog: "First version"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	ctrl.WaitBackground()
+	assert.Equal(t, 2, slack.count())
+	published := chatMessages(slack)[1]
+	assert.Contains(t, published["text"], "(revision 1)")
+	section := published["blocks"].([]interface{})[1].(map[string]interface{})
+	assert.Equal(t, "First version", section["text"].(map[string]interface{})["text"])
+
+	assert.Equal(t, 1, teams.count())
+	card := chatMessages(teams)[0]["attachments"].([]interface{})[0].(map[string]interface{})["content"].(map[string]interface{})
+	assert.Contains(t, card["body"].([]interface{})[0].(map[string]interface{})["text"], "(revision 1)")
+	assert.Equal(t, fmt.Sprintf("http://localhost:3000/projects/%d", projectID), card["actions"].([]interface{})[0].(map[string]interface{})["url"])
+
+	// Inactive channels are left out
+	active := false
+	w = performRequest(router, "PATCH", teamsPath, owner.Token, models.UpdateNotificationChannelRequest{Active: &active})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/dods/%d/items", dodID), owner.Token, models.CreateDoDItemRequest{Title: "Changelog Updated", Order: 3})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/dods/%d/publish", dodID), owner.Token, models.PublishDoDRequest{Changelog: "Changelog item"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	ctrl.WaitBackground()
+	assert.Equal(t, 3, slack.count())
+	assert.Equal(t, 1, teams.count())
+
+	// Failures are answered and kept on the channel
+	slack.answer(http.StatusNotFound)
+	w = performRequest(router, "POST", slackPath+"/test", owner.Token, nil)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Equal(t, "Failed to send test notification: chat answered 404 Not Found", decodeResponse(w)["error"])
+	w = performRequest(router, "GET", channelsPath, owner.Token, nil)
+	channel = decodeResponse(w)["channels"].([]interface{})[0].(map[string]interface{})
+	assert.Contains(t, channel["last_error"], "404")
+
+	w = performRequest(router, "DELETE", slackPath, owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "POST", slackPath+"/test", owner.Token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestNotificationErrorsHideWebhookURL(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "chaterrorowner")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+	channelPath := createTestChannel(t, router, owner, projectID, models.CreateNotificationChannelRequest{Name: "Gone", Kind: chat.Slack, WebhookURL: gone.URL + "/services/T0/B0/topsecret", Events: []string{"*"}})
+
+	w := performRequest(router, "POST", channelPath+"/test", owner.Token, nil)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.NotContains(t, decodeResponse(w)["error"], "topsecret")
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/notification-channels", projectID), owner.Token, nil)
+	channel := decodeResponse(w)["channels"].([]interface{})[0].(map[string]interface{})
+	assert.NotEmpty(t, channel["last_error"])
+	assert.NotContains(t, channel["last_error"], "topsecret")
+}
+
+func TestWaiverAndBlockedNotifications(t *testing.T) {
+	ctrl := setupTestController()
+	router := setupTestRouterWithController(ctrl)
+	owner := registerTestUser(t, router, "chatwaiverowner")
+	lead := registerTestUser(t, router, "chatwaiverlead")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, lead, "editor")
+
+	waivers := newWebhookReceiver()
+	defer waivers.Close()
+	blocked := newWebhookReceiver()
+	defer blocked.Close()
+	createTestChannel(t, router, owner, projectID, models.CreateNotificationChannelRequest{Name: "Waivers", Kind: chat.Slack, WebhookURL: waivers.URL, Events: []string{models.NotifyWaiverExpiring, models.NotifyWaiverExpired}})
+	createTestChannel(t, router, owner, projectID, models.CreateNotificationChannelRequest{Name: "Blocked", Kind: chat.Teams, WebhookURL: blocked.URL, Events: []string{models.NotifyWorkItemBlocked}})
+
+	expiresAt := time.Now().Add(30 * time.Hour)
+	waive := func(workItemPath string) {
+		w := performRequest(router, "POST", fmt.Sprintf("%s/checks/%d/waivers", workItemPath, requiredItemID), owner.Token, models.CreateWaiverRequest{
+			Justification: "Security review booked next sprint",
+			ApproverID:    lead.ID,
+			ExpiresAt:     expiresAt,
+			FollowUpRef:   "SEC-9",
+		})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		waiverID := uint(decodeResponse(w)["waiver"].(map[string]interface{})["id"].(float64))
+		w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/waivers/%d/approve", projectID, waiverID), lead.Token, models.DecideWaiverRequest{})
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	// A waiver whose work item cannot be loaded does not hold back the others
+	broken := createTestWorkItem(t, router, owner, projectID, dodID)
+	waive(fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, uint(broken["id"].(float64))))
+	assert.NoError(t, ctrl.DB.Delete(&models.WorkItem{}, uint(broken["id"].(float64))).Error)
+
+	workItem := createTestWorkItem(t, router, owner, projectID, dodID)
+	workItemID := uint(workItem["id"].(float64))
+	workItemPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, workItemID)
+	waive(workItemPath)
+	var w *httptest.ResponseRecorder
+
+	for _, state := range []string{models.WorkItemInProgress, models.WorkItemDone} {
+		w = performRequest(router, "PUT", workItemPath+"/state", owner.Token, models.UpdateWorkItemStateRequest{State: state})
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	// Waivers are announced once, a day before they run out
+	reminded, err := ctrl.RemindExpiringWaivers(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, reminded)
+
+	reminded, err = ctrl.RemindExpiringWaivers(time.Now().Add(7 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reminded)
+	reminded, _ = ctrl.RemindExpiringWaivers(time.Now().Add(8 * time.Hour))
+	assert.Equal(t, 0, reminded)
+
+	ctrl.WaitBackground()
+	assert.Equal(t, 1, waivers.count())
+	expiring := chatMessages(waivers)[0]
+	assert.Contains(t, expiring["text"], "Waiver expiring")
+	fields := expiring["blocks"].([]interface{})[2].(map[string]interface{})["fields"].([]interface{})
+	assert.Equal(t, "*Follow-up*\nSEC-9", fields[len(fields)-1].(map[string]interface{})["text"])
+	assert.Equal(t, 0, blocked.count())
+
+	// Expiry reopens the work item, which is announced as blocked
+	expired, err := ctrl.ExpireWaivers(expiresAt.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+
+	ctrl.WaitBackground()
+	assert.Equal(t, 2, waivers.count())
+	assert.Contains(t, chatMessages(waivers)[1]["text"], "Waiver expired")
+
+	assert.Equal(t, 1, blocked.count())
+	card := chatMessages(blocked)[0]["attachments"].([]interface{})[0].(map[string]interface{})["content"].(map[string]interface{})
+	assert.Contains(t, card["body"].([]interface{})[0].(map[string]interface{})["text"], "Work item blocked")
+	facts := card["body"].([]interface{})[2].(map[string]interface{})["facts"].([]interface{})
+	assert.Equal(t, "Unmet required items", facts[1].(map[string]interface{})["title"])
+	assert.NotEmpty(t, facts[1].(map[string]interface{})["value"])
+	assert.Equal(t, fmt.Sprintf("http://localhost:3000/projects/%d/work-items/%d", projectID, workItemID), card["actions"].([]interface{})[0].(map[string]interface{})["url"])
+
+	// So is a work item moved to done with unmet required items
+	unfinished := createTestWorkItem(t, router, owner, projectID, dodID)
+	w = performRequest(router, "PUT", fmt.Sprintf("/api/v1/projects/%d/work-items/%d/state", projectID, uint(unfinished["id"].(float64))), owner.Token, models.UpdateWorkItemStateRequest{State: models.WorkItemDone})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	ctrl.WaitBackground()
+	assert.Equal(t, 2, blocked.count())
+	card = chatMessages(blocked)[1]["attachments"].([]interface{})[0].(map[string]interface{})["content"].(map[string]interface{})
+	assert.Contains(t, card["body"].([]interface{})[1].(map[string]interface{})["text"], "moved to done")
+}
+
+func TestReadyChecklistBlockNotification(t *testing.T) {
+	ctrl := setupTestController()
+	router := setupTestRouterWithController(ctrl)
+	owner := registerTestUser(t, router, "chatreadyowner")
+	projectID, dodID, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	receiver := newWebhookReceiver()
+	defer receiver.Close()
+	createTestChannel(t, router, owner, projectID, models.CreateNotificationChannelRequest{Name: "Blocked", Kind: chat.Slack, WebhookURL: receiver.URL, Events: []string{models.NotifyWorkItemBlocked}})
+
+	w := performRequest(router, "POST", "/api/v1/dods/", owner.Token, models.CreateDoDRequest{Title: "Ready", ProjectID: projectID, Kind: models.DoDKindReady})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	readyID := uint(decodeResponse(w)["dod"].(map[string]interface{})["id"].(float64))
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/dods/%d/items", readyID), owner.Token, models.CreateDoDItemRequest{Title: "Acceptance criteria written", IsRequired: true, Order: 1})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	workItem := createTestWorkItem(t, router, owner, projectID, dodID)
+	workItemPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, uint(workItem["id"].(float64)))
+	w = performRequest(router, "PUT", workItemPath+"/state", owner.Token, models.UpdateWorkItemStateRequest{State: models.WorkItemInProgress})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	ctrl.WaitBackground()
+	assert.Equal(t, 1, receiver.count())
+	message := chatMessages(receiver)[0]
+	assert.Contains(t, message["text"], "Work item blocked")
+	fields := message["blocks"].([]interface{})[2].(map[string]interface{})["fields"].([]interface{})
+	assert.Equal(t, "*Unmet required items*\nAcceptance criteria written", fields[1].(map[string]interface{})["text"])
+}
+
+func TestNotificationsDoNotHoldRequests(t *testing.T) {
+	ctrl := setupTestController()
+	router := setupTestRouterWithController(ctrl)
+	owner := registerTestUser(t, router, "chatslowowner")
+	projectID, dodID, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	release := make(chan struct{})
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer stalled.Close()
+	createTestChannel(t, router, owner, projectID, models.CreateNotificationChannelRequest{Name: "Stalled", Kind: chat.Slack, WebhookURL: stalled.URL, Events: []string{"*"}})
+
+	start := time.Now()
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/dods/%d/publish", dodID), owner.Token, models.PublishDoDRequest{Changelog: "First version"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	close(release)
+	ctrl.WaitBackground()
+}
@@ -0,0 +1,279 @@
+package tests
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"dod-backend/models"
+	"dod-backend/oidc"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const mockOIDCClientID = "dod-manager"
+
+// mockOIDCProvider is an in-process identity provider serving discovery,
+// JWKS and token endpoints. Authorize stands in for the user signing in.
+type mockOIDCProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu          sync.Mutex
+	codes       map[string]mockAuthorization
+	kid         string
+	jwksFetches int
+}
+
+type mockAuthorization struct {
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock := &mockOIDCProvider{key: key, codes: map[string]mockAuthorization{}, kid: "test-key"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 mock.URL,
+			"authorization_endpoint": mock.URL + "/authorize",
+			"token_endpoint":         mock.URL + "/token",
+			"jwks_uri":               mock.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		mock.mu.Lock()
+		mock.jwksFetches++
+		mock.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "test-key",
+				"kty": "RSA",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", mock.token)
+	mock.Server = httptest.NewServer(mux)
+	t.Cleanup(mock.Close)
+
+	return mock
+}
+
+// Authorize plays the user consenting at the provider: it returns the code
+// and state the provider would redirect back with.
+func (m *mockOIDCProvider) Authorize(t *testing.T, authorizationURL string, claims jwt.MapClaims) (code, state string) {
+	parsed, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Equal(t, mockOIDCClientID, query.Get("client_id"))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	code = fmt.Sprintf("code-%d", len(m.codes)+1)
+	m.codes[code] = mockAuthorization{
+		challenge: query.Get("code_challenge"),
+		nonce:     query.Get("nonce"),
+		claims:    claims,
+	}
+	return code, query.Get("state")
+}
+
+func (m *mockOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	m.mu.Lock()
+	authorization, ok := m.codes[r.Form.Get("code")]
+	delete(m.codes, r.Form.Get("code"))
+	kid := m.kid
+	m.mu.Unlock()
+
+	if !ok || oidc.CodeChallenge(r.Form.Get("code_verifier")) != authorization.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   m.URL,
+		"aud":   mockOIDCClientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": authorization.nonce,
+	}
+	for key, value := range authorization.claims {
+		claims[key] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	idToken, _ := token.SignedString(m.key)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func setupOIDCTestRouter(t *testing.T) (*gin.Engine, *mockOIDCProvider) {
+	mock := newMockOIDCProvider(t)
+
+	ctrl := setupTestController()
+	ctrl.OIDC = oidc.NewProvider(mock.URL, mockOIDCClientID, "", "http://localhost:3000/oidc/callback")
+	return setupTestRouterWithController(ctrl), mock
+}
+
+// startOIDCLogin returns the authorization URL and the login binding the
+// browser keeps.
+func startOIDCLogin(t *testing.T, router *gin.Engine) (string, string) {
+	w := performRequest(router, "GET", "/api/v1/auth/oidc/login", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response := decodeResponse(w)
+	return response["authorization_url"].(string), response["login_binding"].(string)
+}
+
+func TestOIDCLoginProvisionsUser(t *testing.T) {
+	router, mock := setupOIDCTestRouter(t)
+	email := fmt.Sprintf("sso%d@example.com", time.Now().UnixNano())
+	claims := jwt.MapClaims{"sub": "sso-" + email, "email": email, "email_verified": true}
+
+	authURL, binding := startOIDCLogin(t, router)
+	code, state := mock.Authorize(t, authURL, claims)
+	w := performRequest(router, "POST", "/api/v1/auth/oidc/callback", "", models.OIDCCallbackRequest{Code: code, State: state, LoginBinding: binding})
+	assert.Equal(t, http.StatusOK, w.Code)
+	response := decodeResponse(w)
+	user := response["user"].(map[string]interface{})
+	assert.Equal(t, email, user["email"])
+
+	w = performRequest(router, "GET", "/api/v1/projects/", response["token"].(string), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The state is single-use
+	w = performRequest(router, "POST", "/api/v1/auth/oidc/callback", "", models.OIDCCallbackRequest{Code: code, State: state, LoginBinding: binding})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Signing in again resolves the same account
+	authURL, binding = startOIDCLogin(t, router)
+	code, state = mock.Authorize(t, authURL, claims)
+	w = performRequest(router, "POST", "/api/v1/auth/oidc/callback", "", models.OIDCCallbackRequest{Code: code, State: state, LoginBinding: binding})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, user["id"], decodeResponse(w)["user"].(map[string]interface{})["id"])
+}
+
+func TestOIDCLoginLinksExistingAccount(t *testing.T) {
+	router, mock := setupOIDCTestRouter(t)
+	local := registerTestUser(t, router, "linked")
+
+	authURL, binding := startOIDCLogin(t, router)
+	code, state := mock.Authorize(t, authURL, jwt.MapClaims{"sub": "linked-subject", "email": local.Email, "email_verified": true})
+	w := performRequest(router, "POST", "/api/v1/auth/oidc/callback", "", models.OIDCCallbackRequest{Code: code, State: state, LoginBinding: binding})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(local.ID), decodeResponse(w)["user"].(map[string]interface{})["id"])
+}
+
+func TestOIDCLoginKeepsTwoFactor(t *testing.T) {
+	router, mock := setupOIDCTestRouter(t)
+	local := registerTestUser(t, router, "ssotwofactor")
+	_, recoveryCodes := enableTestTwoFactor(t, router, local)
+
+	authURL, binding := startOIDCLogin(t, router)
+	code, state := mock.Authorize(t, authURL, jwt.MapClaims{"sub": "twofactor-subject", "email": local.Email, "email_verified": true})
+	w := performRequest(router, "POST", "/api/v1/auth/oidc/callback", "", models.OIDCCallbackRequest{Code: code, State: state, LoginBinding: binding})
+	assert.Equal(t, http.StatusOK, w.Code)
+	response := decodeResponse(w)
+	assert.Equal(t, true, response["two_factor_required"])
+	assert.Nil(t, response["token"])
+
+	w = performRequest(router, "POST", "/api/v1/auth/2fa/verify", "", models.TwoFactorLoginRequest{ChallengeToken: response["challenge_token"].(string), Code: recoveryCodes[0].(string)})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(local.ID), decodeResponse(w)["user"].(map[string]interface{})["id"])
+}
+
+func TestOIDCUnknownKeysThrottleJWKSFetches(t *testing.T) {
+	router, mock := setupOIDCTestRouter(t)
+	mock.mu.Lock()
+	mock.kid = "forged-key"
+	mock.mu.Unlock()
+
+	for i := 0; i < 3; i++ {
+		authURL, binding := startOIDCLogin(t, router)
+		code, state := mock.Authorize(t, authURL, jwt.MapClaims{"sub": "forged", "email": "forged@example.com", "email_verified": true})
+		w := performRequest(router, "POST", "/api/v1/auth/oidc/callback", "", models.OIDCCallbackRequest{Code: code, State: state, LoginBinding: binding})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	assert.Equal(t, 1, mock.jwksFetches)
+}
+
+func TestOIDCLoginRejectsUnverifiedEmail(t *testing.T) {
+	router, mock := setupOIDCTestRouter(t)
+	local := registerTestUser(t, router, "unverified")
+
+	authURL, binding := startOIDCLogin(t, router)
+	code, state := mock.Authorize(t, authURL, jwt.MapClaims{"sub": "unverified-subject", "email": local.Email, "email_verified": false})
+	w := performRequest(router, "POST", "/api/v1/auth/oidc/callback", "", models.OIDCCallbackRequest{Code: code, State: state, LoginBinding: binding})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestOIDCLoginRequiresMatchingVerifier(t *testing.T) {
+	router, mock := setupOIDCTestRouter(t)
+
+	// A code obtained for one login cannot be redeemed with another login's state
+	authURL, _ := startOIDCLogin(t, router)
+	code, _ := mock.Authorize(t, authURL, jwt.MapClaims{"sub": "mixed", "email": "mixed@example.com", "email_verified": true})
+	authURL, binding := startOIDCLogin(t, router)
+	_, otherState := mock.Authorize(t, authURL, jwt.MapClaims{})
+
+	w := performRequest(router, "POST", "/api/v1/auth/oidc/callback", "", models.OIDCCallbackRequest{Code: code, State: otherState, LoginBinding: binding})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestOIDCLoginBoundToBrowser(t *testing.T) {
+	router, mock := setupOIDCTestRouter(t)
+
+	// The attacker starts a login and keeps the code and state...
+	authURL, _ := startOIDCLogin(t, router)
+	code, state := mock.Authorize(t, authURL, jwt.MapClaims{"sub": "attacker", "email": "attacker@example.com", "email_verified": true})
+
+	// ...which the victim's browser cannot complete with its own login
+	_, victimBinding := startOIDCLogin(t, router)
+	w := performRequest(router, "POST", "/api/v1/auth/oidc/callback", "", models.OIDCCallbackRequest{Code: code, State: state, LoginBinding: victimBinding})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "This login was started in another browser", decodeResponse(w)["error"])
+
+	w = performRequest(router, "POST", "/api/v1/auth/oidc/callback", "", models.OIDCCallbackRequest{Code: code, State: state})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestOIDCLoginNotConfigured(t *testing.T) {
+	router := setupTestRouter()
+
+	w := performRequest(router, "GET", "/api/v1/auth/oidc/login", "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"dod-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrganizationBaselineInherited(t *testing.T) {
+	router := setupTestRouter()
+	admin := registerTestUser(t, router, "baselineadmin")
+	orgID := createTestOrganization(t, router, admin, "Initech")
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/organizations/%d/dods", orgID), admin.Token, models.CreateOrganizationDoDRequest{Title: "Company baseline"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	baselineID := uint(decodeResponse(w)["dod"].(map[string]interface{})["id"].(float64))
+
+	itemsPath := fmt.Sprintf("/api/v1/organizations/%d/dods/%d/items", orgID, baselineID)
+	w = performRequest(router, "POST", itemsPath, admin.Token, models.CreateDoDItemRequest{Title: "Security review", IsRequired: true, Order: 1})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	lockedID := uint(decodeResponse(w)["item"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "POST", itemsPath, admin.Token, models.CreateDoDItemRequest{Title: "Accessibility audit", IsRequired: false, Order: 2})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	optionalID := uint(decodeResponse(w)["item"].(map[string]interface{})["id"].(float64))
+
+	// A project of the organization with its own DoD
+	w = performRequest(router, "POST", "/api/v1/projects/", admin.Token, models.CreateProjectRequest{Name: "Initech API", OrganizationID: &orgID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	projectID := uint(decodeResponse(w)["project"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "POST", "/api/v1/dods/", admin.Token, models.CreateDoDRequest{Title: "API DoD", ProjectID: projectID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	dodID := uint(decodeResponse(w)["dod"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/dods/%d/items", dodID), admin.Token, models.CreateDoDItemRequest{Title: "Contract tests", IsRequired: true})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	ownID := uint(decodeResponse(w)["item"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/effective-dod", projectID), admin.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	items := decodeResponse(w)["items"].([]interface{})
+	assert.Len(t, items, 3)
+	first := items[0].(map[string]interface{})
+	assert.Equal(t, float64(lockedID), first["id"])
+	assert.Equal(t, true, first["inherited"])
+	assert.Equal(t, true, first["locked"])
+	assert.Equal(t, false, items[2].(map[string]interface{})["inherited"])
+
+	// Projects cannot weaken the baseline
+	notApplicablePath := fmt.Sprintf("/api/v1/projects/%d/inherited-items/%%d/not-applicable", projectID)
+	w = performRequest(router, "PUT", fmt.Sprintf(notApplicablePath, lockedID), admin.Token, models.MarkNotApplicableRequest{Reason: "No time"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, "PATCH", fmt.Sprintf("/api/v1/dods/%d/items/%d", baselineID, lockedID), admin.Token, models.UpdateDoDItemRequest{})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "PUT", fmt.Sprintf(notApplicablePath, ownID), admin.Token, models.MarkNotApplicableRequest{Reason: "Not inherited"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performRequest(router, "PUT", fmt.Sprintf(notApplicablePath, optionalID), admin.Token, models.MarkNotApplicableRequest{Reason: "Backend only"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/effective-dod?dod_id=%d", projectID, dodID), admin.Token, nil)
+	second := decodeResponse(w)["items"].([]interface{})[1].(map[string]interface{})
+	assert.Equal(t, true, second["not_applicable"])
+	assert.Equal(t, "Backend only", second["not_applicable_reason"])
+
+	// Work items are judged against the locked baseline items too
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), admin.Token, models.CreateWorkItemRequest{Title: "Rate limits", DoDID: &dodID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	response := decodeResponse(w)
+	workItemID := uint(response["work_item"].(map[string]interface{})["id"].(float64))
+	status := response["work_item"].(map[string]interface{})["status"].(map[string]interface{})
+	assert.Equal(t, float64(2), status["required_total"])
+	assert.Len(t, status["criteria"], 2)
+
+	checked := true
+	checkPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d/checks/", projectID, workItemID)
+	w = performRequest(router, "PUT", fmt.Sprintf("%s%d", checkPath, ownID), admin.Token, models.CheckDoDItemRequest{Checked: &checked})
+	assert.False(t, workItemDone(t, decodeResponse(w)))
+
+	w = performRequest(router, "PUT", fmt.Sprintf("%s%d", checkPath, optionalID), admin.Token, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performRequest(router, "PUT", fmt.Sprintf("%s%d", checkPath, lockedID), admin.Token, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, workItemDone(t, decodeResponse(w)))
+}
+
+func TestPinnedWorkItemsKeepPublishedBaseline(t *testing.T) {
+	router := setupTestRouter()
+	admin := registerTestUser(t, router, "pinnedbaselineadmin")
+	orgID := createTestOrganization(t, router, admin, "Vandelay")
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/organizations/%d/dods", orgID), admin.Token, models.CreateOrganizationDoDRequest{Title: "Company baseline"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	baselineID := uint(decodeResponse(w)["dod"].(map[string]interface{})["id"].(float64))
+	itemsPath := fmt.Sprintf("/api/v1/organizations/%d/dods/%d/items", orgID, baselineID)
+	w = performRequest(router, "POST", itemsPath, admin.Token, models.CreateDoDItemRequest{Title: "Security review", IsRequired: true})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/projects/", admin.Token, models.CreateProjectRequest{Name: "Vandelay Imports", OrganizationID: &orgID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	projectID := uint(decodeResponse(w)["project"].(map[string]interface{})["id"].(float64))
+	w = performRequest(router, "POST", "/api/v1/dods/", admin.Token, models.CreateDoDRequest{Title: "Imports DoD", ProjectID: projectID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	dodID := uint(decodeResponse(w)["dod"].(map[string]interface{})["id"].(float64))
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/dods/%d/items", dodID), admin.Token, models.CreateDoDItemRequest{Title: "Contract tests", IsRequired: true})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	publishPath := fmt.Sprintf("/api/v1/dods/%d/publish", dodID)
+	w = performRequest(router, "POST", publishPath, admin.Token, models.PublishDoDRequest{Changelog: "First version"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Len(t, decodeResponse(w)["revision"].(map[string]interface{})["inherited"], 1)
+
+	pinned := startTestWorkItem(t, router, admin, projectID, createTestWorkItem(t, router, admin, projectID, dodID))
+	pinnedPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, uint(pinned["id"].(float64)))
+	assert.Equal(t, float64(2), workItemStatus(pinned)["required_total"])
+
+	// Changes to the baseline only reach work items once published again
+	w = performRequest(router, "POST", itemsPath, admin.Token, models.CreateDoDItemRequest{Title: "Privacy review", IsRequired: true})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = performRequest(router, "GET", pinnedPath, admin.Token, nil)
+	assert.Equal(t, float64(2), workItemStatus(decodeResponse(w)["work_item"].(map[string]interface{}))["required_total"])
+
+	w = performRequest(router, "POST", publishPath, admin.Token, models.PublishDoDRequest{Changelog: "New company baseline"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	current := createTestWorkItem(t, router, admin, projectID, dodID)
+	assert.Equal(t, float64(3), workItemStatus(current)["required_total"])
+
+	w = performRequest(router, "GET", pinnedPath, admin.Token, nil)
+	assert.Equal(t, float64(2), workItemStatus(decodeResponse(w)["work_item"].(map[string]interface{}))["required_total"])
+}
+
+func TestOrganizationBaselineManagedByAdmins(t *testing.T) {
+	router := setupTestRouter()
+	admin := registerTestUser(t, router, "baselineowner")
+	member := registerTestUser(t, router, "baselinemember")
+	orgID := createTestOrganization(t, router, admin, "Hooli")
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/organizations/%d/members", orgID), admin.Token, models.AddOrganizationMemberRequest{Email: member.Email, Role: "member"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/organizations/%d/dods", orgID), member.Token, models.CreateOrganizationDoDRequest{Title: "Weak baseline"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/organizations/%d/dods", orgID), admin.Token, models.CreateOrganizationDoDRequest{Title: "Baseline"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	baselineID := uint(decodeResponse(w)["dod"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/organizations/%d/dods", orgID), member.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, decodeResponse(w)["dods"].([]interface{}), 1)
+
+	// Inactive baselines are not inherited
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/organizations/%d/dods/%d/items", orgID, baselineID), admin.Token, models.CreateDoDItemRequest{Title: "Changelog", IsRequired: true})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/projects/", member.Token, models.CreateProjectRequest{Name: "Hooli Chat", OrganizationID: &orgID})
+	projectID := uint(decodeResponse(w)["project"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/effective-dod", projectID), member.Token, nil)
+	assert.Len(t, decodeResponse(w)["items"].([]interface{}), 1)
+
+	inactive := false
+	w = performRequest(router, "PATCH", fmt.Sprintf("/api/v1/organizations/%d/dods/%d", orgID, baselineID), admin.Token, models.UpdateDoDRequest{IsActive: &inactive})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/effective-dod", projectID), member.Token, nil)
+	assert.Len(t, decodeResponse(w)["items"].([]interface{}), 0)
+}
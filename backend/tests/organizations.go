@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestOrganization(t *testing.T, router *gin.Engine, admin testUser, name string) uint {
+	w := performRequest(router, "POST", "/api/v1/organizations/", admin.Token, models.CreateOrganizationRequest{Name: name})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	return uint(decodeResponse(w)["organization"].(map[string]interface{})["id"].(float64))
+}
+
+func TestOrganizationAdminManagesProjects(t *testing.T) {
+	router := setupTestRouter()
+	admin := registerTestUser(t, router, "orgadmin")
+	member := registerTestUser(t, router, "orgmember")
+	orgID := createTestOrganization(t, router, admin, "Acme")
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/organizations/%d/members", orgID), admin.Token, models.AddOrganizationMemberRequest{Email: member.Email, Role: "member"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// A member creates a project in the organization, without adding the admin
+	w = performRequest(router, "POST", "/api/v1/projects/", member.Token, models.CreateProjectRequest{Name: "Acme Web", OrganizationID: &orgID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	projectID := uint(decodeResponse(w)["project"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "POST", "/api/v1/projects/", member.Token, models.CreateProjectRequest{Name: "Side project"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// The admin sees and manages it
+	w = performRequest(router, "GET", "/api/v1/projects/", admin.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	projects := decodeResponse(w)["projects"].([]interface{})
+	assert.Len(t, projects, 1)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/permissions", projectID), admin.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "org_admin", decodeResponse(w)["role"])
+
+	name := "Acme Website"
+	w = performRequest(router, "PATCH", fmt.Sprintf("/api/v1/projects/%d", projectID), admin.Token, models.UpdateProjectRequest{Name: &name})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Members only see their own projects, filterable by organization
+	w = performRequest(router, "GET", "/api/v1/projects/", member.Token, nil)
+	assert.Len(t, decodeResponse(w)["projects"].([]interface{}), 2)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/?organization_id=%d", orgID), member.Token, nil)
+	assert.Len(t, decodeResponse(w)["projects"].([]interface{}), 1)
+
+	// Non-empty organizations cannot be deleted
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/organizations/%d", orgID), admin.Token, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestOrganizationMemberAccess(t *testing.T) {
+	router := setupTestRouter()
+	admin := registerTestUser(t, router, "orgowner")
+	member := registerTestUser(t, router, "orgreader")
+	outsider := registerTestUser(t, router, "orgoutsider")
+	orgID := createTestOrganization(t, router, admin, "Globex")
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/organizations/%d/members", orgID), admin.Token, models.AddOrganizationMemberRequest{Email: member.Email, Role: "member"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/projects/", admin.Token, models.CreateProjectRequest{Name: "Globex API", OrganizationID: &orgID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	projectID := uint(decodeResponse(w)["project"].(map[string]interface{})["id"].(float64))
+
+	// Membership alone grants nothing on projects
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d", projectID), member.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "PATCH", fmt.Sprintf("/api/v1/organizations/%d", orgID), member.Token, models.UpdateOrganizationRequest{})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/organizations/%d", orgID), outsider.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/projects/", outsider.Token, models.CreateProjectRequest{Name: "Intruder", OrganizationID: &orgID})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// The last admin can neither leave nor be demoted
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/organizations/%d/members/%d", orgID, admin.ID), admin.Token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, "PATCH", fmt.Sprintf("/api/v1/organizations/%d/members/%d", orgID, admin.ID), admin.Token, models.UpdateOrganizationMemberRequest{Role: "member"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Members may leave on their own
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/organizations/%d/members/%d", orgID, member.ID), member.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", "/api/v1/organizations/", member.Token, nil)
+	assert.Len(t, decodeResponse(w)["organizations"].([]interface{}), 0)
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"dod-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListAndUpdateParticipants(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "partowner")
+	editor := registerTestUser(t, router, "parteditor")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, editor, "editor")
+
+	w := performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/participants", projectID), editor.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, decodeResponse(w)["participants"], 2)
+
+	rolePath := fmt.Sprintf("/api/v1/projects/%d/participants/%d", projectID, editor.ID)
+	w = performRequest(router, "PATCH", rolePath, editor.Token, models.UpdateParticipantRoleRequest{Role: "viewer"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "PATCH", rolePath, owner.Token, models.UpdateParticipantRoleRequest{Role: "viewer"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "viewer", decodeResponse(w)["participant"].(map[string]interface{})["role"])
+
+	// The demoted viewer can no longer create DoDs
+	w = performRequest(router, "POST", "/api/v1/dods/", editor.Token, models.CreateDoDRequest{Title: "Nope", ProjectID: projectID})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRemoveParticipantAndLeave(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "rmowner")
+	editor := registerTestUser(t, router, "rmeditor")
+	viewer := registerTestUser(t, router, "rmviewer")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, editor, "editor")
+	addTestParticipant(t, router, owner, projectID, viewer, "viewer")
+
+	w := performRequest(router, "DELETE", fmt.Sprintf("/api/v1/projects/%d/participants/%d", projectID, owner.ID), owner.Token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/projects/%d/participants/%d", projectID, editor.ID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d", projectID), editor.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/leave", projectID), viewer.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The owner cannot leave a project without transferring it first
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/leave", projectID), owner.Token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestTransferOwnership(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "xferowner")
+	editor := registerTestUser(t, router, "xfereditor")
+	outsider := registerTestUser(t, router, "xferoutsider")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, editor, "editor")
+
+	path := fmt.Sprintf("/api/v1/projects/%d/transfer-ownership", projectID)
+	w := performRequest(router, "POST", path, owner.Token, models.TransferOwnershipRequest{UserID: outsider.ID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, "POST", path, editor.Token, models.TransferOwnershipRequest{UserID: editor.ID})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "POST", path, owner.Token, models.TransferOwnershipRequest{UserID: editor.ID})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(editor.ID), decodeResponse(w)["project"].(map[string]interface{})["owner_id"])
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/participants", projectID), owner.Token, nil)
+	roles := map[float64]string{}
+	for _, p := range decodeResponse(w)["participants"].([]interface{}) {
+		participant := p.(map[string]interface{})
+		roles[participant["user_id"].(float64)] = participant["role"].(string)
+	}
+	assert.Equal(t, "owner", roles[float64(editor.ID)])
+	assert.Equal(t, "editor", roles[float64(owner.ID)])
+
+	// The previous owner can now leave
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/leave", projectID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"dod-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForgotAndResetPassword(t *testing.T) {
+	router, mailer := setupTestRouterWithMailer()
+	user := registerTestUser(t, router, "forgetful")
+
+	w := performRequest(router, "POST", "/api/v1/auth/password/forgot", "", models.ForgotPasswordRequest{Email: user.Email})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	msg, ok := mailer.Last(user.Email)
+	assert.True(t, ok)
+	token := tokenFromEmail(t, msg.Body, "token")
+
+	w = performRequest(router, "POST", "/api/v1/auth/password/reset", "", models.ResetPasswordRequest{Token: token, NewPassword: "new-password"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The link is single-use
+	w = performRequest(router, "POST", "/api/v1/auth/password/reset", "", models.ResetPasswordRequest{Token: token, NewPassword: "another-password"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Sessions opened with the old password are gone
+	w = performRequest(router, "GET", "/api/v1/projects/", user.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "new-password"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	router, mailer := setupTestRouterWithMailer()
+
+	w := performRequest(router, "POST", "/api/v1/auth/password/forgot", "", models.ForgotPasswordRequest{Email: "nobody@example.com"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, mailer.Messages)
+}
+
+func TestChangePassword(t *testing.T) {
+	router := setupTestRouter()
+	user := registerTestUser(t, router, "changer")
+
+	w := performRequest(router, "PUT", "/api/v1/auth/password", user.Token, models.ChangePasswordRequest{CurrentPassword: "wrong-password", NewPassword: "new-password"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequest(router, "PUT", "/api/v1/auth/password", user.Token, models.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "new-password"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	newToken := decodeResponse(w)["token"].(string)
+
+	// The old tokens are invalidated, the caller keeps working with the new one
+	w = performRequest(router, "GET", "/api/v1/projects/", user.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: user.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequest(router, "GET", "/api/v1/projects/", newToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func addTestParticipant(t *testing.T, router *gin.Engine, owner testUser, projectID uint, user testUser, role string) {
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/participants", projectID), owner.Token, models.AddParticipantRequest{
+		Email: user.Email,
+		Role:  role,
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestGetProject(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "getowner")
+	outsider := registerTestUser(t, router, "getoutsider")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	w := performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d", projectID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	project := decodeResponse(w)["project"].(map[string]interface{})
+	assert.Len(t, project["dods"], 1)
+	assert.Len(t, project["participants"], 1)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d", projectID), outsider.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestUpdateProject(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "updowner")
+	viewer := registerTestUser(t, router, "updviewer")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, viewer, "viewer")
+
+	name := "Renamed Project"
+	w := performRequest(router, "PATCH", fmt.Sprintf("/api/v1/projects/%d", projectID), viewer.Token, models.UpdateProjectRequest{Name: &name})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "PATCH", fmt.Sprintf("/api/v1/projects/%d", projectID), owner.Token, models.UpdateProjectRequest{Name: &name})
+	assert.Equal(t, http.StatusOK, w.Code)
+	project := decodeResponse(w)["project"].(map[string]interface{})
+	assert.Equal(t, name, project["name"])
+}
+
+func TestDeleteProject(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "delowner")
+	editor := registerTestUser(t, router, "deleditor")
+	projectID, dodID, _, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, editor, "editor")
+
+	w := performRequest(router, "DELETE", fmt.Sprintf("/api/v1/projects/%d", projectID), editor.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/projects/%d", projectID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// DoDs and participants go away with the project
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/dods/%d", dodID), owner.Token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performRequest(router, "GET", "/api/v1/projects/", editor.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, decodeResponse(w)["projects"])
+}
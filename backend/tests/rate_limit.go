@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"dod-backend/models"
+	"dod-backend/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	limiter := ratelimit.New(ratelimit.PerMinute(2), ratelimit.NewMemoryStore())
+	limiter.Now = func() time.Time { return now }
+
+	ok, _ := limiter.Allow("a")
+	assert.True(t, ok)
+	ok, _ = limiter.Allow("a")
+	assert.True(t, ok)
+	ok, retryAfter := limiter.Allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, 30*time.Second, retryAfter)
+
+	// Keys have their own buckets
+	ok, _ = limiter.Allow("b")
+	assert.True(t, ok)
+
+	now = now.Add(30 * time.Second)
+	ok, _ = limiter.Allow("a")
+	assert.True(t, ok)
+	ok, _ = limiter.Allow("a")
+	assert.False(t, ok)
+}
+
+func TestLoginRateLimitedPerIP(t *testing.T) {
+	ctrl := setupTestController()
+	ctrl.IPLimiter = ratelimit.New(ratelimit.PerMinute(3), ratelimit.NewMemoryStore())
+	router := setupTestRouterWithController(ctrl)
+
+	for i := 0; i < 3; i++ {
+		w := performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: "nobody@example.com", Password: "password123"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	w := performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: "nobody@example.com", Password: "password123"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "20", w.Header().Get("Retry-After"))
+
+	// Other endpoints keep their own budget
+	registerTestUser(t, router, "ratelimited")
+}
+
+func TestLoginRateLimitedPerAccount(t *testing.T) {
+	ctrl := setupTestController()
+	ctrl.AccountLimiter = ratelimit.New(ratelimit.PerMinute(2), ratelimit.NewMemoryStore())
+	router := setupTestRouterWithController(ctrl)
+	user := registerTestUser(t, router, "accountlimit")
+
+	for i := 0; i < 2; i++ {
+		w := performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "wrongpassword"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	// Even the right password is not evaluated any more
+	w := performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
+
+func TestLoginProgressiveDelay(t *testing.T) {
+	ctrl := setupTestController()
+	ctrl.Cfg.LockoutThreshold = 10
+	ctrl.Cfg.LockoutDuration = time.Hour
+	router := setupTestRouterWithController(ctrl)
+	user := registerTestUser(t, router, "slowdown")
+
+	for i := 0; i < 3; i++ {
+		w := performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "wrongpassword"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	w := performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	time.Sleep(time.Second)
+	w = performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A successful login starts over
+	var stored models.User
+	ctrl.DB.First(&stored, user.ID)
+	assert.Equal(t, 0, stored.FailedLogins)
+	assert.Nil(t, stored.LockedUntil)
+}
+
+func TestLoginDelayDoesNotOverflow(t *testing.T) {
+	ctrl := setupTestController()
+	ctrl.Cfg.LockoutThreshold = 1000
+	ctrl.Cfg.LockoutDuration = time.Hour
+	router := setupTestRouterWithController(ctrl)
+	user := registerTestUser(t, router, "manyfailures")
+
+	// Far past 64 doublings, the delay still holds at its cap
+	ctrl.DB.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("failed_logins", 100)
+	w := performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "wrongpassword"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "3600", w.Header().Get("Retry-After"))
+}
+
+func TestAccountLockout(t *testing.T) {
+	ctrl := setupTestController()
+	ctrl.Cfg.LockoutThreshold = 3
+	ctrl.Cfg.LockoutDuration = time.Hour
+	router := setupTestRouterWithController(ctrl)
+	user := registerTestUser(t, router, "lockedout")
+
+	for i := 0; i < 3; i++ {
+		w := performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "wrongpassword"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	w := performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "3600", w.Header().Get("Retry-After"))
+
+	var entries []models.AuditLog
+	ctrl.DB.Where("action = ? AND user_id = ?", models.AuditAccountLocked, user.ID).Find(&entries)
+	assert.Len(t, entries, 1)
+
+	// Changing the password lifts the lockout
+	w = performRequest(router, "PUT", "/api/v1/auth/password", user.Token, models.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "newpassword123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "newpassword123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
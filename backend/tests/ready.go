@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"dod-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefinitionOfReadyGatesProgress(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "readyowner")
+	projectID, dodID, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	w := performRequest(router, "POST", "/api/v1/dods/", owner.Token, models.CreateDoDRequest{Title: "Ready", ProjectID: projectID, Kind: models.DoDKindReady})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	readyID := uint(decodeResponse(w)["dod"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/dods/%d/items", readyID), owner.Token, models.CreateDoDItemRequest{Title: "Acceptance criteria written", IsRequired: true, Order: 1})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	readyItemID := uint(decodeResponse(w)["item"].(map[string]interface{})["id"].(float64))
+
+	// DoDs can be listed by kind
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/dods?kind=ready", projectID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, decodeResponse(w)["dods"], 1)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/dods?kind=done", projectID), owner.Token, nil)
+	assert.Len(t, decodeResponse(w)["dods"], 1)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/dods?kind=later", projectID), owner.Token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// A ready checklist is not a DoD work items are judged against
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), owner.Token, models.CreateWorkItemRequest{Title: "Story", DoDID: &readyID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	workItem := createTestWorkItem(t, router, owner, projectID, dodID)
+	assert.Equal(t, models.WorkItemTodo, workItem["state"])
+	workItemPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, uint(workItem["id"].(float64)))
+
+	w = performRequest(router, "GET", workItemPath, owner.Token, nil)
+	readiness := decodeResponse(w)["work_item"].(map[string]interface{})["readiness"].(map[string]interface{})
+	assert.Equal(t, false, readiness["done"])
+
+	// Entering progress requires the ready checklist
+	w = performRequest(router, "PUT", workItemPath+"/state", owner.Token, models.UpdateWorkItemStateRequest{State: models.WorkItemInProgress})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Len(t, decodeResponse(w)["unmet_required"], 1)
+
+	// Skipping progress does not get around it
+	w = performRequest(router, "PUT", workItemPath+"/state", owner.Token, models.UpdateWorkItemStateRequest{State: models.WorkItemDone})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	checked := true
+	w = performRequest(router, "PUT", fmt.Sprintf("%s/checks/%d", workItemPath, readyItemID), owner.Token, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusOK, w.Code)
+	updated := decodeResponse(w)["work_item"].(map[string]interface{})
+	assert.Equal(t, true, updated["readiness"].(map[string]interface{})["done"])
+	assert.Len(t, workItemStatus(updated)["criteria"], 2)
+
+	w = performRequest(router, "PUT", workItemPath+"/state", owner.Token, models.UpdateWorkItemStateRequest{State: models.WorkItemInProgress})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.WorkItemInProgress, decodeResponse(w)["work_item"].(map[string]interface{})["state"])
+
+	// A DoD with work items attached cannot become a ready checklist
+	kind := models.DoDKindReady
+	w = performRequest(router, "PATCH", fmt.Sprintf("/api/v1/dods/%d", dodID), owner.Token, models.UpdateDoDRequest{Kind: &kind})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestProjectsWithoutReadyChecklistEnterProgressFreely(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "freeowner")
+	projectID, dodID, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	workItem := createTestWorkItem(t, router, owner, projectID, dodID)
+	assert.Nil(t, workItem["readiness"])
+
+	workItemPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, uint(workItem["id"].(float64)))
+	w := performRequest(router, "PUT", workItemPath+"/state", owner.Token, models.UpdateWorkItemStateRequest{State: models.WorkItemInProgress})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "PUT", workItemPath+"/state", owner.Token, models.UpdateWorkItemStateRequest{State: "blocked"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestOrganizationReadyBaselineIsInherited(t *testing.T) {
+	router := setupTestRouter()
+	admin := registerTestUser(t, router, "readyadmin")
+	organizationID := createTestOrganization(t, router, admin, "Ready Org")
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/organizations/%d/dods", organizationID), admin.Token, models.CreateOrganizationDoDRequest{Title: "Org Ready", Kind: models.DoDKindReady})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	orgDoDID := uint(decodeResponse(w)["dod"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/organizations/%d/dods/%d/items", organizationID, orgDoDID), admin.Token, models.CreateDoDItemRequest{Title: "Estimated", IsRequired: true, Order: 1})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	projectID, dodID, _, _ := createTestProjectWithDoD(t, router, admin)
+	w = performRequest(router, "PUT", fmt.Sprintf("/api/v1/projects/%d/organization", projectID), admin.Token, models.MoveProjectRequest{OrganizationID: organizationID})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The ready baseline stays out of the Definition of Done...
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/effective-dod", projectID), admin.Token, nil)
+	assert.Len(t, decodeResponse(w)["items"], 2)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/effective-dod?kind=ready", projectID), admin.Token, nil)
+	assert.Len(t, decodeResponse(w)["items"], 1)
+
+	// ...but gates progress
+	workItem := createTestWorkItem(t, router, admin, projectID, dodID)
+	assert.Len(t, workItemStatus(workItem)["criteria"], 2)
+
+	workItemPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, uint(workItem["id"].(float64)))
+	w = performRequest(router, "PUT", workItemPath+"/state", admin.Token, models.UpdateWorkItemStateRequest{State: models.WorkItemInProgress})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
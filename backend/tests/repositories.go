@@ -0,0 +1,336 @@
+package tests
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"dod-backend/models"
+	"dod-backend/scm"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type postedStatus struct {
+	Path  string
+	Auth  string
+	State string
+	Body  string
+}
+
+// fakeCodeHost records the commit statuses posted to it, the GitHub way
+// (JSON) or the GitLab way (form).
+type fakeCodeHost struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []postedStatus
+}
+
+func newFakeCodeHost() *fakeCodeHost {
+	host := &fakeCodeHost{}
+	host.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := postedStatus{Path: r.URL.EscapedPath(), Auth: r.Header.Get("Authorization") + r.Header.Get("PRIVATE-TOKEN")}
+		if r.Header.Get("Content-Type") == "application/json" {
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			status.State, status.Body = body["state"], body["description"]
+		} else {
+			r.ParseForm()
+			status.State, status.Body = r.PostForm.Get("state"), r.PostForm.Get("description")
+		}
+
+		host.mu.Lock()
+		host.statuses = append(host.statuses, status)
+		host.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	return host
+}
+
+func (h *fakeCodeHost) last() postedStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.statuses) == 0 {
+		return postedStatus{}
+	}
+	return h.statuses[len(h.statuses)-1]
+}
+
+var testDeliveries int64
+
+// sendTestWebhook gives each webhook its own delivery ID, unless set in the
+// headers.
+func sendTestWebhook(router *gin.Engine, repositoryID uint, headers map[string]string, payload string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/repositories/%d/webhook", repositoryID), strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	delivery := fmt.Sprintf("delivery-%d", atomic.AddInt64(&testDeliveries, 1))
+	req.Header.Set("X-GitHub-Delivery", delivery)
+	req.Header.Set("X-Gitlab-Event-UUID", delivery)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func githubTestWebhook(router *gin.Engine, repositoryID uint, secret, kind, payload string) *httptest.ResponseRecorder {
+	return sendTestWebhook(router, repositoryID, map[string]string{
+		"X-GitHub-Event":      kind,
+		"X-Hub-Signature-256": scm.Sign(secret, []byte(payload)),
+	}, payload)
+}
+
+func connectTestRepository(t *testing.T, router *gin.Engine, owner testUser, projectID uint, req models.CreateRepositoryRequest) uint {
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/repositories", projectID), owner.Token, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	response := decodeResponse(w)
+	repositoryID := uint(response["repository"].(map[string]interface{})["id"].(float64))
+	assert.Equal(t, fmt.Sprintf("/api/v1/repositories/%d/webhook", repositoryID), response["webhook_url"])
+	return repositoryID
+}
+
+const testGitHubPullRequest = `{
+  "action": "%s",
+  "pull_request": {"number": 7, "title": "Checkout page", "state": "open", "merged": false,
+    "html_url": "https://github.com/acme/shop/pull/7", "head": {"ref": "feature/shop-42-checkout", "sha": "%s"}},
+  "review": {"state": "%s", "user": {"login": "alice"}},
+  "repository": {"full_name": "acme/shop"}
+}`
+
+func TestWebhookReferences(t *testing.T) {
+	assert.Equal(t, []string{"SHOP-42", "OPS-7"}, scm.References("feature/shop-42-checkout", "SHOP-42 and ops-7"))
+	assert.Empty(t, scm.References("main", "Fix typo"))
+
+	// GitLab signing tokens follow Standard Webhooks
+	standardWebhook := func(timestamp time.Time) http.Header {
+		header := http.Header{}
+		unix := strconv.FormatInt(timestamp.Unix(), 10)
+		header.Set("Webhook-Id", "msg_1")
+		header.Set("Webhook-Timestamp", unix)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte("msg_1." + unix + ".{}"))
+		header.Set("Webhook-Signature", "v1,c2lnbmF0dXJl v1,"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+		return header
+	}
+	header := standardWebhook(time.Now())
+	assert.NoError(t, scm.Verify(scm.GitLab, "whsec_c2VjcmV0", header, []byte(`{}`)))
+	assert.Equal(t, scm.ErrSignature, scm.Verify(scm.GitLab, "whsec_c2VjcmV0", header, []byte(`{"tampered": true}`)))
+	assert.Equal(t, "msg_1", scm.DeliveryID(scm.GitLab, header))
+
+	// Captured webhooks cannot be replayed later
+	assert.Equal(t, scm.ErrTimestamp, scm.Verify(scm.GitLab, "whsec_c2VjcmV0", standardWebhook(time.Now().Add(-10*time.Minute)), []byte(`{}`)))
+	assert.Equal(t, scm.ErrTimestamp, scm.Verify(scm.GitLab, "whsec_c2VjcmV0", standardWebhook(time.Now().Add(10*time.Minute)), []byte(`{}`)))
+
+	header = http.Header{}
+	header.Set("X-Gitlab-Token", "gitlab-webhook-token")
+	assert.NoError(t, scm.Verify(scm.GitLab, "gitlab-webhook-token", header, []byte(`{}`)))
+	assert.Equal(t, scm.ErrSignature, scm.Verify(scm.GitLab, "another-token", header, []byte(`{}`)))
+}
+
+func TestGitHubPullRequestTicksReview(t *testing.T) {
+	ctrl := setupTestController()
+	router := setupTestRouterWithController(ctrl)
+	owner := registerTestUser(t, router, "scmowner")
+	editor := registerTestUser(t, router, "scmeditor")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, editor, "editor")
+
+	review := models.AutoCheckReview
+	setTestVerification(t, router, owner, dodID, requiredItemID, models.UpdateDoDItemRequest{AutoCheck: &review})
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), owner.Token, models.CreateWorkItemRequest{Title: "Checkout page", ExternalRef: "SHOP-42", DoDID: &dodID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	workItemID := uint(decodeResponse(w)["work_item"].(map[string]interface{})["id"].(float64))
+	workItemPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, workItemID)
+
+	host := newFakeCodeHost()
+	defer host.Close()
+
+	secret := "github-webhook-secret"
+	repositoryReq := models.CreateRepositoryRequest{Provider: scm.GitHub, FullName: "acme/shop", APIURL: host.URL, WebhookSecret: secret, AccessToken: "github-token"}
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/repositories", projectID), editor.Token, repositoryReq)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// The access token only goes to the allowed code hosts
+	internal := repositoryReq
+	internal.APIURL = "http://169.254.169.254/latest"
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/repositories", projectID), owner.Token, internal)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	repositoryID := connectTestRepository(t, router, owner, projectID, repositoryReq)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/repositories", projectID), owner.Token, nil)
+	repository := decodeResponse(w)["repositories"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, true, repository["has_access_token"])
+	assert.NotContains(t, repository, "webhook_secret")
+
+	receiver := newWebhookReceiver()
+	defer receiver.Close()
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/webhooks", projectID), owner.Token, models.CreateWebhookRequest{URL: receiver.URL, Events: []string{models.EventWorkItemDone}})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	deliveriesPath := fmt.Sprintf("/api/v1/projects/%d/webhooks/%d/deliveries", projectID, uint(decodeResponse(w)["webhook"].(map[string]interface{})["id"].(float64)))
+	doneEvents := func() int {
+		w := performRequest(router, "GET", deliveriesPath, owner.Token, nil)
+		return len(decodeResponse(w)["deliveries"].([]interface{}))
+	}
+
+	opened := fmt.Sprintf(testGitHubPullRequest, "opened", "abc123", "")
+	w = githubTestWebhook(router, repositoryID, "wrong-secret", "pull_request", opened)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Linked through the branch name, the DoD is not met yet
+	delivered := map[string]string{"X-GitHub-Event": "pull_request", "X-Hub-Signature-256": scm.Sign(secret, []byte(opened)), "X-GitHub-Delivery": "72d3162e-cc78-11e3-81ab-4c9367dc0958"}
+	w = sendTestWebhook(router, repositoryID, delivered, opened)
+	assert.Equal(t, http.StatusOK, w.Code)
+	pullRequest := decodeResponse(w)["pull_request"].(map[string]interface{})
+	assert.Equal(t, float64(workItemID), pullRequest["work_item_id"])
+	ctrl.WaitBackground()
+	status := host.last()
+	assert.Equal(t, "/repos/acme/shop/statuses/abc123", status.Path)
+	assert.Equal(t, "Bearer github-token", status.Auth)
+	assert.Equal(t, scm.StateFailure, status.State)
+	assert.Contains(t, status.Body, "not checked")
+
+	// Deliveries are handled once
+	w = sendTestWebhook(router, repositoryID, delivered, opened)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Comments leave the approvals alone
+	w = githubTestWebhook(router, repositoryID, secret, "pull_request_review", fmt.Sprintf(testGitHubPullRequest, "submitted", "abc123", "commented"))
+	assert.Equal(t, "Event ignored", decodeResponse(w)["message"])
+
+	w = githubTestWebhook(router, repositoryID, secret, "pull_request_review", fmt.Sprintf(testGitHubPullRequest, "submitted", "abc123", "approved"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	ctrl.WaitBackground()
+	assert.Equal(t, scm.StateSuccess, host.last().State)
+
+	w = performRequest(router, "GET", workItemPath, owner.Token, nil)
+	response := decodeResponse(w)
+	assert.True(t, workItemDone(t, response))
+	assert.Equal(t, 1, doneEvents())
+	criterion := workItemStatus(response["work_item"].(map[string]interface{}))["criteria"].([]interface{})[0].(map[string]interface{})
+	assert.Contains(t, criterion["note"], "1 approvals")
+
+	// CI statuses are recorded, ours are not
+	w = githubTestWebhook(router, repositoryID, secret, "status", `{"sha": "abc123", "state": "failure", "context": "dod/definition-of-done", "repository": {"full_name": "acme/shop"}}`)
+	assert.Equal(t, "Event ignored", decodeResponse(w)["message"])
+	w = githubTestWebhook(router, repositoryID, secret, "status", `{"sha": "abc123", "state": "success", "context": "ci/build", "repository": {"full_name": "acme/shop"}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/pull-requests?work_item_id=%d", projectID, workItemID), owner.Token, nil)
+	pullRequests := decodeResponse(w)["pull_requests"].([]interface{})
+	assert.Len(t, pullRequests, 1)
+	assert.Equal(t, []interface{}{"alice"}, pullRequests[0].(map[string]interface{})["approvers"])
+	assert.Equal(t, scm.StateSuccess, pullRequests[0].(map[string]interface{})["ci_state"])
+
+	// A dismissed review takes the approval back
+	w = githubTestWebhook(router, repositoryID, secret, "pull_request_review", fmt.Sprintf(testGitHubPullRequest, "dismissed", "abc123", "approved"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	ctrl.WaitBackground()
+	assert.Equal(t, scm.StateFailure, host.last().State)
+
+	// Ticking by hand posts the verdict again
+	checked := true
+	w = performRequest(router, "PUT", fmt.Sprintf("%s/checks/%d", workItemPath, requiredItemID), owner.Token, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusOK, w.Code)
+	ctrl.WaitBackground()
+	assert.Equal(t, scm.StateSuccess, host.last().State)
+	assert.Equal(t, 2, doneEvents())
+}
+
+func TestGitLabMergeRequestApprovals(t *testing.T) {
+	ctrl := setupTestController()
+	router := setupTestRouterWithController(ctrl)
+	owner := registerTestUser(t, router, "gitlabowner")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+
+	review, approvals := models.AutoCheckReview, 2.0
+	setTestVerification(t, router, owner, dodID, requiredItemID, models.UpdateDoDItemRequest{AutoCheck: &review, AutoCheckThreshold: &approvals})
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), owner.Token, models.CreateWorkItemRequest{Title: "Refunds", ExternalRef: "SHOP-7", DoDID: &dodID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	workItemID := uint(decodeResponse(w)["work_item"].(map[string]interface{})["id"].(float64))
+
+	host := newFakeCodeHost()
+	defer host.Close()
+
+	secret := "gitlab-webhook-token"
+	repositoryID := connectTestRepository(t, router, owner, projectID, models.CreateRepositoryRequest{Provider: scm.GitLab, FullName: "acme/shop", APIURL: host.URL, WebhookSecret: secret, AccessToken: "gitlab-token"})
+
+	mergeRequest := func(action, username string) string {
+		return fmt.Sprintf(`{
+  "object_kind": "merge_request",
+  "user": {"username": "%s"},
+  "project": {"path_with_namespace": "acme/shop"},
+  "object_attributes": {"iid": 3, "title": "SHOP-7: refunds", "state": "opened", "action": "%s",
+    "url": "https://gitlab.com/acme/shop/-/merge_requests/3", "source_branch": "refunds", "last_commit": {"id": "def456"}}
+}`, username, action)
+	}
+	send := func(token, payload string) *httptest.ResponseRecorder {
+		return sendTestWebhook(router, repositoryID, map[string]string{"X-Gitlab-Event": "Merge Request Hook", "X-Gitlab-Token": token}, payload)
+	}
+
+	w = send("wrong-token", mergeRequest("open", "bob"))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Linked through the title
+	w = send(secret, mergeRequest("open", "bob"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(workItemID), decodeResponse(w)["pull_request"].(map[string]interface{})["work_item_id"])
+	ctrl.WaitBackground()
+	status := host.last()
+	assert.Equal(t, "/projects/acme%2Fshop/statuses/def456", status.Path)
+	assert.Equal(t, "gitlab-token", status.Auth)
+	assert.Equal(t, "failed", status.State)
+
+	// One approval out of the two required
+	w = send(secret, mergeRequest("approved", "alice"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	ctrl.WaitBackground()
+	assert.Equal(t, "failed", host.last().State)
+	w = send(secret, mergeRequest("approved", "alice"))
+	ctrl.WaitBackground()
+	assert.Equal(t, "failed", host.last().State)
+
+	w = send(secret, mergeRequest("approved", "carol"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	ctrl.WaitBackground()
+	assert.Equal(t, scm.StateSuccess, host.last().State)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, workItemID), owner.Token, nil)
+	assert.True(t, workItemDone(t, decodeResponse(w)))
+
+	// Every linked merge request must be approved, closed ones aside
+	other := strings.NewReplacer(`"iid": 3`, `"iid": 4`, `"def456"`, `"fed654"`).Replace(mergeRequest("open", "bob"))
+	w = send(secret, other)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, workItemID), owner.Token, nil)
+	assert.False(t, workItemDone(t, decodeResponse(w)))
+
+	w = send(secret, strings.Replace(other, `"state": "opened"`, `"state": "closed"`, 1))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, workItemID), owner.Token, nil)
+	assert.True(t, workItemDone(t, decodeResponse(w)))
+
+	w = send(secret, mergeRequest("unapproved", "carol"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, workItemID), owner.Token, nil)
+	assert.False(t, workItemDone(t, decodeResponse(w)))
+
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/projects/%d/repositories/%d", projectID, repositoryID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = send(secret, mergeRequest("open", "bob"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
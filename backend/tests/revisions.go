@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestWorkItem(t *testing.T, router *gin.Engine, user testUser, projectID, dodID uint) map[string]interface{} {
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), user.Token, models.CreateWorkItemRequest{Title: "Story", DoDID: &dodID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	return decodeResponse(w)["work_item"].(map[string]interface{})
+}
+
+func workItemStatus(workItem map[string]interface{}) map[string]interface{} {
+	return workItem["status"].(map[string]interface{})
+}
+
+// startTestWorkItem moves the work item out of todo, returning it.
+func startTestWorkItem(t *testing.T, router *gin.Engine, user testUser, projectID uint, workItem map[string]interface{}) map[string]interface{} {
+	w := performRequest(router, "PUT", fmt.Sprintf("/api/v1/projects/%d/work-items/%d/state", projectID, uint(workItem["id"].(float64))), user.Token, models.UpdateWorkItemStateRequest{State: models.WorkItemInProgress})
+	assert.Equal(t, http.StatusOK, w.Code)
+	return decodeResponse(w)["work_item"].(map[string]interface{})
+}
+
+func TestPublishedRevisionsPinWorkItems(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "revisionowner")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+
+	// Work items follow the draft until the DoD is first published...
+	early := createTestWorkItem(t, router, owner, projectID, dodID)
+	assert.Nil(t, early["dod_revision_id"])
+	assert.Nil(t, workItemStatus(early)["revision"])
+
+	publishPath := fmt.Sprintf("/api/v1/dods/%d/publish", dodID)
+	w := performRequest(router, "POST", publishPath, owner.Token, models.PublishDoDRequest{Changelog: "First version"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	revision := decodeResponse(w)["revision"].(map[string]interface{})
+	assert.Equal(t, float64(1), revision["number"])
+	assert.Len(t, revision["items"], 2)
+
+	// ...then its last published revision
+	earlyPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, uint(early["id"].(float64)))
+	w = performRequest(router, "GET", earlyPath, owner.Token, nil)
+	early = decodeResponse(w)["work_item"].(map[string]interface{})
+	assert.Equal(t, float64(1), workItemStatus(early)["revision"])
+	assert.Nil(t, early["dod_revision_id"])
+
+	w = performRequest(router, "POST", publishPath, owner.Token, models.PublishDoDRequest{Changelog: "Nothing new"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Editing the DoD only changes its draft
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/dods/%d/items", dodID), owner.Token, models.CreateDoDItemRequest{Title: "Security Review", IsRequired: true, Order: 3})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/dods/%d", dodID), owner.Token, nil)
+	response := decodeResponse(w)
+	assert.Equal(t, float64(1), response["published_revision"])
+	assert.Equal(t, true, response["unpublished_changes"])
+
+	// Starting a work item pins it to the revision in force
+	pinned := startTestWorkItem(t, router, owner, projectID, createTestWorkItem(t, router, owner, projectID, dodID))
+	assert.NotNil(t, pinned["dod_revision_id"])
+	assert.Len(t, workItemStatus(pinned)["criteria"], 2)
+
+	w = performRequest(router, "POST", publishPath, owner.Token, models.PublishDoDRequest{Changelog: "Add security review"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	current := createTestWorkItem(t, router, owner, projectID, dodID)
+	assert.Equal(t, float64(2), workItemStatus(current)["revision"])
+	assert.Len(t, workItemStatus(current)["criteria"], 3)
+
+	// Work items not started yet move on to the new revision
+	w = performRequest(router, "GET", earlyPath, owner.Token, nil)
+	assert.Equal(t, float64(2), workItemStatus(decodeResponse(w)["work_item"].(map[string]interface{}))["revision"])
+
+	// Started ones keep their criteria, even once an item is deleted
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/dods/%d/items/%d", dodID, requiredItemID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	checked := true
+	w = performRequest(router, "PUT", fmt.Sprintf("/api/v1/projects/%d/work-items/%d/checks/%d", projectID, uint(pinned["id"].(float64)), requiredItemID), owner.Token, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusOK, w.Code)
+	response = decodeResponse(w)
+	assert.True(t, workItemDone(t, response))
+	assert.Equal(t, float64(1), workItemStatus(response["work_item"].(map[string]interface{}))["revision"])
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/dods/%d/revisions", dodID), owner.Token, nil)
+	revisions := decodeResponse(w)["revisions"].([]interface{})
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, "Add security review", revisions[1].(map[string]interface{})["changelog"])
+}
+
+func TestDiffRevisions(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "diffowner")
+	_, dodID, requiredItemID, optionalItemID := createTestProjectWithDoD(t, router, owner)
+
+	publishPath := fmt.Sprintf("/api/v1/dods/%d/publish", dodID)
+	w := performRequest(router, "POST", publishPath, owner.Token, models.PublishDoDRequest{Changelog: "v1"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	title := "Code Review By Two Peers"
+	w = performRequest(router, "PATCH", fmt.Sprintf("/api/v1/dods/%d/items/%d", dodID, requiredItemID), owner.Token, models.UpdateDoDItemRequest{Title: &title})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/dods/%d/items/%d", dodID, optionalItemID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/dods/%d/items", dodID), owner.Token, models.CreateDoDItemRequest{Title: "Changelog Entry", IsRequired: true})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Against the draft
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/dods/%d/diff?from=1", dodID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	diff := decodeResponse(w)["diff"].(map[string]interface{})
+	assert.Len(t, diff["added"], 1)
+	assert.Len(t, diff["removed"], 1)
+	changed := diff["changed"].([]interface{})
+	assert.Len(t, changed, 1)
+	assert.Equal(t, []interface{}{"title"}, changed[0].(map[string]interface{})["fields"])
+	assert.Equal(t, float64(0), diff["unchanged"])
+
+	// Between published revisions
+	w = performRequest(router, "POST", publishPath, owner.Token, models.PublishDoDRequest{Changelog: "v2"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/dods/%d/diff?from=2&to=1", dodID), owner.Token, nil)
+	diff = decodeResponse(w)["diff"].(map[string]interface{})
+	assert.Len(t, diff["added"], 1)
+	assert.Len(t, diff["removed"], 1)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/dods/%d/diff?from=3", dodID), owner.Token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"dod-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	router := setupTestRouter()
+	user := registerTestUser(t, router, "refresher")
+
+	w := performRequest(router, "POST", "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: user.RefreshToken})
+	assert.Equal(t, http.StatusOK, w.Code)
+	response := decodeResponse(w)
+	newRefreshToken := response["refresh_token"].(string)
+	assert.NotEqual(t, user.RefreshToken, newRefreshToken)
+
+	w = performRequest(router, "GET", "/api/v1/projects/", response["token"].(string), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Replaying the rotated token is refused and ends the newer session too
+	w = performRequest(router, "POST", "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: user.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: newRefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequest(router, "GET", "/api/v1/projects/", response["token"].(string), nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLoggedOutRefreshTokenIsNotTheft(t *testing.T) {
+	router := setupTestRouter()
+	user := registerTestUser(t, router, "twotabs")
+
+	w := performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	otherSession := decodeResponse(w)
+
+	w = performRequest(router, "POST", "/api/v1/auth/logout", user.Token, models.LogoutRequest{RefreshToken: user.RefreshToken})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A tab still holding the logged out token is refused, and nothing more
+	w = performRequest(router, "POST", "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: user.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequest(router, "GET", "/api/v1/projects/", otherSession["token"].(string), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "POST", "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: otherSession["refresh_token"].(string)})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	router := setupTestRouter()
+	user := registerTestUser(t, router, "logout")
+
+	w := performRequest(router, "POST", "/api/v1/auth/logout", user.Token, models.LogoutRequest{RefreshToken: user.RefreshToken})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", "/api/v1/projects/", user.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Token has been revoked", decodeResponse(w)["error"])
+
+	w = performRequest(router, "POST", "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: user.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLogoutAllSessions(t *testing.T) {
+	router := setupTestRouter()
+	user := registerTestUser(t, router, "logoutall")
+
+	// Open a second session
+	w := performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	otherSession := decodeResponse(w)
+
+	w = performRequest(router, "POST", "/api/v1/auth/logout-all", user.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", "/api/v1/projects/", otherSession["token"].(string), nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: otherSession["refresh_token"].(string)})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Logging in again works
+	w = performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "GET", "/api/v1/projects/", decodeResponse(w)["token"].(string), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
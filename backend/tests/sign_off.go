@@ -0,0 +1,199 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setTestVerification(t *testing.T, router *gin.Engine, owner testUser, dodID, itemID uint, req models.UpdateDoDItemRequest) {
+	w := performRequest(router, "PATCH", fmt.Sprintf("/api/v1/dods/%d/items/%d", dodID, itemID), owner.Token, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestPeerSignOffRequiresAnotherParticipant(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "signoffowner")
+	reviewer := registerTestUser(t, router, "signoffreviewer")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, reviewer, "editor")
+
+	peer := models.VerificationPeer
+	setTestVerification(t, router, owner, dodID, requiredItemID, models.UpdateDoDItemRequest{Verification: &peer})
+
+	workItem := createTestWorkItem(t, router, owner, projectID, dodID)
+	checkPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d/checks/%d", projectID, uint(workItem["id"].(float64)), requiredItemID)
+
+	// Approving before anything was ticked
+	w := performRequest(router, "POST", checkPath+"/approve", reviewer.Token, models.SignOffRequest{})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	checked := true
+	w = performRequest(router, "PUT", checkPath, owner.Token, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusOK, w.Code)
+	response := decodeResponse(w)
+	assert.False(t, workItemDone(t, response))
+	criterion := workItemStatus(response["work_item"].(map[string]interface{}))["criteria"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, true, criterion["pending_approval"])
+	assert.Equal(t, false, criterion["checked"])
+
+	// Four eyes: not by whoever ticked it
+	w = performRequest(router, "POST", checkPath+"/approve", owner.Token, models.SignOffRequest{})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "POST", checkPath+"/approve", reviewer.Token, models.SignOffRequest{Comment: "Looks good"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, workItemDone(t, decodeResponse(w)))
+
+	w = performRequest(router, "GET", checkPath+"/decisions", owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	decisions := decodeResponse(w)["decisions"].([]interface{})
+	assert.Len(t, decisions, 2)
+	assert.Equal(t, models.DecisionRequested, decisions[0].(map[string]interface{})["decision"])
+	assert.Equal(t, models.DecisionApproved, decisions[1].(map[string]interface{})["decision"])
+	assert.Equal(t, "Looks good", decisions[1].(map[string]interface{})["comment"])
+}
+
+func TestSignOffByAPIKeyCreator(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "keysignowner")
+	reviewer := registerTestUser(t, router, "keysignreviewer")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, reviewer, "editor")
+
+	peer := models.VerificationPeer
+	setTestVerification(t, router, owner, dodID, requiredItemID, models.UpdateDoDItemRequest{Verification: &peer})
+
+	keysPath := fmt.Sprintf("/api/v1/projects/%d/api-keys", projectID)
+	key, record := createTestAPIToken(t, router, keysPath, owner.Token, models.CreateAPITokenRequest{Name: "CI", Scopes: []string{models.ScopeRead, models.ScopeWrite}})
+	keyUserID := uint(record["user_id"].(float64))
+
+	workItemID := uint(createTestWorkItem(t, router, owner, projectID, dodID)["id"].(float64))
+	checkPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d/checks/%d", projectID, workItemID, requiredItemID)
+
+	// Ticked through the key, the item is still the owner's doing
+	checked := true
+	w := performRequest(router, "PUT", checkPath, key, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "POST", checkPath+"/approve", owner.Token, models.SignOffRequest{})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Keys never sign off
+	w = performRequest(router, "PUT", checkPath, owner.Token, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "POST", checkPath+"/approve", key, models.SignOffRequest{})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "POST", checkPath+"/approve", reviewer.Token, models.SignOffRequest{})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Nor approve waivers, and their creator cannot approve what they request
+	// through them
+	waiversPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d/checks/%d/waivers", projectID, workItemID, requiredItemID)
+	w = performRequest(router, "POST", waiversPath, owner.Token, models.CreateWaiverRequest{Justification: "Hotfix", ApproverID: keyUserID, ExpiresAt: time.Now().Add(time.Hour)})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(router, "POST", waiversPath, key, models.CreateWaiverRequest{Justification: "Hotfix", ApproverID: owner.ID, ExpiresAt: time.Now().Add(time.Hour)})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestQuorumSignOffAndRejection(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "quorumowner")
+	first := registerTestUser(t, router, "quorumfirst")
+	second := registerTestUser(t, router, "quorumsecond")
+	viewer := registerTestUser(t, router, "quorumviewer")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, first, "editor")
+	addTestParticipant(t, router, owner, projectID, second, "editor")
+	addTestParticipant(t, router, owner, projectID, viewer, "viewer")
+
+	quorum, approvals := models.VerificationQuorum, 2
+	setTestVerification(t, router, owner, dodID, requiredItemID, models.UpdateDoDItemRequest{Verification: &quorum, RequiredApprovals: &approvals})
+
+	workItem := createTestWorkItem(t, router, owner, projectID, dodID)
+	checkPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d/checks/%d", projectID, uint(workItem["id"].(float64)), requiredItemID)
+
+	checked := true
+	w := performRequest(router, "PUT", checkPath, owner.Token, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "POST", checkPath+"/approve", first.Token, models.SignOffRequest{})
+	assert.Equal(t, http.StatusOK, w.Code)
+	response := decodeResponse(w)
+	assert.False(t, workItemDone(t, response))
+	assert.Equal(t, float64(1), response["completion"].(map[string]interface{})["approvals"])
+
+	w = performRequest(router, "POST", checkPath+"/approve", first.Token, models.SignOffRequest{})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = performRequest(router, "POST", checkPath+"/approve", viewer.Token, models.SignOffRequest{})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Rejections need a reason and send the item back
+	w = performRequest(router, "POST", checkPath+"/reject", second.Token, models.CommentRequest{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, "POST", checkPath+"/reject", second.Token, models.CommentRequest{Comment: "Missing load test"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, false, decodeResponse(w)["completion"].(map[string]interface{})["pending_approval"])
+
+	w = performRequest(router, "POST", checkPath+"/approve", second.Token, models.SignOffRequest{})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Anyone on the project can comment
+	w = performRequest(router, "POST", checkPath+"/comments", viewer.Token, models.CommentRequest{Comment: "Load test is tracked in PERF-12"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// A new request starts the count over
+	w = performRequest(router, "PUT", checkPath, owner.Token, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "POST", checkPath+"/approve", first.Token, models.SignOffRequest{})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "POST", checkPath+"/approve", second.Token, models.SignOffRequest{})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, workItemDone(t, decodeResponse(w)))
+
+	w = performRequest(router, "GET", checkPath+"/decisions", viewer.Token, nil)
+	assert.Len(t, decodeResponse(w)["decisions"], 7)
+}
+
+func TestRoleSignOff(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "roleowner")
+	editor := registerTestUser(t, router, "roleeditor")
+	tester := registerTestUser(t, router, "roletester")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/roles", projectID), owner.Token, models.CreateProjectRoleRequest{Name: "qa", Permissions: []string{"item:check"}})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	addTestParticipant(t, router, owner, projectID, editor, "editor")
+	addTestParticipant(t, router, owner, projectID, tester, "qa")
+
+	role, unknown, qa := models.VerificationRole, "auditor", "qa"
+	w = performRequest(router, "PATCH", fmt.Sprintf("/api/v1/dods/%d/items/%d", dodID, requiredItemID), owner.Token, models.UpdateDoDItemRequest{Verification: &role})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(router, "PATCH", fmt.Sprintf("/api/v1/dods/%d/items/%d", dodID, requiredItemID), owner.Token, models.UpdateDoDItemRequest{Verification: &role, ApproverRole: &unknown})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	setTestVerification(t, router, owner, dodID, requiredItemID, models.UpdateDoDItemRequest{Verification: &role, ApproverRole: &qa})
+
+	workItem := createTestWorkItem(t, router, owner, projectID, dodID)
+	checkPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d/checks/%d", projectID, uint(workItem["id"].(float64)), requiredItemID)
+
+	checked := true
+	w = performRequest(router, "PUT", checkPath, editor.Token, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "POST", checkPath+"/approve", owner.Token, models.SignOffRequest{})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "POST", checkPath+"/approve", tester.Token, models.SignOffRequest{})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, workItemDone(t, decodeResponse(w)))
+}
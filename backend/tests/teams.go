@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestTeam(t *testing.T, router *gin.Engine, maintainer testUser, name string, members ...testUser) uint {
+	w := performRequest(router, "POST", "/api/v1/teams/", maintainer.Token, models.CreateTeamRequest{Name: name})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	teamID := uint(decodeResponse(w)["team"].(map[string]interface{})["id"].(float64))
+
+	for _, member := range members {
+		w = performRequest(router, "POST", fmt.Sprintf("/api/v1/teams/%d/members", teamID), maintainer.Token, models.AddTeamMemberRequest{Email: member.Email, Role: "member"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+	return teamID
+}
+
+func projectRole(t *testing.T, router *gin.Engine, user testUser, projectID uint) string {
+	w := performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/permissions", projectID), user.Token, nil)
+	if w.Code != http.StatusOK {
+		return ""
+	}
+	return decodeResponse(w)["role"].(string)
+}
+
+func TestTeamGrantsProjectAccess(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "teamprojectowner")
+	lead := registerTestUser(t, router, "teamlead")
+	dev := registerTestUser(t, router, "teamdev")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+	teamID := createTestTeam(t, router, lead, "Squad", dev)
+
+	assert.Equal(t, "", projectRole(t, router, dev, projectID))
+
+	// Only members of the team can add it
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/teams", projectID), owner.Token, models.AddProjectTeamRequest{TeamID: teamID, Role: "viewer"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/teams/%d/members", teamID), lead.Token, models.AddTeamMemberRequest{Email: owner.Email, Role: "member"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/teams", projectID), owner.Token, models.AddProjectTeamRequest{TeamID: teamID, Role: "owner"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/teams", projectID), owner.Token, models.AddProjectTeamRequest{TeamID: teamID, Role: "viewer"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Every member reaches the project, and sees it listed
+	assert.Equal(t, "viewer", projectRole(t, router, lead, projectID))
+	assert.Equal(t, "viewer", projectRole(t, router, dev, projectID))
+
+	w = performRequest(router, "GET", "/api/v1/projects/", dev.Token, nil)
+	assert.Len(t, decodeResponse(w)["projects"].([]interface{}), 1)
+
+	w = performRequest(router, "POST", "/api/v1/dods/", dev.Token, models.CreateDoDRequest{Title: "Viewer DoD", ProjectID: projectID})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// The highest of the direct and team grants applies
+	addTestParticipant(t, router, owner, projectID, dev, "editor")
+	assert.Equal(t, "editor", projectRole(t, router, dev, projectID))
+
+	w = performRequest(router, "POST", "/api/v1/dods/", dev.Token, models.CreateDoDRequest{Title: "Editor DoD", ProjectID: projectID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = performRequest(router, "PATCH", fmt.Sprintf("/api/v1/projects/%d/teams/%d", projectID, teamID), owner.Token, models.UpdateProjectTeamRequest{Role: "editor"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "editor", projectRole(t, router, lead, projectID))
+
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/projects/%d/teams/%d", projectID, teamID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "", projectRole(t, router, lead, projectID))
+	assert.Equal(t, "editor", projectRole(t, router, dev, projectID))
+}
+
+func TestTeamMembershipPropagates(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "teamowner2")
+	lead := registerTestUser(t, router, "teamlead2")
+	dev := registerTestUser(t, router, "teamdev2")
+	newcomer := registerTestUser(t, router, "teamnewcomer")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+	teamID := createTestTeam(t, router, lead, "Platform", dev, owner)
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/teams", projectID), owner.Token, models.AddProjectTeamRequest{TeamID: teamID, Role: "editor"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Joining and leaving the team applies to the project right away
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/teams/%d/members", teamID), lead.Token, models.AddTeamMemberRequest{Email: newcomer.Email, Role: "member"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "editor", projectRole(t, router, newcomer, projectID))
+
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/teams/%d/members/%d", teamID, dev.ID), lead.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "", projectRole(t, router, dev, projectID))
+
+	// Only maintainers manage the team, which keeps one
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/teams/%d/members", teamID), newcomer.Token, models.AddTeamMemberRequest{Email: dev.Email, Role: "member"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "PATCH", fmt.Sprintf("/api/v1/teams/%d/members/%d", teamID, lead.ID), lead.Token, models.UpdateTeamMemberRequest{Role: "member"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Deleting the team revokes its access
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/teams/%d", teamID), lead.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "", projectRole(t, router, newcomer, projectID))
+}
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"dod-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateDoDFromBuiltinTemplate(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "templateowner")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	w := performRequest(router, "GET", "/api/v1/dod-templates/", owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	templates := decodeResponse(w)["templates"].([]interface{})
+	assert.Len(t, templates, 4)
+	assert.Equal(t, "feature", templates[0].(map[string]interface{})["key"])
+
+	w = performRequest(router, "POST", "/api/v1/dods/from-template", owner.Token, models.CreateDoDFromTemplateRequest{ProjectID: projectID, Template: "bugfix"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	dod := decodeResponse(w)["dod"].(map[string]interface{})
+	assert.Equal(t, "Bug Fix DoD", dod["title"])
+	items := dod["items"].([]interface{})
+	assert.Len(t, items, 4)
+
+	required := 0
+	for _, item := range items {
+		if item.(map[string]interface{})["is_required"].(bool) {
+			required++
+		}
+	}
+	assert.Equal(t, 3, required)
+
+	w = performRequest(router, "POST", "/api/v1/dods/from-template", owner.Token, models.CreateDoDFromTemplateRequest{ProjectID: projectID, Template: "epic"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	templateID := uint(1)
+	w = performRequest(router, "POST", "/api/v1/dods/from-template", owner.Token, models.CreateDoDFromTemplateRequest{ProjectID: projectID, Template: "spike", TemplateID: &templateID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	stranger := registerTestUser(t, router, "templatestranger")
+	w = performRequest(router, "POST", "/api/v1/dods/from-template", stranger.Token, models.CreateDoDFromTemplateRequest{ProjectID: projectID, Template: "spike"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestUserDefinedTemplate(t *testing.T) {
+	router := setupTestRouter()
+	author := registerTestUser(t, router, "templateauthor")
+	colleague := registerTestUser(t, router, "templatecolleague")
+	_, dodID, _, _ := createTestProjectWithDoD(t, router, author)
+
+	// Saved from an existing DoD, the template is private to its author
+	w := performRequest(router, "POST", "/api/v1/dod-templates/", author.Token, models.CreateDoDTemplateRequest{Name: "Story DoD", DoDID: &dodID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	template := decodeResponse(w)["template"].(map[string]interface{})
+	templateID := uint(template["id"].(float64))
+	assert.Len(t, template["items"], 2)
+
+	w = performRequest(router, "POST", "/api/v1/projects/", colleague.Token, models.CreateProjectRequest{Name: "Colleague Project"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	colleagueProject := uint(decodeResponse(w)["project"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "POST", "/api/v1/dods/from-template", colleague.Token, models.CreateDoDFromTemplateRequest{ProjectID: colleagueProject, TemplateID: &templateID})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Sharing it with an organization makes it available to its members
+	orgID := createTestOrganization(t, router, author, "Template Guild")
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/organizations/%d/members", orgID), author.Token, models.AddOrganizationMemberRequest{Email: colleague.Email, Role: "member"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/dod-templates/", author.Token, models.CreateDoDTemplateRequest{
+		Name:           "Guild DoD",
+		OrganizationID: &orgID,
+		Items:          []models.DoDTemplateItemRequest{{Title: "Pairing session held", IsRequired: true}},
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	sharedID := uint(decodeResponse(w)["template"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "GET", "/api/v1/dod-templates/", colleague.Token, nil)
+	assert.Len(t, decodeResponse(w)["templates"].([]interface{}), 5)
+
+	w = performRequest(router, "POST", "/api/v1/dods/from-template", colleague.Token, models.CreateDoDFromTemplateRequest{ProjectID: colleagueProject, TemplateID: &sharedID, Title: "Our DoD"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "Our DoD", decodeResponse(w)["dod"].(map[string]interface{})["title"])
+
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/dod-templates/%d", sharedID), colleague.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "DELETE", fmt.Sprintf("/api/v1/dod-templates/%d", templateID), author.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCloneProject(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "cloneowner")
+	qa := registerTestUser(t, router, "cloneqa")
+	viewer := registerTestUser(t, router, "cloneviewer")
+	projectID, _, _, optionalItemID := createTestProjectWithDoD(t, router, owner)
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/roles", projectID), owner.Token, models.CreateProjectRoleRequest{Name: "qa", Permissions: []string{"item:check"}})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	addTestParticipant(t, router, owner, projectID, qa, "qa")
+	addTestParticipant(t, router, owner, projectID, viewer, "viewer")
+
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/clone", projectID), owner.Token, models.CloneProjectRequest{Name: "Copy", IncludeParticipants: true})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	clone := decodeResponse(w)["project"].(map[string]interface{})
+	cloneID := uint(clone["id"].(float64))
+	assert.NotEqual(t, projectID, cloneID)
+	assert.Len(t, clone["participants"], 3)
+
+	dods := clone["dods"].([]interface{})
+	assert.Len(t, dods, 1)
+	items := dods[0].(map[string]interface{})["items"].([]interface{})
+	assert.Len(t, items, 2)
+	for _, item := range items {
+		item := item.(map[string]interface{})
+		assert.NotEqual(t, float64(optionalItemID), item["id"])
+		if item["title"] == "Documentation Updated" {
+			assert.Equal(t, false, item["is_required"])
+		}
+	}
+
+	// Custom roles come along with the participants holding them
+	assert.Equal(t, "qa", projectRole(t, router, qa, cloneID))
+
+	// Viewers clone the DoDs only
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/clone", projectID), viewer.Token, models.CloneProjectRequest{Name: "Viewer copy", IncludeParticipants: true})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/clone", projectID), viewer.Token, models.CloneProjectRequest{Name: "Viewer copy"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	viewerClone := decodeResponse(w)["project"].(map[string]interface{})
+	assert.Len(t, viewerClone["participants"], 1)
+	assert.Equal(t, float64(viewer.ID), viewerClone["owner_id"])
+}
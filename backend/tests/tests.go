@@ -3,16 +3,19 @@ package tests
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"dod-backend/config"
 	"dod-backend/controllers"
 	"dod-backend/database"
-	"dod-backend/middleware"
+	"dod-backend/mailer"
 	"dod-backend/models"
 	"dod-backend/routes"
+	"dod-backend/safehttp"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -34,6 +37,26 @@ func TestHealthCheck(t *testing.T) {
 
 
 func setupTestRouter() *gin.Engine {
+	router, _ := setupTestRouterWithMailer()
+	return router
+}
+
+// setupTestRouterWithMailer also returns the in-memory mailer that captures
+// every email sent by the controllers.
+func setupTestRouterWithMailer() (*gin.Engine, *mailer.MemoryMailer) {
+	ctrl := setupTestController()
+	return setupTestRouterWithController(ctrl), ctrl.Mailer.(*mailer.MemoryMailer)
+}
+
+func setupTestRouterWithController(ctrl *controllers.Controller) *gin.Engine {
+	r := gin.Default()
+	routes.SetupRoutesWithController(r, ctrl)
+	return r
+}
+
+// setupTestController lets tests swap the controller dependencies before
+// building the router.
+func setupTestController() *controllers.Controller {
 	gin.SetMode(gin.TestMode)
 	
 	// Use test config
@@ -45,15 +68,24 @@ func setupTestRouter() *gin.Engine {
 		DBName:      "test_database",
 		JWTSecret:   "test-secret-key",
 		Environment: "test",
+		AppURL:      "http://localhost:3000",
+
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 24 * time.Hour,
+
+		// Stand-ins of code hosts listen on the loopback interface
+		SCMAPIHosts: []string{"127.0.0.1"},
 	}
 
 	// Initialize test database (you might want to use sqlite in memory for tests)
 	db := database.Initialize(cfg)
-	
-	r := gin.Default()
-	routes.SetupRoutes(r, db)
-	
-	return r
+
+	ctrl := controllers.NewController(db, cfg)
+	ctrl.Mailer = &mailer.MemoryMailer{}
+	// Stand-ins of webhook receivers and chats listen on the loopback interface
+	ctrl.Webhooks.HTTPClient = safehttp.NewTrustedClient(10 * time.Second)
+	ctrl.Chat.HTTPClient = safehttp.NewTrustedClient(10 * time.Second)
+	return ctrl
 }
 
 func TestCreateProject(t *testing.T) {
@@ -145,4 +177,56 @@ func TestUnauthorizedAccess(t *testing.T) {
 	var response map[string]interface{}
 	json.Unmarshal(w.Body.Bytes(), &response)
 	assert.Equal(t, "Authorization header required", response["error"])
-}
\ No newline at end of file
+}
+
+type testUser struct {
+	ID           uint
+	Email        string
+	Token        string
+	RefreshToken string
+}
+
+// registerTestUser creates a user with a unique email and returns it with its token.
+func registerTestUser(t *testing.T, router *gin.Engine, username string) testUser {
+	suffix := time.Now().UnixNano()
+	registerReq := models.RegisterRequest{
+		Username: fmt.Sprintf("%s%d", username, suffix),
+		Email:    fmt.Sprintf("%s%d@example.com", username, suffix),
+		Password: "password123",
+	}
+
+	w := performRequest(router, "POST", "/api/v1/auth/register", "", registerReq)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	response := decodeResponse(w)
+	user := response["user"].(map[string]interface{})
+	return testUser{
+		ID:           uint(user["id"].(float64)),
+		Email:        registerReq.Email,
+		Token:        response["token"].(string),
+		RefreshToken: response["refresh_token"].(string),
+	}
+}
+
+func performRequest(router *gin.Engine, method, path, token string, payload interface{}) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		json.NewEncoder(&body).Encode(payload)
+	}
+
+	req, _ := http.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func decodeResponse(w *httptest.ResponseRecorder) map[string]interface{} {
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response
+}
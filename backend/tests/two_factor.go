@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"dod-backend/models"
+	"dod-backend/totp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// enableTestTwoFactor enrols the user and returns the TOTP secret and recovery codes.
+func enableTestTwoFactor(t *testing.T, router *gin.Engine, user testUser) (string, []interface{}) {
+	w := performRequest(router, "POST", "/api/v1/auth/2fa/setup", user.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	setup := decodeResponse(w)
+	secret := setup["secret"].(string)
+	assert.Contains(t, setup["otpauth_uri"], "otpauth://totp/")
+
+	w = performRequest(router, "POST", "/api/v1/auth/2fa/enable", user.Token, models.TwoFactorCodeRequest{Code: "000000"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	code, _ := totp.Code(secret, totp.Counter(time.Now()))
+	w = performRequest(router, "POST", "/api/v1/auth/2fa/enable", user.Token, models.TwoFactorCodeRequest{Code: code})
+	assert.Equal(t, http.StatusOK, w.Code)
+	recoveryCodes := decodeResponse(w)["recovery_codes"].([]interface{})
+	assert.Len(t, recoveryCodes, 10)
+
+	return secret, recoveryCodes
+}
+
+func TestTwoStepLogin(t *testing.T) {
+	router := setupTestRouter()
+	user := registerTestUser(t, router, "twofactor")
+	secret, _ := enableTestTwoFactor(t, router, user)
+
+	w := performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	response := decodeResponse(w)
+	assert.Equal(t, true, response["two_factor_required"])
+	assert.Nil(t, response["token"])
+	challenge := response["challenge_token"].(string)
+
+	// The challenge is not a session token
+	w = performRequest(router, "GET", "/api/v1/projects/", challenge, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// The code used at enrolment cannot be replayed, the next one is accepted
+	code, _ := totp.Code(secret, totp.Counter(time.Now()))
+	w = performRequest(router, "POST", "/api/v1/auth/2fa/verify", "", models.TwoFactorLoginRequest{ChallengeToken: challenge, Code: code})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	code, _ = totp.Code(secret, totp.Counter(time.Now())+1)
+	w = performRequest(router, "POST", "/api/v1/auth/2fa/verify", "", models.TwoFactorLoginRequest{ChallengeToken: challenge, Code: code})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", "/api/v1/projects/", decodeResponse(w)["token"].(string), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRecoveryCodeLogin(t *testing.T) {
+	router := setupTestRouter()
+	user := registerTestUser(t, router, "recovery")
+	_, recoveryCodes := enableTestTwoFactor(t, router, user)
+	recoveryCode := recoveryCodes[0].(string)
+
+	login := func() string {
+		w := performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "password123"})
+		return decodeResponse(w)["challenge_token"].(string)
+	}
+
+	w := performRequest(router, "POST", "/api/v1/auth/2fa/verify", "", models.TwoFactorLoginRequest{ChallengeToken: login(), Code: recoveryCode})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Recovery codes are single-use
+	w = performRequest(router, "POST", "/api/v1/auth/2fa/verify", "", models.TwoFactorLoginRequest{ChallengeToken: login(), Code: recoveryCode})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestDisableTwoFactor(t *testing.T) {
+	router := setupTestRouter()
+	user := registerTestUser(t, router, "disable2fa")
+	_, recoveryCodes := enableTestTwoFactor(t, router, user)
+
+	w := performRequest(router, "POST", "/api/v1/auth/2fa/disable", user.Token, models.DisableTwoFactorRequest{Password: "wrong-password", Code: recoveryCodes[0].(string)})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/auth/2fa/disable", user.Token, models.DisableTwoFactorRequest{Password: "password123", Code: recoveryCodes[0].(string)})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "POST", "/api/v1/auth/login", "", models.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotNil(t, decodeResponse(w)["token"])
+}
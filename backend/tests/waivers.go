@@ -0,0 +1,174 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"dod-backend/mailer"
+	"dod-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApprovedWaiverSatisfiesRequiredItem(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "waiverowner")
+	lead := registerTestUser(t, router, "waiverlead")
+	viewer := registerTestUser(t, router, "waiverviewer")
+	projectID, dodID, requiredItemID, optionalItemID := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, lead, "editor")
+	addTestParticipant(t, router, owner, projectID, viewer, "viewer")
+
+	workItem := createTestWorkItem(t, router, owner, projectID, dodID)
+	workItemPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, uint(workItem["id"].(float64)))
+	waiverPath := fmt.Sprintf("%s/checks/%d/waivers", workItemPath, requiredItemID)
+	expiresAt := time.Now().Add(7 * 24 * time.Hour)
+
+	// Only required items, approved by someone else who can check items
+	w := performRequest(router, "POST", fmt.Sprintf("%s/checks/%d/waivers", workItemPath, optionalItemID), owner.Token, models.CreateWaiverRequest{Justification: "Later", ApproverID: lead.ID, ExpiresAt: expiresAt})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(router, "POST", waiverPath, owner.Token, models.CreateWaiverRequest{Justification: "Later", ApproverID: owner.ID, ExpiresAt: expiresAt})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(router, "POST", waiverPath, owner.Token, models.CreateWaiverRequest{Justification: "Later", ApproverID: viewer.ID, ExpiresAt: expiresAt})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(router, "POST", waiverPath, owner.Token, models.CreateWaiverRequest{Justification: "Later", ApproverID: lead.ID, ExpiresAt: time.Now().Add(-time.Hour)})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, "POST", waiverPath, owner.Token, models.CreateWaiverRequest{
+		Justification: "Load tests run after the release freeze",
+		ApproverID:    lead.ID,
+		ExpiresAt:     expiresAt,
+		FollowUpRef:   "PERF-12",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	waiver := decodeResponse(w)["waiver"].(map[string]interface{})
+	assert.Equal(t, models.WaiverPending, waiver["status"])
+	decidePath := fmt.Sprintf("/api/v1/projects/%d/waivers/%d", projectID, uint(waiver["id"].(float64)))
+
+	w = performRequest(router, "POST", waiverPath, owner.Token, models.CreateWaiverRequest{Justification: "Again", ApproverID: lead.ID, ExpiresAt: expiresAt})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Pending waivers do not count
+	w = performRequest(router, "GET", workItemPath, owner.Token, nil)
+	assert.False(t, workItemDone(t, decodeResponse(w)))
+
+	w = performRequest(router, "POST", decidePath+"/approve", owner.Token, models.DecideWaiverRequest{})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequest(router, "POST", decidePath+"/approve", lead.Token, models.DecideWaiverRequest{Comment: "Fine until then"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "POST", decidePath+"/reject", lead.Token, models.DecideWaiverRequest{})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = performRequest(router, "GET", workItemPath, owner.Token, nil)
+	response := decodeResponse(w)
+	assert.True(t, workItemDone(t, response))
+	status := workItemStatus(response["work_item"].(map[string]interface{}))
+	assert.Len(t, status["waived"], 1)
+	criterion := status["criteria"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, waiver["id"], criterion["waiver_id"])
+	assert.NotNil(t, criterion["waived_until"])
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/waivers?status=approved", projectID), viewer.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, decodeResponse(w)["waivers"], 1)
+
+	// Revoking sends the item back to unmet
+	w = performRequest(router, "DELETE", decidePath, viewer.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequest(router, "DELETE", decidePath, owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", workItemPath, owner.Token, nil)
+	assert.False(t, workItemDone(t, decodeResponse(w)))
+}
+
+func TestExpiredWaiverRaisesAlert(t *testing.T) {
+	ctrl := setupTestController()
+	router := setupTestRouterWithController(ctrl)
+	owner := registerTestUser(t, router, "expiryowner")
+	lead := registerTestUser(t, router, "expirylead")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, lead, "editor")
+
+	workItem := createTestWorkItem(t, router, owner, projectID, dodID)
+	workItemPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, uint(workItem["id"].(float64)))
+
+	w := performRequest(router, "POST", fmt.Sprintf("%s/checks/%d/waivers", workItemPath, requiredItemID), owner.Token, models.CreateWaiverRequest{
+		Justification: "Security review booked next sprint",
+		ApproverID:    lead.ID,
+		ExpiresAt:     time.Now().Add(time.Hour),
+		FollowUpRef:   "SEC-7",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	waiverID := uint(decodeResponse(w)["waiver"].(map[string]interface{})["id"].(float64))
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/waivers/%d/approve", projectID, waiverID), lead.Token, models.DecideWaiverRequest{})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for _, state := range []string{models.WorkItemInProgress, models.WorkItemDone} {
+		w = performRequest(router, "PUT", workItemPath+"/state", owner.Token, models.UpdateWorkItemStateRequest{State: state})
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	// A waiver that fails to expire does not hold back the others
+	broken := models.Waiver{ProjectID: projectID, WorkItemID: 999999, DoDItemID: requiredItemID, Justification: "Orphaned", ExpiresAt: time.Now().Add(time.Hour), Status: models.WaiverApproved, RequestedBy: owner.ID, ApproverID: lead.ID}
+	assert.NoError(t, ctrl.DB.Create(&broken).Error)
+
+	// Nothing runs out before its time
+	expired, err := ctrl.ExpireWaivers(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, expired)
+
+	expired, err = ctrl.ExpireWaivers(time.Now().Add(2 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+
+	var waiver models.Waiver
+	assert.NoError(t, ctrl.DB.First(&waiver, waiverID).Error)
+	assert.Equal(t, models.WaiverExpired, waiver.Status)
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/alerts", projectID), owner.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	alerts := decodeResponse(w)["alerts"].([]interface{})
+	assert.Len(t, alerts, 1)
+	assert.Equal(t, models.AlertWaiverExpired, alerts[0].(map[string]interface{})["kind"])
+	assert.Contains(t, alerts[0].(map[string]interface{})["message"], "SEC-7")
+
+	sent := ctrl.Mailer.(*mailer.MemoryMailer)
+	for _, user := range []testUser{owner, lead} {
+		msg, ok := sent.Last(user.Email)
+		assert.True(t, ok)
+		assert.Contains(t, msg.Subject, "Waiver expired")
+	}
+
+	// The work item no longer meets its DoD
+	w = performRequest(router, "GET", workItemPath, owner.Token, nil)
+	response := decodeResponse(w)
+	assert.False(t, workItemDone(t, response))
+	assert.Equal(t, models.WorkItemInProgress, response["work_item"].(map[string]interface{})["state"])
+}
+
+func TestDemotedApproverCannotDecideWaiver(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "demotedwaiverowner")
+	lead := registerTestUser(t, router, "demotedwaiverlead")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, lead, "editor")
+
+	workItem := createTestWorkItem(t, router, owner, projectID, dodID)
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items/%d/checks/%d/waivers", projectID, uint(workItem["id"].(float64)), requiredItemID), owner.Token, models.CreateWaiverRequest{Justification: "Later", ApproverID: lead.ID, ExpiresAt: time.Now().Add(24 * time.Hour)})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	decidePath := fmt.Sprintf("/api/v1/projects/%d/waivers/%d", projectID, uint(decodeResponse(w)["waiver"].(map[string]interface{})["id"].(float64)))
+
+	// Demoted after the request, the approver can no longer decide
+	w = performRequest(router, "PATCH", fmt.Sprintf("/api/v1/projects/%d/participants/%d", projectID, lead.ID), owner.Token, models.UpdateParticipantRoleRequest{Role: "viewer"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "POST", decidePath+"/approve", lead.Token, models.DecideWaiverRequest{})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "PATCH", fmt.Sprintf("/api/v1/projects/%d/participants/%d", projectID, lead.ID), owner.Token, models.UpdateParticipantRoleRequest{Role: "editor"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "POST", decidePath+"/approve", lead.Token, models.DecideWaiverRequest{})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,306 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"dod-backend/models"
+	"dod-backend/safehttp"
+	"dod-backend/webhooks"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type receivedWebhook struct {
+	Header http.Header
+	Body   []byte
+}
+
+// webhookReceiver records the deliveries it is sent, answering with the
+// status it is told to.
+type webhookReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	received []receivedWebhook
+}
+
+func newWebhookReceiver() *webhookReceiver {
+	receiver := &webhookReceiver{status: http.StatusOK}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.received = append(receiver.received, receivedWebhook{Header: r.Header, Body: body})
+		w.WriteHeader(receiver.status)
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	return receiver
+}
+
+func (r *webhookReceiver) answer(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+func (r *webhookReceiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.received)
+}
+
+func (r *webhookReceiver) last() receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.received[len(r.received)-1]
+}
+
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"event":"ping"}`)
+	signature := webhooks.Sign("secret", 1700000000, body)
+	assert.True(t, webhooks.Verify("secret", 1700000000, body, signature))
+	assert.False(t, webhooks.Verify("secret", 1700000001, body, signature))
+	assert.False(t, webhooks.Verify("other", 1700000000, body, signature))
+
+	assert.Equal(t, time.Minute, webhooks.Backoff(1))
+	assert.Equal(t, 4*time.Minute, webhooks.Backoff(3))
+	assert.Equal(t, time.Hour, webhooks.Backoff(7))
+	assert.Equal(t, time.Hour, webhooks.Backoff(50))
+}
+
+func TestWebhookSenderRefusesInternalAddresses(t *testing.T) {
+	for address, public := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+		"::ffff:127.0.0.1": false,
+	} {
+		assert.Equal(t, public, safehttp.Public(net.ParseIP(address)), address)
+	}
+
+	receiver := newWebhookReceiver()
+	defer receiver.Close()
+	req := webhooks.Request{URL: receiver.URL, Secret: "secret", Event: models.EventPing, DeliveryID: 1, Payload: []byte(`{}`)}
+
+	_, err := webhooks.NewSender().Send(context.Background(), req)
+	assert.ErrorIs(t, err, safehttp.ErrNonPublicAddress)
+	assert.Equal(t, 0, receiver.count())
+
+	// Redirects are not followed, even to trusted hosts
+	redirect := httptest.NewServer(http.RedirectHandler(receiver.URL, http.StatusTemporaryRedirect))
+	defer redirect.Close()
+	req.URL = redirect.URL
+	sender := &webhooks.Sender{HTTPClient: safehttp.NewTrustedClient(10 * time.Second)}
+	result, err := sender.Send(context.Background(), req)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusTemporaryRedirect, result.StatusCode)
+	assert.Equal(t, 0, receiver.count())
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	ctrl := setupTestController()
+	router := setupTestRouterWithController(ctrl)
+	owner := registerTestUser(t, router, "hookowner")
+	editor := registerTestUser(t, router, "hookeditor")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+	addTestParticipant(t, router, owner, projectID, editor, "editor")
+
+	receiver := newWebhookReceiver()
+	defer receiver.Close()
+
+	hooksPath := fmt.Sprintf("/api/v1/projects/%d/webhooks", projectID)
+	req := models.CreateWebhookRequest{URL: receiver.URL, Events: []string{models.EventItemAdded, models.EventWorkItemDone}}
+	w := performRequest(router, "POST", hooksPath, editor.Token, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequest(router, "POST", hooksPath, owner.Token, models.CreateWebhookRequest{URL: receiver.URL, Events: []string{"dod.renamed"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, "POST", hooksPath, owner.Token, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	response := decodeResponse(w)
+	secret := response["secret"].(string)
+	assert.NotEmpty(t, secret)
+	assert.NotContains(t, response["webhook"], "secret")
+	hookPath := fmt.Sprintf("%s/%d", hooksPath, uint(response["webhook"].(map[string]interface{})["id"].(float64)))
+
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/dods/%d/items", dodID), owner.Token, models.CreateDoDItemRequest{Title: "Changelog Updated", Order: 3})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Work item creation is not subscribed to
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), owner.Token, models.CreateWorkItemRequest{Title: "Story", DoDID: &dodID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	workItemID := uint(decodeResponse(w)["work_item"].(map[string]interface{})["id"].(float64))
+
+	delivered, err := ctrl.DeliverWebhooks(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	received := receiver.last()
+	assert.Equal(t, models.EventItemAdded, received.Header.Get(webhooks.HeaderEvent))
+	timestamp, _ := strconv.ParseInt(received.Header.Get(webhooks.HeaderTimestamp), 10, 64)
+	assert.True(t, webhooks.Verify(secret, timestamp, received.Body, received.Header.Get(webhooks.HeaderSignature)))
+	var event map[string]interface{}
+	json.Unmarshal(received.Body, &event)
+	assert.Equal(t, models.EventItemAdded, event["event"])
+	assert.Equal(t, float64(owner.ID), event["actor_id"])
+	assert.Equal(t, "Changelog Updated", event["data"].(map[string]interface{})["title"])
+	firstBody := received.Body
+
+	// Work items are done once their DoD is met, not when moved to done
+	workItemPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d", projectID, workItemID)
+	w = performRequest(router, "PUT", workItemPath+"/state", owner.Token, models.UpdateWorkItemStateRequest{State: models.WorkItemDone})
+	assert.Equal(t, http.StatusOK, w.Code)
+	delivered, _ = ctrl.DeliverWebhooks(time.Now())
+	assert.Equal(t, 0, delivered)
+
+	// Failed deliveries are retried after a backoff
+	receiver.answer(http.StatusServiceUnavailable)
+	checked := true
+	w = performRequest(router, "PUT", fmt.Sprintf("%s/checks/%d", workItemPath, requiredItemID), owner.Token, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	now := time.Now()
+	delivered, _ = ctrl.DeliverWebhooks(now)
+	assert.Equal(t, 0, delivered)
+	assert.Equal(t, 2, receiver.count())
+
+	w = performRequest(router, "GET", hookPath+"/deliveries?status=failed", owner.Token, nil)
+	deliveries := decodeResponse(w)["deliveries"].([]interface{})
+	assert.Len(t, deliveries, 1)
+	failed := deliveries[0].(map[string]interface{})
+	assert.Equal(t, models.EventWorkItemDone, failed["event"])
+	assert.Equal(t, float64(1), failed["attempts"])
+	assert.Equal(t, float64(http.StatusServiceUnavailable), failed["response_status"])
+	assert.NotNil(t, failed["next_attempt_at"])
+
+	delivered, _ = ctrl.DeliverWebhooks(now.Add(30 * time.Second))
+	assert.Equal(t, 0, delivered)
+	assert.Equal(t, 2, receiver.count())
+
+	receiver.answer(http.StatusOK)
+	delivered, _ = ctrl.DeliverWebhooks(now.Add(2 * time.Minute))
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, models.EventWorkItemDone, receiver.last().Header.Get(webhooks.HeaderEvent))
+
+	// Replays send the same payload again
+	w = performRequest(router, "GET", hookPath+"/deliveries", owner.Token, nil)
+	deliveries = decodeResponse(w)["deliveries"].([]interface{})
+	assert.Len(t, deliveries, 2)
+	first := deliveries[1].(map[string]interface{})
+	assert.Equal(t, models.DeliveryDelivered, first["status"])
+
+	w = performRequest(router, "POST", fmt.Sprintf("%s/deliveries/%d/replay", hookPath, uint(first["id"].(float64))), owner.Token, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, first["id"], decodeResponse(w)["delivery"].(map[string]interface{})["replay_of"])
+	delivered, _ = ctrl.DeliverWebhooks(time.Now())
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, firstBody, receiver.last().Body)
+}
+
+func TestSlowWebhookOnlyHoldsBackItself(t *testing.T) {
+	ctrl := setupTestController()
+	router := setupTestRouterWithController(ctrl)
+	owner := registerTestUser(t, router, "slowhookowner")
+	projectID, dodID, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	release := make(chan struct{})
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer stalled.Close()
+	receiver := newWebhookReceiver()
+	defer receiver.Close()
+
+	hooksPath := fmt.Sprintf("/api/v1/projects/%d/webhooks", projectID)
+	for _, url := range []string{stalled.URL, receiver.URL} {
+		w := performRequest(router, "POST", hooksPath, owner.Token, models.CreateWebhookRequest{URL: url, Events: []string{models.EventItemAdded}})
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/dods/%d/items", dodID), owner.Token, models.CreateDoDItemRequest{Title: "Changelog Updated", Order: 3})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	done := make(chan int)
+	go func() {
+		delivered, err := ctrl.DeliverWebhooks(time.Now())
+		assert.NoError(t, err)
+		done <- delivered
+	}()
+
+	// The second endpoint does not wait for the first one
+	assert.Eventually(t, func() bool { return receiver.count() == 1 }, 2*time.Second, 10*time.Millisecond)
+	close(release)
+	assert.Equal(t, 2, <-done)
+}
+
+func TestWebhookDisabledAfterFailures(t *testing.T) {
+	ctrl := setupTestController()
+	router := setupTestRouterWithController(ctrl)
+	owner := registerTestUser(t, router, "failinghookowner")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	receiver := newWebhookReceiver()
+	defer receiver.Close()
+	receiver.answer(http.StatusInternalServerError)
+
+	hooksPath := fmt.Sprintf("/api/v1/projects/%d/webhooks", projectID)
+	w := performRequest(router, "POST", hooksPath, owner.Token, models.CreateWebhookRequest{URL: receiver.URL, Secret: "a-long-enough-secret", Events: []string{"*"}})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	hookPath := fmt.Sprintf("%s/%d", hooksPath, uint(decodeResponse(w)["webhook"].(map[string]interface{})["id"].(float64)))
+
+	for i := 0; i < 3; i++ {
+		w = performRequest(router, "POST", hookPath+"/ping", owner.Token, nil)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		_, err := ctrl.DeliverWebhooks(now)
+		assert.NoError(t, err)
+		now = now.Add(2 * time.Hour)
+	}
+
+	w = performRequest(router, "GET", hooksPath, owner.Token, nil)
+	hook := decodeResponse(w)["webhooks"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, false, hook["active"])
+	assert.Contains(t, hook["disabled_reason"], "20 failed deliveries")
+
+	w = performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/alerts", projectID), owner.Token, nil)
+	alerts := decodeResponse(w)["alerts"].([]interface{})
+	assert.Equal(t, models.AlertWebhookDisabled, alerts[0].(map[string]interface{})["kind"])
+
+	// Nothing is sent to disabled webhooks
+	w = performRequest(router, "POST", hookPath+"/ping", owner.Token, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = performRequest(router, "GET", hookPath+"/deliveries", owner.Token, nil)
+	for _, delivery := range decodeResponse(w)["deliveries"].([]interface{}) {
+		assert.Nil(t, delivery.(map[string]interface{})["next_attempt_at"])
+	}
+	assert.Equal(t, 20, receiver.count())
+
+	active := true
+	w = performRequest(router, "PATCH", hookPath, owner.Token, models.UpdateWebhookRequest{Active: &active})
+	assert.Equal(t, http.StatusOK, w.Code)
+	hook = decodeResponse(w)["webhook"].(map[string]interface{})
+	assert.Equal(t, true, hook["active"])
+	assert.Equal(t, float64(0), hook["failures"])
+}
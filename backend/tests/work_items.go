@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"dod-backend/database"
+	"dod-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// createTestProjectWithDoD sets up a project owned by the user with a DoD made
+// of one required and one optional item.
+func createTestProjectWithDoD(t *testing.T, router *gin.Engine, owner testUser) (projectID, dodID, requiredItemID, optionalItemID uint) {
+	w := performRequest(router, "POST", "/api/v1/projects/", owner.Token, models.CreateProjectRequest{Name: "Work Item Project"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	projectID = uint(decodeResponse(w)["project"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "POST", "/api/v1/dods/", owner.Token, models.CreateDoDRequest{Title: "Story DoD", ProjectID: projectID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	dodID = uint(decodeResponse(w)["dod"].(map[string]interface{})["id"].(float64))
+
+	itemPath := fmt.Sprintf("/api/v1/dods/%d/items", dodID)
+	w = performRequest(router, "POST", itemPath, owner.Token, models.CreateDoDItemRequest{Title: "Code Review Completed", IsRequired: true, Order: 1})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	requiredItemID = uint(decodeResponse(w)["item"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "POST", itemPath, owner.Token, models.CreateDoDItemRequest{Title: "Documentation Updated", IsRequired: false, Order: 2})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	optionalItemID = uint(decodeResponse(w)["item"].(map[string]interface{})["id"].(float64))
+
+	return projectID, dodID, requiredItemID, optionalItemID
+}
+
+func workItemDone(t *testing.T, response map[string]interface{}) bool {
+	workItem := response["work_item"].(map[string]interface{})
+	return workItem["status"].(map[string]interface{})["done"].(bool)
+}
+
+func TestWorkItemDoneWhenRequiredItemsChecked(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "wiowner")
+	projectID, dodID, requiredItemID, optionalItemID := createTestProjectWithDoD(t, router, owner)
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), owner.Token, models.CreateWorkItemRequest{
+		Title:       "Checkout page",
+		ExternalRef: "SHOP-42",
+		DoDID:       &dodID,
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	response := decodeResponse(w)
+	assert.False(t, workItemDone(t, response))
+	workItemID := uint(response["work_item"].(map[string]interface{})["id"].(float64))
+
+	checked := true
+	checkPath := fmt.Sprintf("/api/v1/projects/%d/work-items/%d/checks/", projectID, workItemID)
+
+	// Optional items alone do not make the work item done
+	w = performRequest(router, "PUT", fmt.Sprintf("%s%d", checkPath, optionalItemID), owner.Token, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, workItemDone(t, decodeResponse(w)))
+
+	w = performRequest(router, "PUT", fmt.Sprintf("%s%d", checkPath, requiredItemID), owner.Token, models.CheckDoDItemRequest{Checked: &checked, Note: "Reviewed by bob"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, workItemDone(t, decodeResponse(w)))
+
+	// Unticking a required item reverts the work item to not done
+	unchecked := false
+	w = performRequest(router, "PUT", fmt.Sprintf("%s%d", checkPath, requiredItemID), owner.Token, models.CheckDoDItemRequest{Checked: &unchecked})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, workItemDone(t, decodeResponse(w)))
+}
+
+func TestCompletionsUniquePerItem(t *testing.T) {
+	ctrl := setupTestController()
+	router := setupTestRouterWithController(ctrl)
+	owner := registerTestUser(t, router, "uniqueowner")
+	projectID, dodID, requiredItemID, _ := createTestProjectWithDoD(t, router, owner)
+	workItemID := uint(createTestWorkItem(t, router, owner, projectID, dodID)["id"].(float64))
+
+	checked := true
+	w := performRequest(router, "PUT", fmt.Sprintf("/api/v1/projects/%d/work-items/%d/checks/%d", projectID, workItemID, requiredItemID), owner.Token, models.CheckDoDItemRequest{Checked: &checked})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Error(t, ctrl.DB.Create(&models.DoDItemCompletion{WorkItemID: workItemID, DoDItemID: requiredItemID}).Error)
+
+	// Migrating again keeps the completion
+	assert.NoError(t, database.Migrate(ctrl.DB))
+	var count int
+	ctrl.DB.Model(&models.DoDItemCompletion{}).Where("work_item_id = ?", workItemID).Count(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestAttachDoDFromAnotherProject(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "wiattach")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+	_, otherDoDID, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	w := performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), owner.Token, models.CreateWorkItemRequest{Title: "Bug fix", Type: "bug"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	workItemID := uint(decodeResponse(w)["work_item"].(map[string]interface{})["id"].(float64))
+
+	w = performRequest(router, "PUT", fmt.Sprintf("/api/v1/projects/%d/work-items/%d/dod", projectID, workItemID), owner.Token, models.AttachDoDRequest{DoDID: otherDoDID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestWorkItemsRequireParticipation(t *testing.T) {
+	router := setupTestRouter()
+	owner := registerTestUser(t, router, "wiprivate")
+	outsider := registerTestUser(t, router, "wioutsider")
+	projectID, _, _, _ := createTestProjectWithDoD(t, router, owner)
+
+	w := performRequest(router, "GET", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), outsider.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, "POST", fmt.Sprintf("/api/v1/projects/%d/work-items", projectID), outsider.Token, models.CreateWorkItemRequest{Title: "Sneaky"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,130 @@
+// Package webhooks delivers project events to the URLs subscribed to them:
+// JSON payloads signed with the subscription's secret, retried with an
+// exponential backoff when the receiver fails.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dod-backend/safehttp"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-DoD-Event"
+	HeaderDelivery  = "X-DoD-Delivery"
+	HeaderTimestamp = "X-DoD-Timestamp"
+	HeaderSignature = "X-DoD-Signature"
+)
+
+// Retry schedule: attempts are spaced by BaseDelay, doubling each time up to
+// MaxDelay, and a delivery is given up after MaxAttempts.
+const (
+	BaseDelay   = time.Minute
+	MaxDelay    = time.Hour
+	MaxAttempts = 8
+)
+
+// Backoff returns the delay before the attempt following the given one.
+func Backoff(attempt int) time.Duration {
+	delay := BaseDelay
+	for i := 1; i < attempt && delay < MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > MaxDelay {
+		delay = MaxDelay
+	}
+	return delay
+}
+
+// NewSecret returns a random signing secret.
+func NewSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// Sign returns the X-DoD-Signature header of a payload: the hex HMAC-SHA256
+// of "<timestamp>.<body>", so that receivers can reject replayed requests.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature made by Sign, for receivers written in Go.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Request is one delivery attempt.
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID uint
+	Payload    []byte
+}
+
+// Result is what the receiver answered. StatusCode is 0 when it could not be
+// reached. Its body is not kept: receivers are chosen by users, and could
+// make the server read pages it alone has access to.
+type Result struct {
+	StatusCode int
+	Duration   time.Duration
+}
+
+// Sender posts deliveries.
+type Sender struct {
+	HTTPClient *http.Client
+}
+
+// NewSender returns a sender that only posts to public addresses, without
+// following redirects.
+func NewSender() *Sender {
+	return &Sender{HTTPClient: safehttp.NewClient(10 * time.Second)}
+}
+
+// Send posts the payload and fails unless the receiver answers with a 2xx
+// status.
+func (s *Sender) Send(ctx context.Context, req Request) (Result, error) {
+	var result Result
+	httpReq, err := http.NewRequest(http.MethodPost, req.URL, bytes.NewReader(req.Payload))
+	if err != nil {
+		return result, err
+	}
+
+	timestamp := time.Now().Unix()
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "DoD-Manager-Webhooks/1.0")
+	httpReq.Header.Set(HeaderEvent, req.Event)
+	httpReq.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(req.DeliveryID), 10))
+	httpReq.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	httpReq.Header.Set(HeaderSignature, Sign(req.Secret, timestamp, req.Payload))
+
+	start := time.Now()
+	resp, err := s.HTTPClient.Do(httpReq.WithContext(ctx))
+	result.Duration = time.Since(start)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return result, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return result, nil
+}
@@ -6,6 +6,7 @@ import { AuthProvider, useAuth } from './contexts/AuthContext';
 import Navbar from './components/Navbar';
 import Login from './pages/Login';
 import Register from './pages/Register';
+import OIDCCallback from './pages/OIDCCallback';
 import Dashboard from './pages/Dashboard';
 import Projects from './pages/Projects';
 import ProjectDetail from './pages/ProjectDetail';
@@ -71,6 +72,14 @@ function AppContent() {
               </PublicRoute>
             } 
           />
+          <Route 
+            path="/oidc/callback" 
+            element={
+              <PublicRoute>
+                <OIDCCallback />
+              </PublicRoute>
+            } 
+          />
 
           {/* Protected Routes */}
           <Route 
@@ -1,4 +1,5 @@
 import React, { createContext, useContext, useState, useEffect } from 'react';
+import axios from 'axios';
 import api from '../services/api';
 
 const AuthContext = createContext();
@@ -33,6 +34,19 @@ export const AuthProvider = ({ children }) => {
     initAuth();
   }, []);
 
+  const storeSession = (data) => {
+    const { token: newToken, refresh_token: refreshToken, user: userData } = data;
+
+    localStorage.setItem('token', newToken);
+    localStorage.setItem('refresh_token', refreshToken);
+    localStorage.setItem('user', JSON.stringify(userData));
+
+    setToken(newToken);
+    setUser(userData);
+
+    api.defaults.headers.common['Authorization'] = `Bearer ${newToken}`;
+  };
+
   const login = async (email, password) => {
     try {
       const response = await api.post('/auth/login', {
@@ -40,15 +54,16 @@ export const AuthProvider = ({ children }) => {
         password,
       });
 
-      const { token: newToken, user: userData } = response.data;
+      // Accounts with 2FA get a challenge to complete with verifyTwoFactor
+      if (response.data.two_factor_required) {
+        return {
+          success: false,
+          twoFactorRequired: true,
+          challengeToken: response.data.challenge_token,
+        };
+      }
 
-      localStorage.setItem('token', newToken);
-      localStorage.setItem('user', JSON.stringify(userData));
-      
-      setToken(newToken);
-      setUser(userData);
-      
-      api.defaults.headers.common['Authorization'] = `Bearer ${newToken}`;
+      storeSession(response.data);
 
       return { success: true };
     } catch (error) {
@@ -59,23 +74,82 @@ export const AuthProvider = ({ children }) => {
     }
   };
 
-  const register = async (username, email, password) => {
+  const verifyTwoFactor = async (challengeToken, code) => {
+    try {
+      const response = await api.post('/auth/2fa/verify', {
+        challenge_token: challengeToken,
+        code,
+      });
+
+      storeSession(response.data);
+
+      return { success: true };
+    } catch (error) {
+      return {
+        success: false,
+        error: error.response?.data?.error || 'Verification failed',
+      };
+    }
+  };
+
+  const startSSO = async () => {
+    try {
+      const response = await api.get('/auth/oidc/login');
+      // Ties the login to this browser: the callback must present it back
+      sessionStorage.setItem('oidc_login_binding', response.data.login_binding);
+      window.location.assign(response.data.authorization_url);
+      return { success: true };
+    } catch (error) {
+      return {
+        success: false,
+        error: error.response?.data?.error || 'Single sign-on unavailable',
+      };
+    }
+  };
+
+  const completeSSO = async (code, state) => {
+    try {
+      const loginBinding = sessionStorage.getItem('oidc_login_binding');
+      sessionStorage.removeItem('oidc_login_binding');
+      if (!loginBinding) {
+        return { success: false, error: 'This sign-in was not started from this browser' };
+      }
+
+      const response = await api.post('/auth/oidc/callback', {
+        code,
+        state,
+        login_binding: loginBinding,
+      });
+
+      if (response.data.two_factor_required) {
+        return {
+          success: false,
+          twoFactorRequired: true,
+          challengeToken: response.data.challenge_token,
+        };
+      }
+
+      storeSession(response.data);
+
+      return { success: true };
+    } catch (error) {
+      return {
+        success: false,
+        error: error.response?.data?.error || 'Single sign-on failed',
+      };
+    }
+  };
+
+  const register = async (username, email, password, invitationToken) => {
     try {
       const response = await api.post('/auth/register', {
         username,
         email,
         password,
+        invitation_token: invitationToken || undefined,
       });
 
-      const { token: newToken, user: userData } = response.data;
-
-      localStorage.setItem('token', newToken);
-      localStorage.setItem('user', JSON.stringify(userData));
-      
-      setToken(newToken);
-      setUser(userData);
-      
-      api.defaults.headers.common['Authorization'] = `Bearer ${newToken}`;
+      storeSession(response.data);
 
       return { success: true };
     } catch (error) {
@@ -87,7 +161,22 @@ export const AuthProvider = ({ children }) => {
   };
 
   const logout = () => {
+    const currentToken = localStorage.getItem('token');
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (currentToken) {
+      // Revoke the session server-side, bypassing the interceptors since the
+      // local state is cleared right away regardless of the outcome
+      axios
+        .post(
+          `${api.defaults.baseURL}/auth/logout`,
+          { refresh_token: refreshToken },
+          { headers: { Authorization: `Bearer ${currentToken}` } }
+        )
+        .catch(() => {});
+    }
+
     localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
     localStorage.removeItem('user');
     
     setToken(null);
@@ -102,6 +191,9 @@ export const AuthProvider = ({ children }) => {
     loading,
     isAuthenticated: !!token,
     login,
+    verifyTwoFactor,
+    startSSO,
+    completeSSO,
     register,
     logout,
   };
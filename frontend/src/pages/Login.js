@@ -9,15 +9,18 @@ import {
   Box,
   Link as MuiLink,
 } from '@mui/material';
-import { Link, useNavigate } from 'react-router-dom';
+import { Link, useLocation, useNavigate } from 'react-router-dom';
 import { useForm } from 'react-hook-form';
 import { useAuth } from '../contexts/AuthContext';
 
 const Login = () => {
-  const { login } = useAuth();
+  const { login, verifyTwoFactor, startSSO } = useAuth();
   const navigate = useNavigate();
+  const location = useLocation();
   const [error, setError] = useState('');
   const [loading, setLoading] = useState(false);
+  // Single sign-on of accounts with 2FA lands here for the code
+  const [challengeToken, setChallengeToken] = useState(location.state?.challengeToken || null);
 
   const {
     register,
@@ -29,10 +32,14 @@ const Login = () => {
     setLoading(true);
     setError('');
 
-    const result = await login(data.email, data.password);
+    const result = challengeToken
+      ? await verifyTwoFactor(challengeToken, data.code)
+      : await login(data.email, data.password);
 
     if (result.success) {
       navigate('/dashboard');
+    } else if (result.twoFactorRequired) {
+      setChallengeToken(result.challengeToken);
     } else {
       setError(result.error);
     }
@@ -40,6 +47,14 @@ const Login = () => {
     setLoading(false);
   };
 
+  const onSSO = async () => {
+    setError('');
+    const result = await startSSO();
+    if (!result.success) {
+      setError(result.error);
+    }
+  };
+
   return (
     <Container maxWidth="sm" sx={{ mt: 8 }}>
       <Paper elevation={3} sx={{ p: 4 }}>
@@ -89,6 +104,20 @@ const Login = () => {
             helperText={errors.password?.message}
           />
 
+          {challengeToken && (
+            <TextField
+              fullWidth
+              autoFocus
+              label="Authentication code"
+              margin="normal"
+              helperText={errors.code?.message || 'Code from your authenticator app, or a recovery code'}
+              {...register('code', {
+                required: 'Authentication code is required',
+              })}
+              error={!!errors.code}
+            />
+          )}
+
           <Button
             type="submit"
             fullWidth
@@ -99,6 +128,10 @@ const Login = () => {
             {loading ? 'Signing in...' : 'Sign In'}
           </Button>
 
+          <Button fullWidth variant="outlined" sx={{ mb: 2 }} onClick={onSSO}>
+            Sign in with SSO
+          </Button>
+
           <Box textAlign="center">
             <Typography variant="body2">
               Don't have an account?{' '}
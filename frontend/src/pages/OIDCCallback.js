@@ -0,0 +1,59 @@
+import React, { useEffect, useRef, useState } from 'react';
+import { Container, Paper, Typography, Alert, Link as MuiLink } from '@mui/material';
+import { Link, useNavigate, useSearchParams } from 'react-router-dom';
+import { useAuth } from '../contexts/AuthContext';
+
+const OIDCCallback = () => {
+  const { completeSSO } = useAuth();
+  const navigate = useNavigate();
+  const [searchParams] = useSearchParams();
+  const [error, setError] = useState('');
+  const started = useRef(false);
+
+  useEffect(() => {
+    // The state is single-use, so guard against double effects in dev mode
+    if (started.current) return;
+    started.current = true;
+
+    const code = searchParams.get('code');
+    const state = searchParams.get('state');
+    if (!code || !state) {
+      setError(searchParams.get('error_description') || 'Single sign-on was cancelled');
+      return;
+    }
+
+    completeSSO(code, state).then((result) => {
+      if (result.success) {
+        navigate('/dashboard');
+      } else if (result.twoFactorRequired) {
+        // The login page asks for the code
+        navigate('/login', { state: { challengeToken: result.challengeToken } });
+      } else {
+        setError(result.error);
+      }
+    });
+  }, [completeSSO, navigate, searchParams]);
+
+  return (
+    <Container maxWidth="sm" sx={{ mt: 8 }}>
+      <Paper elevation={3} sx={{ p: 4 }}>
+        {error ? (
+          <>
+            <Alert severity="error" sx={{ mb: 2 }}>
+              {error}
+            </Alert>
+            <Typography variant="body2" align="center">
+              <MuiLink component={Link} to="/login">
+                Back to login
+              </MuiLink>
+            </Typography>
+          </>
+        ) : (
+          <Typography align="center">Signing in...</Typography>
+        )}
+      </Paper>
+    </Container>
+  );
+};
+
+export default OIDCCallback;
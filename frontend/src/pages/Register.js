@@ -9,13 +9,14 @@ import {
   Box,
   Link as MuiLink,
 } from '@mui/material';
-import { Link, useNavigate } from 'react-router-dom';
+import { Link, useNavigate, useSearchParams } from 'react-router-dom';
 import { useForm } from 'react-hook-form';
 import { useAuth } from '../contexts/AuthContext';
 
 const Register = () => {
   const { register: registerUser } = useAuth();
   const navigate = useNavigate();
+  const [searchParams] = useSearchParams();
   const [error, setError] = useState('');
   const [loading, setLoading] = useState(false);
 
@@ -32,7 +33,13 @@ const Register = () => {
     setLoading(true);
     setError('');
 
-    const result = await registerUser(data.username, data.email, data.password);
+    // Signing up from an invitation link joins the invited projects
+    const result = await registerUser(
+      data.username,
+      data.email,
+      data.password,
+      searchParams.get('invitation')
+    );
 
     if (result.success) {
       navigate('/dashboard');
@@ -25,17 +25,59 @@ api.interceptors.request.use(
   }
 );
 
+const clearSession = () => {
+  localStorage.removeItem('token');
+  localStorage.removeItem('refresh_token');
+  localStorage.removeItem('user');
+  window.location.href = '/login';
+};
+
+// Access tokens are short-lived: exchange the refresh token once and replay the request
+let refreshPromise = null;
+const refreshSession = () => {
+  if (!refreshPromise) {
+    const refreshToken = localStorage.getItem('refresh_token');
+    refreshPromise = axios
+      .post(`${API_BASE_URL}/auth/refresh`, { refresh_token: refreshToken })
+      .then((response) => {
+        localStorage.setItem('token', response.data.token);
+        localStorage.setItem('refresh_token', response.data.refresh_token);
+        return response.data.token;
+      })
+      .finally(() => {
+        refreshPromise = null;
+      });
+  }
+  return refreshPromise;
+};
+
 // Response interceptor to handle auth errors
 api.interceptors.response.use(
   (response) => {
     return response;
   },
-  (error) => {
+  async (error) => {
+    const originalRequest = error.config;
     if (error.response?.status === 401) {
+      const canRefresh =
+        localStorage.getItem('refresh_token') &&
+        !originalRequest._retried &&
+        !originalRequest.url.startsWith('/auth/');
+
+      if (canRefresh) {
+        originalRequest._retried = true;
+        try {
+          const token = await refreshSession();
+          originalRequest.headers.Authorization = `Bearer ${token}`;
+          return api(originalRequest);
+        } catch (refreshError) {
+          clearSession();
+          return Promise.reject(refreshError);
+        }
+      }
+
       // Token expired or invalid
-      localStorage.removeItem('token');
-      localStorage.removeItem('user');
-      window.location.href = '/login';
+      clearSession();
     }
     return Promise.reject(error);
   }
@@ -48,6 +90,9 @@ export const authService = {
   
   register: (username, email, password) =>
     api.post('/auth/register', { username, email, password }),
+
+  logoutAll: () =>
+    api.post('/auth/logout-all'),
 };
 
 // Project services